@@ -2,14 +2,19 @@ package delivery
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"net/http"
 
 	"auth-service/domain"
+	"shared/rbac"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
@@ -22,23 +27,55 @@ type token struct {
 
 type AuthHanlder struct {
 	au domain.AuthUseCase
+
+	// internalToken authenticates other services on the internal API
+	internalToken string
 }
 
 type ctxKey int8
 
 const CtxKeyUser ctxKey = iota
 
-func NewAuthHandler(router *chi.Mux, au domain.AuthUseCase) {
+func NewAuthHandler(c *domain.Config, router *chi.Mux, au domain.AuthUseCase) {
 	handler := &AuthHanlder{
-		au: au,
+		au:            au,
+		internalToken: c.InternalToken,
 	}
 
 	router.Post("/signup", handler.SignUpHanlder)
 	router.Post("/login", handler.LoginHandler)
 	router.Get("/update-token", handler.UpdateTokenHanlder)
 
+	router.Post("/logout", handler.LogoutHandler)
+	router.Get("/.well-known/jwks.json", handler.JWKSHandler)
+
 	router.With(handler.CheckAuthMiddleware).Get("/user-data", handler.UserDataHandler)
+	router.With(handler.CheckAuthMiddleware).Get("/sessions", handler.SessionsHandler)
+	router.With(handler.CheckAuthMiddleware).Delete("/sessions", handler.RevokeSessionsHandler)
+	router.With(handler.CheckAuthMiddleware).Delete("/sessions/{id}", handler.RevokeSessionHandler)
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(handler.CheckAuthMiddleware)
+
+		r.With(handler.RequirePermission(rbac.PermUsersRead)).Get("/users", handler.ListUsersHandler)
+		r.With(handler.RequirePermission(rbac.PermUsersRead)).Get("/users/{id}", handler.AdminUserHandler)
+		r.With(handler.RequirePermission(rbac.PermUsersWrite)).Put("/users/{id}/role", handler.ChangeRoleHandler)
+		r.With(handler.RequirePermission(rbac.PermComplianceReview)).Get("/compliance/cases", handler.ComplianceCasesHandler)
+		r.With(handler.RequirePermission(rbac.PermComplianceReview)).Post("/compliance/cases/{id}/clear", handler.ClearCaseHandler)
+		r.With(handler.RequirePermission(rbac.PermComplianceReview)).Post("/compliance/cases/{id}/confirm", handler.ConfirmCaseHandler)
+	})
+
+}
+
+// NewInternalHandler serves the API for other services on its own router,
+// which is not to be exposed with the public one.
+func NewInternalHandler(c *domain.Config, router *chi.Mux, au domain.AuthUseCase) {
+	handler := &AuthHanlder{
+		au:            au,
+		internalToken: c.InternalToken,
+	}
 
+	router.With(handler.CheckServiceMiddleware).Get("/internal/users/{id}", handler.InternalUserHandler)
 }
 
 func (s *AuthHanlder) CheckAuthMiddleware(next http.Handler) http.Handler {
@@ -77,6 +114,48 @@ func (s *AuthHanlder) CheckAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// CheckServiceMiddleware lets through requests of services
+// that carry the internal token as a bearer token.
+func (s *AuthHanlder) CheckServiceMiddleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.internalToken)) != 1 {
+			log.Warn().Str("remote", r.RemoteAddr).Msg("internal API: wrong token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// RequirePermission lets through only users whose role grants the permission.
+// It must be used after CheckAuthMiddleware.
+func (s *AuthHanlder) RequirePermission(p rbac.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+			if !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if !u.Can(p) {
+				log.Warn().Int64("user", u.ID).Str("permission", string(p)).Msg("permission denied")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("Permission denied"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
 func (s *AuthHanlder) SignUpHanlder(w http.ResponseWriter, r *http.Request) {
 
 	user := &domain.User{
@@ -86,7 +165,7 @@ func (s *AuthHanlder) SignUpHanlder(w http.ResponseWriter, r *http.Request) {
 		LastName:   r.FormValue("LastName"),
 		IIN:        r.FormValue("IIN"),
 		Phone:      r.FormValue("Phone"),
-		Role:       "user",
+		Role:       rbac.RoleUser,
 		Registered: time.Now(),
 	}
 
@@ -96,7 +175,13 @@ func (s *AuthHanlder) SignUpHanlder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.au.CreateUser(r.Context(), user); err != nil {
+	err := s.au.CreateUser(r.Context(), user)
+	if err == domain.ErrUnderage {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("Could not create user"))
 		log.Info().Err(err).Msg("Invalid user")
@@ -118,7 +203,7 @@ func (s *AuthHanlder) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := s.au.GenerateAndSendTokens(u)
+	accessToken, refreshToken, err := s.au.GenerateAndSendTokens(u, sessionFromRequest(r))
 	if err != nil {
 
 		log.Debug().Err(err).Msgf("GenerateAndSendTokens: %v", err)
@@ -177,7 +262,7 @@ func (s *AuthHanlder) UpdateTokenHanlder(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	accessToken, refreshToken, err := s.au.UpdateToken(c.Value)
+	accessToken, refreshToken, err := s.au.UpdateToken(c.Value, sessionFromRequest(r))
 
 	if err != nil {
 		log.Debug().Err(err).Msgf("GenerateAndSendTokens: %v", err)
@@ -245,7 +330,7 @@ func (s *AuthHanlder) UserDataHandler(w http.ResponseWriter, r *http.Request) {
 
 	req, err := http.NewRequest(http.MethodGet, "http://transfer-app:8080/accounts", nil)
 	if err != nil {
-		log.Debug().Err(err).Msgf("UserDataHandler: %w", err)
+		log.Debug().Err(err).Msgf("UserDataHandler: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -259,7 +344,7 @@ func (s *AuthHanlder) UserDataHandler(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Debug().Err(err).Msgf("UserDataHandler: %w", err)
+		log.Debug().Err(err).Msgf("UserDataHandler: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -286,6 +371,352 @@ func (s *AuthHanlder) UserDataHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(reply)
 }
 
+func (s *AuthHanlder) SessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := s.au.Sessions(u.ID)
+	if err != nil {
+		log.Warn().Err(err).Msg("SessionsHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == u.SessionID
+	}
+
+	reply, err := json.Marshal(sessions)
+	if err != nil {
+		log.Debug().Err(err).Msgf("SessionsHandler: Marshal sessions: %v", err)
+
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Error proceeding data"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+func (s *AuthHanlder) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+
+	u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err := s.au.RevokeSession(u.ID, chi.URLParam(r, "id"))
+	switch err {
+	case nil:
+	case domain.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("No session found"))
+		return
+	default:
+		log.Warn().Err(err).Msg("RevokeSessionHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte("Session revoked"))
+}
+
+func (s *AuthHanlder) RevokeSessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.au.RevokeSessions(u.ID); err != nil {
+		log.Warn().Err(err).Msg("RevokeSessionsHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	clearTokenCookies(w)
+	w.Write([]byte("All sessions revoked"))
+}
+
+// LogoutHandler ends the current session. Cookies are cleared
+// even when the session is already gone.
+func (s *AuthHanlder) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+
+	if c, err := r.Cookie("refresh_token"); err == nil {
+		if err := s.au.Logout(c.Value); err != nil && err != domain.ErrNotFound {
+			log.Debug().Err(err).Msgf("Logout: %v", err)
+		}
+	}
+
+	clearTokenCookies(w)
+	w.Write([]byte("Successfully logged out"))
+}
+
+// JWKSHandler publishes public keys, so other services can verify tokens
+// without holding any secret.
+func (s *AuthHanlder) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+
+	reply, err := json.Marshal(s.au.PublicKeys())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(reply)
+}
+
+func (s *AuthHanlder) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	users, err := s.au.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		log.Warn().Err(err).Msg("ListUsersHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	reply, err := json.Marshal(users)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+func (s *AuthHanlder) AdminUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.au.GetUserData(r.Context(), ID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("No user found"))
+		return
+	}
+
+	reply, err := json.Marshal(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+// InternalUserHandler tells other services the current role of a user,
+// e.g. money-transfer before it runs a standing order of the user.
+func (s *AuthHanlder) InternalUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// a user that is gone and a database that is down must not look the same
+	user, err := s.au.GetUserData(r.Context(), ID)
+	if err == domain.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("InternalUserHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// names are screened against sanctions lists along with IINs
+	reply, err := json.Marshal(&domain.User{ID: user.ID, Role: user.Role, FirstName: user.FirstName, LastName: user.LastName})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+func (s *AuthHanlder) ChangeRoleHandler(w http.ResponseWriter, r *http.Request) {
+
+	u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = s.au.ChangeRole(r.Context(), u, ID, r.FormValue("Role"))
+	switch err {
+	case nil:
+	case domain.ErrInvalidRole, domain.ErrOwnRole:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	case domain.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("No user found"))
+		return
+	default:
+		log.Warn().Err(err).Msg("ChangeRoleHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte("Role changed"))
+}
+
+// ComplianceCasesHandler lists sign-ups matched by screening, status=open by default.
+func (s *AuthHanlder) ComplianceCasesHandler(w http.ResponseWriter, r *http.Request) {
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = domain.CaseOpen
+	}
+
+	cases, err := s.au.ComplianceCases(r.Context(), status, limit, offset)
+	if err != nil {
+		log.Warn().Err(err).Msg("ComplianceCasesHandler")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	reply, err := json.Marshal(cases)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+// ClearCaseHandler closes a case as a false positive, takes an optional Note.
+func (s *AuthHanlder) ClearCaseHandler(w http.ResponseWriter, r *http.Request) {
+	s.resolveCase(w, r, domain.CaseCleared)
+}
+
+// ConfirmCaseHandler closes a case as a true match, takes an optional Note.
+func (s *AuthHanlder) ConfirmCaseHandler(w http.ResponseWriter, r *http.Request) {
+	s.resolveCase(w, r, domain.CaseConfirmed)
+}
+
+func (s *AuthHanlder) resolveCase(w http.ResponseWriter, r *http.Request, status string) {
+
+	u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c, err := s.au.ResolveCase(r.Context(), u, ID, status, r.FormValue("Note"))
+	switch err {
+	case nil:
+	case domain.ErrCaseNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	case domain.ErrCaseResolved:
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		log.Warn().Err(err).Msg("resolveCase")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	reply, err := json.Marshal(c)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+func clearTokenCookies(w http.ResponseWriter) {
+	for _, name := range []string{"access_token", "refresh_token"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	}
+}
+
+// sessionFromRequest describes the device a request came from.
+func sessionFromRequest(r *http.Request) *domain.Session {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	userAgent := r.UserAgent()
+
+	device := r.FormValue("Device")
+	if device == "" {
+		device = "desktop"
+		if strings.Contains(userAgent, "Mobile") {
+			device = "mobile"
+		}
+	}
+
+	return &domain.Session{
+		Device:    device,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+}
+
 func extractCredentials(r *http.Request) (string, string) {
 	return string(r.FormValue("Email")), string(r.FormValue("Password"))
 }
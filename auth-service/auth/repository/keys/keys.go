@@ -0,0 +1,292 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	keyBits       = 2048
+	createdHeader = "Created"
+)
+
+// dirKeyStore keeps every key in its own PEM file named by key ID.
+// The newest key that was published long enough signs tokens,
+// older keys only verify tokens until everything signed by them has expired.
+type dirKeyStore struct {
+	dir            string
+	publishDelay   time.Duration
+	tokenTTL       time.Duration
+	reloadInterval time.Duration
+
+	mu     sync.Mutex
+	keys   []*domain.SigningKey
+	loaded time.Time
+}
+
+// NewDirKeyStore reads signing keys from the keys directory.
+// The first key is generated when the directory is empty.
+func NewDirKeyStore(c *domain.Config) (domain.KeyStore, error) {
+	tokenTTL := c.AccessTokenTTL.Duration
+	if c.RefreshTokenTTL.Duration > tokenTTL {
+		tokenTTL = c.RefreshTokenTTL.Duration
+	}
+
+	s := &dirKeyStore{
+		dir:            c.KeysDir,
+		publishDelay:   c.KeyPublishDelay.Duration,
+		tokenTTL:       tokenTTL,
+		reloadInterval: c.KeysReloadInterval.Duration,
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	if len(s.keys) == 0 {
+		log.Info().Str("dir", s.dir).Msg("no signing keys found, generating new one")
+
+		if _, err := s.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *dirKeyStore) SigningKey() *domain.SigningKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadIfStale()
+
+	return s.keys[s.signing(time.Now())]
+}
+
+func (s *dirKeyStore) PublicKey(kid string) (*rsa.PublicKey, bool) {
+	for _, k := range s.Keys() {
+		if k.ID == kid && !s.expired(k, time.Now()) {
+			return &k.Private.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+func (s *dirKeyStore) PublicKeys() *domain.JWKSet {
+	set := &domain.JWKSet{Keys: []domain.JWK{}}
+
+	for _, k := range s.Keys() {
+		if !s.expired(k, time.Now()) {
+			set.Keys = append(set.Keys, toJWK(k))
+		}
+	}
+	return set
+}
+
+// Keys returns all keys oldest first with their retirement time filled.
+func (s *dirKeyStore) Keys() []*domain.SigningKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadIfStale()
+
+	return s.keys
+}
+
+// Rotate introduces a new key. It is published right away
+// and starts signing tokens after the publish delay.
+func (s *dirKeyStore) Rotate() (*domain.SigningKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &domain.SigningKey{
+		ID:      Thumbprint(&private.PublicKey),
+		Private: private,
+		Created: time.Now().UTC(),
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{createdHeader: key.Created.Format(time.RFC3339Nano)},
+		Bytes:   der,
+	})
+
+	if err := os.WriteFile(filepath.Join(s.dir, key.ID+".pem"), data, 0600); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return key, s.reload()
+}
+
+// Prune deletes keys that can't verify any unexpired token anymore.
+func (s *dirKeyStore) Prune() ([]string, error) {
+	pruned := make([]string, 0)
+
+	for _, k := range s.Keys() {
+		if !s.expired(k, time.Now()) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, k.ID+".pem")); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, k.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return pruned, s.reload()
+}
+
+// signing returns index of the key that signs tokens at the moment.
+func (s *dirKeyStore) signing(now time.Time) int {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].Created.Add(s.publishDelay).After(now) {
+			return i
+		}
+	}
+	// nothing is published long enough, e.g. on the very first start
+	return 0
+}
+
+func (s *dirKeyStore) expired(k *domain.SigningKey, now time.Time) bool {
+	return !k.Retired.IsZero() && now.After(k.Retired.Add(s.tokenTTL))
+}
+
+func (s *dirKeyStore) reloadIfStale() {
+	if time.Since(s.loaded) < s.reloadInterval {
+		return
+	}
+
+	if err := s.reload(); err != nil {
+		log.Warn().Err(err).Str("dir", s.dir).Msg("cannot reload signing keys, keeping loaded ones")
+	}
+}
+
+// reload reads the keys directory. Caller must hold the lock.
+func (s *dirKeyStore) reload() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*domain.SigningKey, 0, len(files))
+
+	for _, file := range files {
+		k, err := readKey(file)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+
+	// a key retires once a newer key starts signing
+	for i := 0; i < len(keys)-1; i++ {
+		keys[i].Retired = keys[i+1].Created.Add(s.publishDelay)
+	}
+
+	if len(keys) > 0 || len(s.keys) == 0 {
+		s.keys = keys
+	}
+	s.loaded = time.Now()
+
+	return nil
+}
+
+func readKey(path string) (*domain.SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA private key", path)
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, block.Headers[createdHeader])
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid %s header: %v", path, createdHeader, err)
+	}
+
+	id := Thumbprint(&private.PublicKey)
+	if name := strings.TrimSuffix(filepath.Base(path), ".pem"); name != id {
+		return nil, fmt.Errorf("%s: file name doesn't match key ID %s", path, id)
+	}
+
+	return &domain.SigningKey{
+		ID:      id,
+		Private: private,
+		Created: created,
+	}, nil
+}
+
+func toJWK(k *domain.SigningKey) domain.JWK {
+	return domain.JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.ID,
+		N:   encode(k.Private.PublicKey.N.Bytes()),
+		E:   encode(big.NewInt(int64(k.Private.PublicKey.E)).Bytes()),
+	}
+}
+
+// Thumbprint is JWK thumbprint of the key (RFC 7638), used as key ID.
+func Thumbprint(key *rsa.PublicKey) string {
+	n := encode(key.N.Bytes())
+	e := encode(big.NewInt(int64(key.E)).Bytes())
+
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return encode(sum[:])
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -48,11 +48,12 @@ func (db *sqlRepository) CloseConnection() {
 
 func (db *sqlRepository) CreateUser(ctx context.Context, u *domain.User) error {
 
-	_, err := db.Exec(ctx,
-		`INSERT INTO users(Email, FirstName, LastName, Password, IIN, Phone, Registered, Role) 
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
-		u.Email, u.FirstName, u.LastName, u.Password, u.IIN, u.Phone, u.Registered, u.Role,
-	)
+	err := db.QueryRow(ctx,
+		`INSERT INTO users(Email, FirstName, LastName, Password, IIN, BirthDate, Gender, Phone, Registered, Role) 
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ID`,
+		u.Email, u.FirstName, u.LastName, u.Password, u.IIN, u.BirthDate, u.Gender, u.Phone, u.Registered, u.Role,
+	).Scan(&u.ID)
 
 	var pgErr *pgconn.PgError
 	if err != nil && errors.As(err, &pgErr) {
@@ -84,15 +85,57 @@ func (db *sqlRepository) GetUserData(ctx context.Context, ID int64) (*domain.Use
 	user := &domain.User{}
 
 	err := db.QueryRow(ctx,
-		`SELECT ID, Email, IIN, Registered, Role
+		`SELECT ID, Email, IIN, BirthDate, Gender, Registered, Role
 		FROM users 
 		WHERE ID=$1`,
 		ID,
-	).Scan(&user.ID, &user.Email, &user.IIN, &user.Registered, &user.Role)
+	).Scan(&user.ID, &user.Email, &user.IIN, &user.BirthDate, &user.Gender, &user.Registered, &user.Role)
 
 	if err == pgx.ErrNoRows {
-		return nil, err
+		return nil, domain.ErrNotFound
 	}
 
 	return user, err
 }
+
+func (db *sqlRepository) ListUsers(ctx context.Context, limit, offset int) ([]*domain.User, error) {
+	rows, err := db.Query(ctx,
+		`SELECT ID, Email, FirstName, LastName, IIN, BirthDate, Gender, Phone, Registered, Role
+		FROM users 
+		ORDER BY ID 
+		LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make([]*domain.User, 0)
+
+	for rows.Next() {
+		u := &domain.User{}
+
+		err := rows.Scan(&u.ID, &u.Email, &u.FirstName, &u.LastName, &u.IIN, &u.BirthDate, &u.Gender, &u.Phone, &u.Registered, &u.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+func (db *sqlRepository) ChangeRole(ctx context.Context, ID int64, role string) error {
+	tag, err := db.Exec(ctx, `UPDATE users SET Role = $1 WHERE ID = $2`, role, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package rd
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"auth-service/domain"
@@ -15,6 +16,22 @@ type caсheStore struct {
 	*redis.Client
 }
 
+// rotateScript swaps the current token of a session in one step.
+// It returns -1 for unknown session, 0 for an already rotated token and 1 on success.
+var rotateScript = redis.NewScript(`
+local user = redis.call('HGET', KEYS[1], 'user')
+if not user or user ~= ARGV[1] then
+	return -1
+end
+if redis.call('HGET', KEYS[1], 'current') ~= ARGV[2] then
+	return 0
+end
+redis.call('HMSET', KEYS[1], 'current', ARGV[3], 'last_used', ARGV[4], 'ip', ARGV[5], 'user_agent', ARGV[6])
+redis.call('PEXPIRE', KEYS[1], ARGV[7])
+redis.call('PEXPIRE', KEYS[2], ARGV[7])
+return 1
+`)
+
 func NewRedisClient(c *domain.Config) (domain.CaсheStore, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     c.CacheHost + c.CacheAddr,
@@ -34,19 +51,130 @@ func NewRedisClient(c *domain.Config) (domain.CaсheStore, error) {
 	}, nil
 }
 
-func (c *caсheStore) FindToken(id int64, token string) bool {
-	key := fmt.Sprintf("user:%d", id)
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
+}
+
+func userSessionsKey(userID int64) string {
+	return fmt.Sprintf("user:%d:sessions", userID)
+}
+
+func (c *caсheStore) CreateSession(s *domain.Session, tokenID string) error {
+	key := sessionKey(s.ID)
+	userKey := userSessionsKey(s.UserID)
 
-	value, err := c.Get(key).Result()
+	_, err := c.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HMSet(key, map[string]interface{}{
+			"user":       s.UserID,
+			"current":    tokenID,
+			"device":     s.Device,
+			"ip":         s.IP,
+			"user_agent": s.UserAgent,
+			"created":    s.Created.Unix(),
+			"last_used":  s.LastUsed.Unix(),
+		})
+		pipe.Expire(key, c.rTokenTTL)
+		pipe.SAdd(userKey, s.ID)
+		pipe.Expire(userKey, c.rTokenTTL)
+		return nil
+	})
+	return err
+}
+
+func (c *caсheStore) RotateToken(s *domain.Session, tokenID, nextID string) error {
+	res, err := rotateScript.Run(c.Client,
+		[]string{sessionKey(s.ID), userSessionsKey(s.UserID)},
+		s.UserID, tokenID, nextID, s.LastUsed.Unix(), s.IP, s.UserAgent, c.rTokenTTL.Milliseconds(),
+	).Int()
 	if err != nil {
-		return false
+		return err
 	}
 
-	return token == value
+	switch res {
+	case 1:
+		return nil
+	case 0:
+		return domain.ErrTokenReused
+	default:
+		return domain.ErrInvalidToken
+	}
 }
 
-func (c *caсheStore) InsertToken(id int64, token string) error {
-	key := fmt.Sprintf("user:%d", id)
+// Sessions returns live sessions of the user.
+// Sessions expired by Redis are cleaned from the user's index on the way.
+func (c *caсheStore) Sessions(userID int64) ([]*domain.Session, error) {
+	userKey := userSessionsKey(userID)
 
-	return c.Set(key, token, c.rTokenTTL).Err()
+	IDs, err := c.SMembers(userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*domain.Session, 0, len(IDs))
+
+	for _, id := range IDs {
+		fields, err := c.HGetAll(sessionKey(id)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fields) == 0 {
+			c.SRem(userKey, id)
+			continue
+		}
+
+		sessions = append(sessions, &domain.Session{
+			ID:        id,
+			UserID:    userID,
+			Device:    fields["device"],
+			IP:        fields["ip"],
+			UserAgent: fields["user_agent"],
+			Created:   unixField(fields["created"]),
+			LastUsed:  unixField(fields["last_used"]),
+		})
+	}
+
+	return sessions, nil
+}
+
+func (c *caсheStore) RevokeSession(userID int64, sessionID string) error {
+	owner, err := c.HGet(sessionKey(sessionID), "user").Int64()
+	if err == redis.Nil || (err == nil && owner != userID) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = c.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(sessionKey(sessionID))
+		pipe.SRem(userSessionsKey(userID), sessionID)
+		return nil
+	})
+	return err
+}
+
+func (c *caсheStore) RevokeSessions(userID int64) error {
+	userKey := userSessionsKey(userID)
+
+	IDs, err := c.SMembers(userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(IDs)+1)
+	for _, id := range IDs {
+		keys = append(keys, sessionKey(id))
+	}
+	keys = append(keys, userKey)
+
+	return c.Del(keys...).Err()
+}
+
+func unixField(value string) time.Time {
+	sec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
 }
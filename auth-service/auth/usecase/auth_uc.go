@@ -2,31 +2,46 @@ package authUseCase
 
 import (
 	"context"
+	crand "crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
+	"auth-service/auth/repository/keys"
 	"auth-service/auth/repository/pg"
 	rd "auth-service/auth/repository/redis"
+	"shared/screening"
 
 	"auth-service/domain"
+	"shared/iin"
+	"shared/rbac"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
 type authUseCase struct {
-	accessSecret  string
-	refreshSecret string
+	keys domain.KeyStore
 
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 
 	cache domain.CaсheStore
 	db    domain.Repository
+
+	screener domain.Screener
+
+	minAge int
 }
 
 func New(c *domain.Config) (domain.AuthUseCase, error) {
@@ -41,14 +56,27 @@ func New(c *domain.Config) (domain.AuthUseCase, error) {
 		return nil, err
 	}
 
+	k, err := keys.NewDirKeyStore(c)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := screening.New(c.ScreeningLists, c.ScreeningFlagScore, c.ScreeningBlockScore)
+	if err != nil {
+		return nil, err
+	}
+
 	return &authUseCase{
-		accessSecret:  c.AccessTokenSecret,
-		refreshSecret: c.RefreshTokenSecret,
+		keys: k,
 
 		accessTokenTTL:  c.AccessTokenTTL.Duration,
 		refreshTokenTTL: c.RefreshTokenTTL.Duration,
 		cache:           r,
 		db:              d,
+
+		screener: s,
+
+		minAge: c.MinAge,
 	}, nil
 }
 
@@ -88,8 +116,31 @@ func (a *authUseCase) FindUser(ctx context.Context, email, password string) (*do
 	return u, nil
 }
 
+// CreateUser takes the birth date and gender from the IIN and screens the user
+// against sanctions lists. A blocked sign-up is refused, a flagged one goes on,
+// both are recorded for review.
 func (a *authUseCase) CreateUser(ctx context.Context, u *domain.User) error {
 
+	info, err := iin.Parse(u.IIN)
+	if err != nil {
+		return err
+	}
+
+	if info.Age(time.Now()) < a.minAge {
+		return domain.ErrUnderage
+	}
+
+	u.BirthDate, u.Gender = info.BirthDate, info.Gender
+
+	screened := a.screener.Screen(u.FirstName+" "+u.LastName, u.IIN)
+
+	if screened.Action == domain.ScreeningBlock {
+		if err := a.recordCase(ctx, u, screened); err != nil {
+			return err
+		}
+		return domain.ErrScreeningBlocked
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Warn().Err(err).Msgf("incorrect encryption for email: %s", u.Email)
@@ -98,7 +149,101 @@ func (a *authUseCase) CreateUser(ctx context.Context, u *domain.User) error {
 
 	u.Password = string(hashedPassword)
 
-	return a.db.CreateUser(ctx, u)
+	if err := a.db.CreateUser(ctx, u); err != nil {
+		return err
+	}
+
+	if screened.Action == domain.ScreeningFlag {
+		if err := a.recordCase(ctx, u, screened); err != nil {
+			log.Error().Err(err).Int64("user", u.ID).Msg("cannot record compliance case of flagged user")
+		}
+	}
+
+	return nil
+}
+
+func (a *authUseCase) recordCase(ctx context.Context, u *domain.User, screened *domain.ScreeningResult) error {
+	c := &domain.ComplianceCase{
+		UserID:  u.ID,
+		Email:   u.Email,
+		Name:    u.FirstName + " " + u.LastName,
+		IIN:     u.IIN,
+		Action:  screened.Action,
+		Matches: screened.Matches,
+		Status:  domain.CaseOpen,
+		Created: time.Now(),
+	}
+
+	if err := a.db.CreateCase(ctx, c); err != nil {
+		return err
+	}
+
+	log.Warn().
+		Str("event", "screening_match").
+		Int64("case", c.ID).
+		Int64("user", c.UserID).
+		Str("action", c.Action).
+		Msg("security: sign-up matched sanctions list")
+
+	return nil
+}
+
+func (a *authUseCase) ComplianceCases(ctx context.Context, status string, limit, offset int) ([]*domain.ComplianceCase, error) {
+	return a.db.Cases(ctx, status, limit, offset)
+}
+
+// ResolveCase only records the outcome, a cleared blocked user has to sign up again.
+func (a *authUseCase) ResolveCase(ctx context.Context, officer *domain.User, ID int64, status, note string) (*domain.ComplianceCase, error) {
+	now := time.Now()
+
+	c := &domain.ComplianceCase{
+		ID:         ID,
+		Status:     status,
+		Note:       strings.TrimSpace(note),
+		Resolved:   &now,
+		ResolvedBy: officer.ID,
+	}
+
+	if err := a.db.ResolveCase(ctx, c); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("event", "case_resolved").
+		Int64("case", c.ID).
+		Int64("officer", officer.ID).
+		Str("status", c.Status).
+		Msg("security: compliance case resolved")
+
+	return c, nil
+}
+
+func (a *authUseCase) ListUsers(ctx context.Context, limit, offset int) ([]*domain.User, error) {
+	return a.db.ListUsers(ctx, limit, offset)
+}
+
+// ChangeRole takes effect on the user's next token refresh.
+func (a *authUseCase) ChangeRole(ctx context.Context, requester *domain.User, ID int64, role string) error {
+	if !rbac.ValidRole(role) {
+		return domain.ErrInvalidRole
+	}
+
+	if requester.ID == ID {
+		return domain.ErrOwnRole
+	}
+
+	if err := a.db.ChangeRole(ctx, ID, role); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("event", "role_changed").
+		Int64("admin", requester.ID).
+		Int64("user", ID).
+		Str("role", role).
+		Msg("security: user role changed")
+
+	return nil
 }
 
 func (a *authUseCase) Close() {
@@ -120,64 +265,120 @@ func (a *authUseCase) ExtractToken(AuthorizationHeader string) (string, error) {
 }
 
 func (a *authUseCase) ParseToken(token string, isAccess bool) (*domain.User, error) {
+	user, _, err := a.parseClaims(token, isAccess)
+	return user, err
+}
+
+// parseRefreshToken returns the owner of a refresh token with its family and token IDs.
+func (a *authUseCase) parseRefreshToken(token string) (*domain.User, string, string, error) {
+	user, claims, err := a.parseClaims(token, false)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	familyID, ok := claims["fid"].(string)
+	if !ok || familyID == "" {
+		return nil, "", "", domain.ErrInvalidToken
+	}
+
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return nil, "", "", domain.ErrInvalidToken
+	}
+
+	return user, familyID, tokenID, nil
+}
+
+func (a *authUseCase) parseClaims(token string, isAccess bool) (*domain.User, jwt.MapClaims, error) {
 
 	JWTToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("failed to extract token metadata, unexpected signing method: %v", token.Header["alg"])
 		}
-		if isAccess {
-			return []byte(a.accessSecret), nil
+
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := a.keys.PublicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
 		}
-		return []byte(a.refreshSecret), nil
+		return key, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	claims, ok := JWTToken.Claims.(jwt.MapClaims)
 
 	if ok && JWTToken.Valid {
 
+		typ, _ := claims["typ"].(string)
+		if (isAccess && typ != accessTokenType) || (!isAccess && typ != refreshTokenType) {
+			return nil, nil, domain.ErrInvalidToken
+		}
+
 		var userID float64
 
 		userID, ok = claims["id"].(float64)
 		if !ok {
-			return nil, domain.ErrInvalidToken
+			return nil, nil, domain.ErrInvalidToken
 		}
 
 		exp, ok := claims["exp"].(float64)
 		if !ok {
-			return nil, domain.ErrInvalidToken
+			return nil, nil, domain.ErrInvalidToken
 		}
 
 		role, ok := claims["role"].(string)
-		if !ok || (role != "user" && role != "admin") {
-			return nil, domain.ErrInvalidToken
+		if !ok || !rbac.ValidRole(role) {
+			return nil, nil, domain.ErrInvalidToken
 		}
 
 		iin, ok := claims["iin"].(string)
 		if !ok {
-			return nil, domain.ErrInvalidToken
+			return nil, nil, domain.ErrInvalidToken
 		}
 
+		sessionID, _ := claims["sid"].(string)
+
 		expiredTime := time.Unix(int64(exp), 0)
 
 		if time.Now().After(expiredTime) {
-			return nil, domain.ErrExpiredToken
+			return nil, nil, domain.ErrExpiredToken
 		}
 		return &domain.User{
-			ID:   int64(userID),
-			Role: role,
-			IIN:  iin,
-		}, nil
+			ID:        int64(userID),
+			Role:      role,
+			IIN:       iin,
+			SessionID: sessionID,
+		}, claims, nil
 	}
 
-	return nil, domain.ErrInvalidToken
+	return nil, nil, domain.ErrInvalidToken
 }
 
 // GenerateAndSendTokens return access token and refresh token in that order.
-func (a *authUseCase) GenerateAndSendTokens(u *domain.User) (string, string, error) {
+// Every call starts a new session with its own refresh token family.
+func (a *authUseCase) GenerateAndSendTokens(u *domain.User, s *domain.Session) (string, string, error) {
+	s.ID, s.UserID = newTokenID(), u.ID
+	s.Created, s.LastUsed = time.Now(), time.Now()
+
+	tokenID := newTokenID()
+
+	accessSignedToken, refreshSignedToken, err := a.signTokens(u, s.ID, tokenID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := a.cache.CreateSession(s, tokenID); err != nil {
+		return "", "", domain.ErrTokenNotCreated
+	}
+	return accessSignedToken, refreshSignedToken, nil
+}
+
+func (a *authUseCase) signTokens(u *domain.User, familyID, tokenID string) (string, string, error) {
+	key := a.keys.SigningKey()
 
 	accessTokenExp := time.Now().Add(a.accessTokenTTL).Unix()
 
@@ -187,9 +388,12 @@ func (a *authUseCase) GenerateAndSendTokens(u *domain.User) (string, string, err
 	accessTokenClaims["exp"] = accessTokenExp
 	accessTokenClaims["role"] = u.Role
 	accessTokenClaims["iin"] = u.IIN
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
+	accessTokenClaims["sid"] = familyID
+	accessTokenClaims["typ"] = accessTokenType
+	accessToken := jwt.NewWithClaims(jwt.SigningMethodRS256, accessTokenClaims)
+	accessToken.Header["kid"] = key.ID
 
-	accessSignedToken, err := accessToken.SignedString([]byte(a.accessSecret))
+	accessSignedToken, err := accessToken.SignedString(key.Private)
 	if err != nil {
 		return "", "", domain.ErrTokenNotCreated
 	}
@@ -201,35 +405,112 @@ func (a *authUseCase) GenerateAndSendTokens(u *domain.User) (string, string, err
 	refreshTokenClaims["exp"] = refreshTokenExp
 	refreshTokenClaims["role"] = u.Role
 	refreshTokenClaims["iin"] = u.IIN
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
+	refreshTokenClaims["fid"] = familyID
+	refreshTokenClaims["jti"] = tokenID
+	refreshTokenClaims["typ"] = refreshTokenType
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodRS256, refreshTokenClaims)
+	refreshToken.Header["kid"] = key.ID
 
-	refreshSignedToken, err := refreshToken.SignedString([]byte(a.refreshSecret))
+	refreshSignedToken, err := refreshToken.SignedString(key.Private)
 	if err != nil {
 		return "", "", domain.ErrTokenNotCreated
 	}
 
-	if err := a.cache.InsertToken(int64(u.ID), refreshSignedToken); err != nil {
-		return "", "", domain.ErrTokenNotCreated
-	}
 	return accessSignedToken, refreshSignedToken, nil
 }
 
 // UpdateToken returns access token and refresh token (in that order).
-func (a *authUseCase) UpdateToken(refreshToken string) (string, string, error) {
+// Presenting a refresh token that was already rotated revokes its whole family.
+func (a *authUseCase) UpdateToken(refreshToken string, s *domain.Session) (string, string, error) {
 
 	//checking update token
-	user, err := a.ParseToken(refreshToken, false)
+	user, familyID, tokenID, err := a.parseRefreshToken(refreshToken)
 	if err != nil {
 		return "", "", err
 	}
 
-	// find token in Redis
-	ok := a.cache.FindToken(int64(user.ID), refreshToken)
-	if !ok {
+	// role could have been changed since the token was issued
+	stored, err := a.db.GetUserData(context.Background(), user.ID)
+	if err != nil || stored == nil {
 		return "", "", domain.ErrInvalidToken
 	}
+	user.Role = stored.Role
+
+	nextID := newTokenID()
+
+	accessSignedToken, refreshSignedToken, err := a.signTokens(user, familyID, nextID)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.ID, s.UserID, s.LastUsed = familyID, user.ID, time.Now()
 
-	return a.GenerateAndSendTokens(user)
+	err = a.cache.RotateToken(s, tokenID, nextID)
+	switch err {
+	case nil:
+		return accessSignedToken, refreshSignedToken, nil
+
+	case domain.ErrTokenReused:
+		log.Warn().
+			Str("event", "refresh_token_reuse").
+			Int64("user", user.ID).
+			Str("family", familyID).
+			Msg("security: refresh token reused, revoking token family")
+
+		if err := a.cache.RevokeSession(user.ID, familyID); err != nil {
+			log.Error().Err(err).Str("family", familyID).Msg("cannot revoke token family")
+		}
+		return "", "", domain.ErrInvalidToken
+
+	case domain.ErrInvalidToken:
+		return "", "", err
+
+	default:
+		log.Warn().Err(err).Msg("cannot rotate refresh token")
+		return "", "", domain.ErrTokenNotCreated
+	}
+}
+
+func (a *authUseCase) Sessions(userID int64) ([]*domain.Session, error) {
+	sessions, err := a.cache.Sessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsed.After(sessions[j].LastUsed)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession ends a session of the user. Access tokens already issued
+// for it stay valid until they expire.
+func (a *authUseCase) RevokeSession(userID int64, sessionID string) error {
+	return a.cache.RevokeSession(userID, sessionID)
+}
+
+func (a *authUseCase) RevokeSessions(userID int64) error {
+	return a.cache.RevokeSessions(userID)
+}
+
+// Logout ends the session the refresh token belongs to.
+func (a *authUseCase) Logout(refreshToken string) error {
+	user, sessionID, _, err := a.parseRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return a.cache.RevokeSession(user.ID, sessionID)
+}
+
+// newTokenID returns random identifier for a token or a token family.
+func newTokenID() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
 }
 
 func getRandomSecret() string {
@@ -244,6 +525,11 @@ func getRandomSecret() string {
 	return string(str)
 }
 
+// PublicKeys returns keys verifying tokens issued by the service.
+func (a *authUseCase) PublicKeys() *domain.JWKSet {
+	return a.keys.PublicKeys()
+}
+
 func (a *authUseCase) GetAccessTokenTTL() time.Duration {
 	return a.accessTokenTTL
 }
@@ -0,0 +1,112 @@
+// Command keys manages keys that sign tokens.
+//
+//	keys list    shows keys and their state
+//	keys rotate  introduces a new signing key and deletes expired ones
+//	keys prune   deletes keys that can't verify any unexpired token
+//
+// Running auth service picks up changes on its next keys reload.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"auth-service/auth/repository/keys"
+	"auth-service/domain"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	configPath string
+)
+
+func init() {
+	flag.StringVar(&configPath, "config-path", "configs/server.toml", "path to config file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] list|rotate|prune\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	config := domain.NewConfig()
+
+	_, err := toml.DecodeFile(configPath, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot parse config file")
+	}
+
+	store, err := keys.NewDirKeyStore(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot open keys")
+	}
+
+	switch flag.Arg(0) {
+	case "list":
+		list(store)
+
+	case "rotate":
+		key, err := store.Rotate()
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot rotate keys")
+		}
+
+		log.Info().
+			Str("kid", key.ID).
+			Time("signs_from", key.Created.Add(config.KeyPublishDelay.Duration)).
+			Msg("new signing key introduced")
+
+		prune(store)
+
+	case "prune":
+		prune(store)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func list(store domain.KeyStore) {
+	signing := store.SigningKey()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tCREATED\tRETIRED\tSTATE")
+
+	for _, k := range store.Keys() {
+		state := "verifying"
+		switch {
+		case k.ID == signing.ID:
+			state = "signing"
+		case k.Created.After(signing.Created):
+			state = "published"
+		}
+
+		retired := "-"
+		if !k.Retired.IsZero() {
+			retired = k.Retired.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.ID, k.Created.Format(time.RFC3339), retired, state)
+	}
+
+	w.Flush()
+}
+
+func prune(store domain.KeyStore) {
+	pruned, err := store.Prune()
+	for _, kid := range pruned {
+		log.Info().Str("kid", kid).Msg("expired key deleted")
+	}
+
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot prune keys")
+	}
+}
@@ -37,6 +37,10 @@ func main() {
 		log.Fatal().Err(err).Msg("cannot parse config file")
 	}
 
+	if err := config.CheckSecrets(); err != nil {
+		log.Fatal().Err(err).Msg("cannot start")
+	}
+
 	router := chi.NewRouter()
 
 	authUseCase, err := authUseCase.New(config)
@@ -44,7 +48,7 @@ func main() {
 		log.Fatal().Err(err).Msg("cannot start")
 	}
 
-	delivery.NewAuthHandler(router, authUseCase)
+	delivery.NewAuthHandler(config, router, authUseCase)
 
 	server := &http.Server{
 		Addr:         config.BindAddr,
@@ -54,6 +58,26 @@ func main() {
 		IdleTimeout:  15 * time.Second,
 	}
 
+	// serving other services apart from users, without internal_token it is off
+	if config.InternalToken != "" {
+		internalRouter := chi.NewRouter()
+		delivery.NewInternalHandler(config, internalRouter, authUseCase)
+
+		internal := &http.Server{
+			Addr:         config.InternalBindAddr,
+			Handler:      internalRouter,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  15 * time.Second,
+		}
+
+		go func() {
+			if err := internal.ListenAndServe(); err != nil {
+				log.Fatal().Err(err).Msg("internal listener failed")
+			}
+		}()
+	}
+
 	log.Info().Msg("databases connected")
 
 	if err := server.ListenAndServe(); err != nil {
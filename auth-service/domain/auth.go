@@ -8,14 +8,27 @@ import (
 // AuthUseCase is bussiness logic over our auth service.
 type AuthUseCase interface {
 	// Login(username, password string) (*User, error)
-	GenerateAndSendTokens(u *User) (string, string, error)
+	GenerateAndSendTokens(u *User, s *Session) (string, string, error)
 	ExtractToken(authorizationHeader string) (string, error)
 	ParseToken(token string, isAccess bool) (*User, error)
-	UpdateToken(refreshToken string) (string, string, error)
+	UpdateToken(refreshToken string, s *Session) (string, string, error)
+	PublicKeys() *JWKSet
+
+	Sessions(userID int64) ([]*Session, error)
+	RevokeSession(userID int64, sessionID string) error
+	RevokeSessions(userID int64) error
+	Logout(refreshToken string) error
 
 	FindUser(ctx context.Context, email, password string) (*User, error)
 	CreateUser(ctx context.Context, u *User) error
 	GetUserData(ctx context.Context, ID int64) (*User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]*User, error)
+	ChangeRole(ctx context.Context, requester *User, ID int64, role string) error
+
+	// ComplianceCases lists sign-ups flagged or blocked by screening, empty status is every status.
+	ComplianceCases(ctx context.Context, status string, limit, offset int) ([]*ComplianceCase, error)
+	// ResolveCase clears or confirms an open case, it is meant for compliance officers.
+	ResolveCase(ctx context.Context, officer *User, ID int64, status, note string) (*ComplianceCase, error)
 
 	GetAccessTokenTTL() time.Duration
 	GetRefreshTokenTTL() time.Duration
@@ -24,12 +37,18 @@ type AuthUseCase interface {
 }
 
 // CaсheStore is representing NoSQL database for cache.
-// In our case, it is used to keep refresh tokens.
+// In our case, it is used to keep sessions.
+// Every login starts a session with its own refresh token family
+// and every refresh rotates the current token, so a token can be exchanged only once.
 // At this moment it is Redis, but can be updated anytime,
 // so bussiness logic will not be affected.
 type CaсheStore interface {
-	FindToken(id int64, token string) bool
-	InsertToken(id int64, token string) error
+	CreateSession(s *Session, tokenID string) error
+	// RotateToken returns ErrTokenReused when tokenID is not the current token of the session.
+	RotateToken(s *Session, tokenID, nextID string) error
+	Sessions(userID int64) ([]*Session, error)
+	RevokeSession(userID int64, sessionID string) error
+	RevokeSessions(userID int64) error
 }
 
 // Repository is representing database.
@@ -39,5 +58,11 @@ type Repository interface {
 	FindUser(ctx context.Context, email string) (*User, error)
 	CreateUser(ctx context.Context, u *User) error
 	GetUserData(ctx context.Context, ID int64) (*User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]*User, error)
+	ChangeRole(ctx context.Context, ID int64, role string) error
+	CreateCase(ctx context.Context, c *ComplianceCase) error
+	Cases(ctx context.Context, status string, limit, offset int) ([]*ComplianceCase, error)
+	// ResolveCase stores the status, note and resolver of an open case.
+	ResolveCase(ctx context.Context, c *ComplianceCase) error
 	CloseConnection()
 }
@@ -1,6 +1,13 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// UnsetSecret stands for secrets in the sample configs, the service
+// doesn't start until it is replaced.
+const UnsetSecret = "change-me"
 
 // Config
 type Config struct {
@@ -16,10 +23,23 @@ type Config struct {
 	CachePassword string `toml:"cashe_password"`
 	CacheAddr     string `toml:"cache_addr"`
 
-	AccessTokenSecret  string   `toml:"access_token_secret"`
-	RefreshTokenSecret string   `toml:"refresh_token_secret"`
+	KeysDir            string   `toml:"keys_dir"`
+	KeyPublishDelay    duration `toml:"key_publish_delay"`
+	KeysReloadInterval duration `toml:"keys_reload_interval"`
 	AccessTokenTTL     duration `toml:"access_token_ttl"`
 	RefreshTokenTTL    duration `toml:"refresh_token_ttl"`
+
+	// InternalToken authenticates other services on /internal, empty turns it off.
+	// The internal API is served on InternalBindAddr only, apart from the public one.
+	InternalToken    string `toml:"internal_token"`
+	InternalBindAddr string `toml:"internal_bind_addr"`
+
+	// MinAge is the age in full years users must be to sign up.
+	MinAge int `toml:"min_age"`
+
+	ScreeningLists      []string `toml:"screening_lists"`
+	ScreeningFlagScore  float64  `toml:"screening_flag_score"`
+	ScreeningBlockScore float64  `toml:"screening_block_score"`
 }
 
 type duration struct {
@@ -46,7 +66,27 @@ func NewConfig() *Config {
 		CachePassword: "",
 		CacheAddr:     ":6379",
 
-		AccessTokenTTL:  duration{10 * time.Minute},
-		RefreshTokenTTL: duration{1 * time.Hour},
+		KeysDir:            "configs/keys",
+		KeyPublishDelay:    duration{10 * time.Minute},
+		KeysReloadInterval: duration{1 * time.Minute},
+		AccessTokenTTL:     duration{10 * time.Minute},
+		RefreshTokenTTL:    duration{1 * time.Hour},
+
+		InternalToken:    UnsetSecret,
+		InternalBindAddr: ":7576",
+
+		MinAge: 18,
+
+		ScreeningLists:      []string{"../shared/sanctions/un.xml", "../shared/sanctions/local.csv"},
+		ScreeningFlagScore:  0.88,
+		ScreeningBlockScore: 0.98,
+	}
+}
+
+// CheckSecrets refuses secrets left as UnsetSecret.
+func (c *Config) CheckSecrets() error {
+	if c.InternalToken == UnsetSecret {
+		return fmt.Errorf("internal_token is %q, set a token of its own", UnsetSecret)
 	}
+	return nil
 }
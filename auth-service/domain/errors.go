@@ -18,4 +18,23 @@ var ErrInvalidToken = errors.New("invalid token")
 
 var ErrExpiredToken = errors.New("expired token")
 
+var ErrInvalidRole = errors.New("invalid role")
+
+// ErrOwnRole - admins can't change their own role and lock themselves out.
+var ErrOwnRole = errors.New("can't change own role")
+
 var ErrTokenNotCreated = errors.New("failed to create token")
+
+// ErrTokenReused - refresh token was already exchanged for a new one.
+var ErrTokenReused = errors.New("refresh token reused")
+
+// ErrScreeningBlocked - the user is on a sanctions list.
+// The sign-up is refused without telling why.
+var ErrScreeningBlocked = errors.New("sign-up is refused")
+
+var ErrCaseNotFound = errors.New("compliance case not found")
+
+var ErrCaseResolved = errors.New("compliance case was already resolved")
+
+// ErrUnderage - the user is younger than the minimum age.
+var ErrUnderage = errors.New("user is under the minimum age")
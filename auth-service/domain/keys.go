@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"crypto/rsa"
+	"time"
+)
+
+// KeyStore is representing storage of keys used to sign tokens.
+// Only auth service holds private keys, other services verify tokens
+// with public keys published as JWKS.
+type KeyStore interface {
+	SigningKey() *SigningKey
+	PublicKey(kid string) (*rsa.PublicKey, bool)
+	PublicKeys() *JWKSet
+
+	Keys() []*SigningKey
+	Rotate() (*SigningKey, error)
+	Prune() ([]string, error)
+}
+
+// SigningKey is RSA key identified by its JWK thumbprint.
+// Tokens name the key they were signed with in the kid header.
+type SigningKey struct {
+	ID      string
+	Private *rsa.PrivateKey
+	Created time.Time
+	// Retired is the moment a newer key took over signing,
+	// zero for the newest key.
+	Retired time.Time
+}
+
+// JWK is public RSA key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
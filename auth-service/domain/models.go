@@ -3,6 +3,8 @@ package domain
 import (
 	"regexp"
 	"time"
+
+	"shared/iin"
 )
 
 type User struct {
@@ -12,10 +14,28 @@ type User struct {
 	FirstName  string    `json:"FirstName,omitempty"`
 	LastName   string    `json:"LastName,omitempty"`
 	IIN        string    `json:"IIN,omitempty"`
+	BirthDate  time.Time `json:"BirthDate,omitempty"`
+	Gender     string    `json:"Gender,omitempty"`
 	Registered time.Time `json:"Registered,omitempty"`
 	Phone      string    `json:"Phone,omitempty"`
 	Role       string    `json:"Role,omitempty"`
 	Wallets    string    `json:"Wallets,omitempty"`
+
+	// SessionID is the session the access token was issued for.
+	SessionID string `json:"-"`
+}
+
+// Session is a login on a single device.
+// Its ID is the ID of the refresh token family.
+type Session struct {
+	ID        string    `json:"ID,omitempty"`
+	UserID    int64     `json:"UserID,omitempty"`
+	Device    string    `json:"Device,omitempty"`
+	IP        string    `json:"IP,omitempty"`
+	UserAgent string    `json:"UserAgent,omitempty"`
+	Created   time.Time `json:"Created,omitempty"`
+	LastUsed  time.Time `json:"LastUsed,omitempty"`
+	Current   bool      `json:"Current,omitempty"`
 }
 
 func (u *User) Valid() bool {
@@ -28,7 +48,7 @@ func (u *User) Valid() bool {
 		return false
 	}
 
-	if len(u.IIN) < 12 || len(u.Phone) < 11 {
+	if _, err := iin.Parse(u.IIN); err != nil || len(u.Phone) < 11 {
 		return false
 	}
 
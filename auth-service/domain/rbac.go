@@ -0,0 +1,8 @@
+package domain
+
+import "shared/rbac"
+
+// Can reports whether the user's role grants the permission.
+func (u *User) Can(p rbac.Permission) bool {
+	return rbac.Can(u.Role, p)
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"shared/screening"
+)
+
+// Actions of sanctions screening, from the mildest. A flagged party goes on,
+// a blocked one is refused. Either way a compliance case is recorded.
+const (
+	ScreeningClear = screening.Clear
+	ScreeningFlag  = screening.Flag
+	ScreeningBlock = screening.Block
+)
+
+// Fields a party is matched by.
+const (
+	MatchName = screening.MatchName
+	MatchIIN  = screening.MatchIIN
+)
+
+// The lists and the matching are shared with the other service,
+// see the screening package of the shared module.
+type (
+	SanctionEntry   = screening.Entry
+	ScreeningMatch  = screening.Match
+	ScreeningResult = screening.Result
+)
+
+// Screener matches parties against sanctions lists.
+type Screener interface {
+	// Screen matches the name and the IIN, either may be empty.
+	Screen(name, iin string) *ScreeningResult
+}
+
+// Statuses of compliance cases. An officer either clears a case
+// as a false positive or confirms the match.
+const (
+	CaseOpen      = "open"
+	CaseCleared   = "cleared"
+	CaseConfirmed = "confirmed"
+)
+
+// ComplianceCase records a sign-up flagged or blocked by screening.
+// UserID is zero when the sign-up was blocked.
+type ComplianceCase struct {
+	ID         int64            `json:"ID"`
+	UserID     int64            `json:"UserID,omitempty"`
+	Email      string           `json:"Email"`
+	Name       string           `json:"Name"`
+	IIN        string           `json:"IIN"`
+	Action     string           `json:"Action"`
+	Matches    []ScreeningMatch `json:"Matches"`
+	Status     string           `json:"Status"`
+	Note       string           `json:"Note,omitempty"`
+	Created    time.Time        `json:"Created"`
+	Resolved   *time.Time       `json:"Resolved,omitempty"`
+	ResolvedBy int64            `json:"ResolvedBy,omitempty"`
+}
@@ -12,6 +12,7 @@ require (
 	github.com/jackc/pgx/v4 v4.14.1
 	github.com/rs/zerolog v1.26.0
 	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b
+	shared v0.0.0
 )
 
 require (
@@ -26,3 +27,5 @@ require (
 	github.com/onsi/gomega v1.17.0 // indirect
 	golang.org/x/text v0.3.6 // indirect
 )
+
+replace shared => ../shared
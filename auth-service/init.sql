@@ -5,8 +5,58 @@ CREATE TABLE IF NOT EXISTS users (
     LastName VARCHAR NOT NULL,
     Password VARCHAR NOT NULL,
     IIN VARCHAR NOT NULL UNIQUE,
+    BirthDate DATE NOT NULL,
+    Gender VARCHAR NOT NULL,
     Phone VARCHAR NOT NULL UNIQUE,
     Registered TIMESTAMP NOT NULL,
     Role VARCHAR NOT NULL,
     CONSTRAINT users_pk PRIMARY KEY (ID)
-);
\ No newline at end of file
+);
+
+-- Users who signed up before the IIN was decoded get the birth date and
+-- gender from it, the same way shared/iin does: YYMMDD, then the century
+-- and gender digit, odd for men. A user whose IIN doesn't decode keeps
+-- NULLs and stops the migration at SET NOT NULL, correct the IIN and rerun.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS BirthDate DATE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS Gender VARCHAR;
+
+WITH decoded AS MATERIALIZED (
+    SELECT ID,
+        1800 + (substr(IIN, 7, 1)::int - 1) / 2 * 100 + substr(IIN, 1, 2)::int AS BirthYear,
+        substr(IIN, 3, 2)::int AS BirthMonth,
+        substr(IIN, 5, 2)::int AS BirthDay,
+        substr(IIN, 7, 1)::int AS Century
+    FROM users
+    WHERE BirthDate IS NULL
+        AND IIN ~ '^[0-9]{6}[1-6][0-9]{5}$'
+        AND substr(IIN, 3, 2) BETWEEN '01' AND '12'
+)
+UPDATE users SET
+    BirthDate = make_date(BirthYear, BirthMonth, 1) + (BirthDay - 1),
+    Gender = CASE WHEN Century % 2 = 1 THEN 'male' ELSE 'female' END
+FROM decoded
+WHERE users.ID = decoded.ID
+    AND BirthDay BETWEEN 1 AND extract(day FROM make_date(BirthYear, BirthMonth, 1) + interval '1 month - 1 day');
+
+ALTER TABLE users
+    ALTER COLUMN BirthDate SET NOT NULL,
+    ALTER COLUMN Gender SET NOT NULL;
+
+-- Sign-ups flagged or blocked by sanctions screening, Matches are
+-- domain.ScreeningMatch values. UserID is NULL when the sign-up was blocked.
+CREATE TABLE IF NOT EXISTS compliance_cases (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    UserID BIGINT REFERENCES users (ID),
+    Email VARCHAR NOT NULL,
+    Name VARCHAR NOT NULL,
+    IIN VARCHAR NOT NULL,
+    Action VARCHAR NOT NULL,
+    Matches JSONB NOT NULL,
+    Status VARCHAR NOT NULL,
+    Note VARCHAR NOT NULL DEFAULT '',
+    Created TIMESTAMP NOT NULL,
+    Resolved TIMESTAMP,
+    ResolvedBy BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS compliance_cases_status_idx ON compliance_cases (Status, ID);
@@ -0,0 +1,51 @@
+// Command events prints domain events published to NATS,
+// one JSON object per line, to check the relay against a local broker:
+//
+//	docker compose up -d nats
+//	go run ./cmd/events -nats-url nats://localhost:4222
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+
+	"money-transfer/domain"
+	"money-transfer/transfer/broker"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	natsURL string
+	subject string
+)
+
+func init() {
+	flag.StringVar(&natsURL, "nats-url", "nats://localhost:4222", "NATS server")
+	flag.StringVar(&subject, "subject", "money-transfer.events", "subject prefix of events")
+}
+
+func main() {
+	flag.Parse()
+
+	b, err := broker.DialNATS(natsURL, subject)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to nats")
+	}
+	defer b.Close()
+
+	out := json.NewEncoder(os.Stdout)
+
+	err = b.Subscribe(func(e *domain.Event) {
+		out.Encode(e)
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot subscribe")
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
@@ -0,0 +1,146 @@
+// Command iso8583 sends a test message to the card authorization simulator
+// and prints the request and the answer, one field per line. Card messages
+// are signed with the key of the terminal, the one of configs/server.toml by default:
+//
+//	go run ./cmd/iso8583 -mti 0100 -pan 4400431234567894 -expiry 2910 -cvv 123 -amount 150000 -rrn 000000000001
+//	go run ./cmd/iso8583 -mti 0200 -pan 4400431234567894 -expiry 2910 -amount 120000 -rrn 000000000001
+//	go run ./cmd/iso8583 -mti 0400 -pan 4400431234567894 -rrn 000000000001
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"money-transfer/transfer/iso8583"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	addr     string
+	mti      string
+	pan      string
+	expiry   string
+	cvv      string
+	amount   int64
+	currency string
+	terminal string
+	merchant string
+	key      string
+	rrn      string
+	timeout  time.Duration
+)
+
+func init() {
+	flag.StringVar(&addr, "addr", "localhost:8583", "address of the simulator")
+	flag.StringVar(&mti, "mti", "0100", "0100 authorization, 0200 purchase or completion, 0400 reversal, 0800 echo")
+	flag.StringVar(&pan, "pan", "", "card number")
+	flag.StringVar(&expiry, "expiry", "", "expiry date of the card, YYMM")
+	flag.StringVar(&cvv, "cvv", "", "CVV2 of the card, sent in field 48")
+	flag.Int64Var(&amount, "amount", 0, "amount in minor units")
+	flag.StringVar(&currency, "currency", "398", "ISO 4217 numeric currency code, empty is the currency of the card")
+	flag.StringVar(&terminal, "terminal", "TERM0001", "terminal ID")
+	flag.StringVar(&merchant, "merchant", "MERCHANT0000001", "merchant ID")
+	flag.StringVar(&key, "key", "", "key of the terminal the message is signed with")
+	flag.StringVar(&rrn, "rrn", "", "retrieval reference number, a fresh one by default")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "time to wait for the answer")
+}
+
+func main() {
+	flag.Parse()
+
+	now := time.Now()
+	stan := fmt.Sprintf("%06d", rand.New(rand.NewSource(now.UnixNano())).Intn(1000000))
+
+	if rrn == "" {
+		// YDDD, hour and STAN like terminals make them
+		rrn = now.Format("06")[1:] + fmt.Sprintf("%03d", now.YearDay()) + now.Format("15") + stan
+	}
+
+	req := iso8583.NewMessage(mti)
+	req.Set(iso8583.FieldTransmittedAt, now.UTC().Format("0102150405"))
+	req.Set(iso8583.FieldSTAN, stan)
+
+	if mti == "0800" {
+		req.Set(iso8583.FieldNetworkCode, "301")
+	} else {
+		req.Set(iso8583.FieldPAN, pan)
+		req.Set(iso8583.FieldProcessingCode, "000000")
+		req.Set(iso8583.FieldAmount, fmt.Sprint(amount))
+		req.Set(iso8583.FieldLocalTime, now.Format("150405"))
+		req.Set(iso8583.FieldLocalDate, now.Format("0102"))
+		req.Set(iso8583.FieldRRN, rrn)
+		req.Set(iso8583.FieldTerminalID, terminal)
+		req.Set(iso8583.FieldMerchantID, merchant)
+
+		for field, value := range map[int]string{
+			iso8583.FieldExpiry:         expiry,
+			iso8583.FieldAdditionalData: cvv,
+			iso8583.FieldCurrency:       currency,
+		} {
+			if value != "" {
+				req.Set(field, value)
+			}
+		}
+
+		if err := req.Sign([]byte(key)); err != nil {
+			log.Fatal().Err(err).Msg("cannot sign the message")
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to the simulator")
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	dump(">>", req)
+
+	if err := iso8583.WriteMessage(conn, req); err != nil {
+		log.Fatal().Err(err).Msg("cannot send the message")
+	}
+
+	resp, err := iso8583.ReadMessage(conn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("no answer")
+	}
+
+	dump("<<", resp)
+
+	// declines of unknown terminals and bad MACs come unsigned
+	if resp.Has(iso8583.FieldMAC) && !resp.Verify([]byte(key)) {
+		log.Fatal().Msg("the answer is not signed by the simulator")
+	}
+
+	if resp.Get(iso8583.FieldResponseCode) != iso8583.Approved {
+		os.Exit(1)
+	}
+}
+
+func dump(direction string, m *iso8583.Message) {
+	fmt.Println(direction, "MTI", m.MTI)
+
+	fields := make([]int, 0, len(m.Fields))
+	for f := range m.Fields {
+		fields = append(fields, f)
+	}
+	sort.Ints(fields)
+
+	for _, f := range fields {
+		value := m.Fields[f]
+		if f == iso8583.FieldPAN && len(value) > 10 {
+			value = value[:6] + "******" + value[len(value)-4:]
+		}
+		if f == iso8583.FieldAdditionalData {
+			value = "***"
+		}
+		fmt.Printf("%s %3d %q\n", direction, f, value)
+	}
+}
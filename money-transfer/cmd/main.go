@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"net/http"
 	"time"
@@ -10,6 +11,7 @@ import (
 
 	"money-transfer/domain"
 	"money-transfer/transfer/delivery"
+	"money-transfer/transfer/iso8583"
 	transferUseCase "money-transfer/transfer/usecase"
 
 	"github.com/BurntSushi/toml"
@@ -34,12 +36,40 @@ func main() {
 		log.Fatal().Err(err).Msg("cannot parse config file")
 	}
 
+	// refusing to run with the secrets of the sample config
+	if err := config.CheckSecrets(); err != nil {
+		log.Fatal().Err(err).Msg("cannot start app")
+	}
+
 	// setting up bussiness logic
 	usecase, err := transferUseCase.New(config)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot start app")
 	}
 
+	// refusing to serve money from books that don't balance
+	if err := usecase.CheckLedger(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("ledger check failed")
+	}
+
+	// running standing orders in the background
+	go usecase.RunScheduler(context.Background())
+
+	// publishing domain events written to the outbox
+	go usecase.RunRelay(context.Background())
+
+	// sending events to webhooks
+	go usecase.RunWebhooks(context.Background())
+
+	// answering card terminals
+	if config.ISO8583Addr != "" {
+		go func() {
+			if err := iso8583.NewServer(config, usecase).ListenAndServe(); err != nil {
+				log.Fatal().Err(err).Msg("iso8583 listener failed")
+			}
+		}()
+	}
+
 	// connecting delivery layer
 	router := chi.NewRouter()
 
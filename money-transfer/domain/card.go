@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of cards. An active card is expired once Expires passes,
+// expired cards are never written back.
+const (
+	CardActive  = "active"
+	CardBlocked = "blocked"
+	CardExpired = "expired"
+)
+
+// PANs of issued cards are a 6 digit BIN, 9 digits of the card and a Luhn check digit.
+const (
+	panLength = 16
+	binLength = 6
+)
+
+// Card is a virtual card of an account. PAN and CVV are shown only when
+// the card is issued, the service keeps a keyed hash of the PAN to find
+// the card by, its BIN and last four digits, and a bcrypt hash of the CVV.
+// The card is valid through the month before Expires.
+type Card struct {
+	ID            int64     `json:"ID"`
+	AccountID     int64     `json:"-"`
+	OwnerID       int64     `json:"-"`
+	AccountNumber string    `json:"AccountNumber"`
+	PAN           string    `json:"PAN,omitempty"`
+	CVV           string    `json:"CVV,omitempty"`
+	BIN           string    `json:"BIN"`
+	Last4         string    `json:"Last4"`
+	PANHash       string    `json:"-"`
+	CVVHash       string    `json:"-"`
+	Status        string    `json:"Status"`
+	Created       time.Time `json:"Created"`
+	Expires       time.Time `json:"Expires"`
+}
+
+// Expiry is the MM/YY printed on the card.
+func (c *Card) Expiry() string {
+	return c.Expires.Add(-time.Nanosecond).Format("01/06")
+}
+
+// NewPAN completes the BIN and the digits of the card with the check digit.
+func NewPAN(bin, digits string) (string, error) {
+	payload := bin + digits
+	if !ValidBIN(bin) || len(payload) != panLength-1 || !allDigits(payload) {
+		return "", ErrInvalidPAN
+	}
+
+	return payload + string(luhnDigit(payload)), nil
+}
+
+// ParsePAN checks a PAN and returns it without spaces and dashes.
+func ParsePAN(s string) (string, error) {
+	pan := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(s))
+
+	if len(pan) != panLength || !allDigits(pan) || luhnDigit(pan[:panLength-1]) != pan[panLength-1] {
+		return "", ErrInvalidPAN
+	}
+
+	return pan, nil
+}
+
+func ValidBIN(bin string) bool {
+	return len(bin) == binLength && allDigits(bin) && bin[0] != '0'
+}
+
+// BINRange is the inclusive range of BINs cards are issued in.
+type BINRange struct {
+	From int
+	To   int
+}
+
+func NewBINRange(from, to string) (*BINRange, error) {
+	if !ValidBIN(from) || !ValidBIN(to) {
+		return nil, ErrInvalidBINRange
+	}
+
+	r := &BINRange{}
+	r.From, _ = strconv.Atoi(from)
+	r.To, _ = strconv.Atoi(to)
+
+	if r.From > r.To {
+		return nil, ErrInvalidBINRange
+	}
+
+	return r, nil
+}
+
+// Contains reports whether the PAN was issued in the range.
+func (r *BINRange) Contains(pan string) bool {
+	if len(pan) < binLength {
+		return false
+	}
+
+	bin, err := strconv.Atoi(pan[:binLength])
+	return err == nil && bin >= r.From && bin <= r.To
+}
+
+// luhnDigit is the check digit that makes payload followed by it pass the Luhn check.
+func luhnDigit(payload string) byte {
+	sum := 0
+	double := true
+
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Kinds of card messages, after the ISO 8583 messages they come in.
+// A completion captures the authorization with the same RRN,
+// without one it is a purchase on its own.
+const (
+	CardAuthorization = "authorization"
+	CardCompletion    = "completion"
+	CardReversal      = "reversal"
+)
+
+// Statuses of card messages. A message is pending from the moment it is
+// reserved until it is approved, a declined one is dropped.
+const (
+	CardMessagePending  = "pending"
+	CardMessageApproved = "approved"
+)
+
+// CardMessage is a card request of a terminal paying MerchantID.
+// A terminal tells its messages apart by RRN, a repeated message gets
+// the outcome of the first one, and a reversal refers to the message it
+// undoes by the same RRN. Expiry is MM/YY like Card.Expiry, Currency may
+// be empty, and the CVV too when the terminal is CardPresent.
+// The outcome is in CardID, HoldID and TransactionID.
+type CardMessage struct {
+	ID            int64     `json:"ID"`
+	Kind          string    `json:"Kind"`
+	Status        string    `json:"Status"`
+	TerminalID    string    `json:"TerminalID"`
+	MerchantID    string    `json:"MerchantID"`
+	RRN           string    `json:"RRN"`
+	PAN           string    `json:"-"`
+	Expiry        string    `json:"-"`
+	CVV           string    `json:"-"`
+	CardPresent   bool      `json:"-"`
+	Amount        int64     `json:"Amount"`
+	Currency      string    `json:"Currency"`
+	CardID        int64     `json:"CardID"`
+	HoldID        int64     `json:"HoldID,omitempty"`
+	TransactionID int64     `json:"TransactionID,omitempty"`
+	Created       time.Time `json:"Created"`
+}
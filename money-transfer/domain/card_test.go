@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLuhnDigit(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    byte
+	}{
+		{"411111111111111", '1'},
+		{"440043123456789", '4'},
+		{"400000000000000", '2'},
+		{"7992739871", '3'},
+		{"0", '0'},
+	}
+
+	for _, tt := range tests {
+		if got := luhnDigit(tt.payload); got != tt.want {
+			t.Errorf("luhnDigit(%q) = %c, want %c", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestNewPAN(t *testing.T) {
+	tests := []struct {
+		bin, digits string
+		want        string
+		err         error
+	}{
+		{"440043", "123456789", "4400431234567894", nil},
+		{"411111", "111111111", "4111111111111111", nil},
+		{"040043", "123456789", "", ErrInvalidPAN}, // BINs don't start with 0
+		{"44004", "1234567890", "", ErrInvalidPAN},
+		{"440043", "12345678", "", ErrInvalidPAN},
+		{"440043", "12345678a", "", ErrInvalidPAN},
+	}
+
+	for _, tt := range tests {
+		got, err := NewPAN(tt.bin, tt.digits)
+		if got != tt.want || err != tt.err {
+			t.Errorf("NewPAN(%q, %q) = %q, %v, want %q, %v", tt.bin, tt.digits, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParsePAN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"4400431234567894", "4400431234567894", nil},
+		{"4400 4312 3456 7894", "4400431234567894", nil},
+		{" 4400-4312-3456-7894 ", "4400431234567894", nil},
+		{"4111111111111111", "4111111111111111", nil},
+		{"4400431234567895", "", ErrInvalidPAN}, // check digit
+		{"4400431234567849", "", ErrInvalidPAN}, // swapped digits
+		{"440043123456789", "", ErrInvalidPAN},
+		{"44004312345678941", "", ErrInvalidPAN},
+		{"4400x31234567894", "", ErrInvalidPAN},
+		{"", "", ErrInvalidPAN},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePAN(tt.in)
+		if got != tt.want || err != tt.err {
+			t.Errorf("ParsePAN(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestBINRange(t *testing.T) {
+	r, err := NewBINRange("440043", "440045")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pan  string
+		want bool
+	}{
+		{"4400431234567894", true},
+		{"4400451234567892", true},
+		{"4400421234567895", false},
+		{"4400461234567891", false},
+		{"44004", false},
+		{"44004x1234567894", false},
+	}
+
+	for _, tt := range tests {
+		if got := r.Contains(tt.pan); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.pan, got, tt.want)
+		}
+	}
+
+	for _, bins := range [][2]string{{"440045", "440043"}, {"44004", "440043"}, {"040043", "440043"}} {
+		if _, err := NewBINRange(bins[0], bins[1]); err != ErrInvalidBINRange {
+			t.Errorf("NewBINRange(%q, %q) error = %v, want %v", bins[0], bins[1], err, ErrInvalidBINRange)
+		}
+	}
+}
+
+func TestCardExpiry(t *testing.T) {
+	// valid through October 2029
+	c := &Card{Expires: time.Date(2029, 11, 1, 0, 0, 0, 0, time.UTC)}
+
+	if got := c.Expiry(); got != "10/29" {
+		t.Errorf("Expiry() = %q, want %q", got, "10/29")
+	}
+}
@@ -1,6 +1,13 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// UnsetSecret stands for secrets in the sample configs, the service
+// doesn't start until it is replaced.
+const UnsetSecret = "change-me"
 
 // Config ...
 type Config struct {
@@ -12,10 +19,79 @@ type Config struct {
 	UserDB     string `toml:"db_user"`
 	PasswordDB string `toml:"db_password"`
 
-	AccessTokenSecret  string   `toml:"access_token_secret"`
-	RefreshTokenSecret string   `toml:"refresh_token_secret"`
-	AccessTokenTTL     duration `toml:"access_token_ttl"`
-	RefreshTokenTTL    duration `toml:"refresh_token_ttl"`
+	JWKSURL         string   `toml:"jwks_url"`
+	JWKSCacheTTL    duration `toml:"jwks_cache_ttl"`
+	AccessTokenTTL  duration `toml:"access_token_ttl"`
+	RefreshTokenTTL duration `toml:"refresh_token_ttl"`
+
+	// UsersURL is the internal users API of auth-service, InternalToken authenticates to it.
+	UsersURL      string `toml:"users_url"`
+	InternalToken string `toml:"internal_token"`
+
+	IdempotencyKeyTTL duration `toml:"idempotency_key_ttl"`
+	// IdempotencyLease is how long a key waits for the answer of its request,
+	// after it a retry may take the key over.
+	IdempotencyLease duration `toml:"idempotency_lease"`
+
+	// BankCode is the bank code in IBANs of new accounts.
+	BankCode string `toml:"bank_code"`
+
+	// Cards are issued in BINs from CardBINFrom to CardBINTo and are valid for CardYears.
+	CardBINFrom string `toml:"card_bin_from"`
+	CardBINTo   string `toml:"card_bin_to"`
+	CardYears   int    `toml:"card_years"`
+	// CardPANKey keys the hashes cards are found by their PAN with.
+	CardPANKey string `toml:"card_pan_key"`
+
+	// ISO8583Addr is where the card authorization simulator listens, empty turns it off.
+	ISO8583Addr string `toml:"iso8583_addr"`
+	// CardMerchants maps merchant IDs of card messages to the accounts they are paid to
+	// and the terminals allowed to send them.
+	CardMerchants map[string]CardMerchant `toml:"card_merchants"`
+
+	RatesFile  string `toml:"rates_file"`
+	LimitsFile string `toml:"limits_file"`
+	RiskFile   string `toml:"risk_file"`
+
+	FeesFile           string   `toml:"fees_file"`
+	FeesReloadInterval duration `toml:"fees_reload_interval"`
+
+	SchedulerInterval   duration `toml:"scheduler_interval"`
+	SchedulerLease      duration `toml:"scheduler_lease"`
+	SchedulerRetryDelay duration `toml:"scheduler_retry_delay"`
+	SchedulerMaxRetries int      `toml:"scheduler_max_retries"`
+
+	HoldTTL duration `toml:"hold_ttl"`
+
+	Broker         string   `toml:"broker"`
+	BrokerSubject  string   `toml:"broker_subject"`
+	NATSURL        string   `toml:"nats_url"`
+	OutboxInterval duration `toml:"outbox_interval"`
+
+	WebhookInterval    duration `toml:"webhook_interval"`
+	WebhookLease       duration `toml:"webhook_lease"`
+	WebhookTimeout     duration `toml:"webhook_timeout"`
+	WebhookRetryDelay  duration `toml:"webhook_retry_delay"`
+	WebhookMaxAttempts int      `toml:"webhook_max_attempts"`
+
+	ScreeningLists      []string `toml:"screening_lists"`
+	ScreeningFlagScore  float64  `toml:"screening_flag_score"`
+	ScreeningBlockScore float64  `toml:"screening_block_score"`
+	// ScreeningThreshold is the base amount from which transfers are screened.
+	ScreeningThreshold int64 `toml:"screening_threshold"`
+}
+
+// CardMerchant is paid card messages to the account with the IBAN Account.
+type CardMerchant struct {
+	Account   string                  `toml:"account"`
+	Terminals map[string]CardTerminal `toml:"terminals"`
+}
+
+// CardTerminal signs its messages with Key. A card-present terminal reads
+// the card itself, its messages may come without the CVV2.
+type CardTerminal struct {
+	Key         string `toml:"key"`
+	CardPresent bool   `toml:"card_present"`
 }
 
 type duration struct {
@@ -38,7 +114,79 @@ func NewConfig() *Config {
 		UserDB:     "postgres",
 		PasswordDB: "postgres",
 
+		JWKSURL:         "http://localhost:7575/.well-known/jwks.json",
+		JWKSCacheTTL:    duration{10 * time.Minute},
 		AccessTokenTTL:  duration{10 * time.Minute},
 		RefreshTokenTTL: duration{1 * time.Hour},
+
+		UsersURL:      "http://localhost:7576/internal/users",
+		InternalToken: UnsetSecret,
+
+		IdempotencyKeyTTL: duration{24 * time.Hour},
+		IdempotencyLease:  duration{1 * time.Minute},
+
+		BankCode: "999",
+
+		CardBINFrom: "440043",
+		CardBINTo:   "440043",
+		CardYears:   3,
+		CardPANKey:  UnsetSecret,
+
+		CardMerchants: map[string]CardMerchant{
+			"MERCHANT0000001": {
+				Account:   "KZ699990000000000001",
+				Terminals: map[string]CardTerminal{"TERM0001": {Key: UnsetSecret}},
+			},
+		},
+
+		RatesFile:  "configs/rates.toml",
+		LimitsFile: "configs/limits.toml",
+		RiskFile:   "configs/risk.toml",
+
+		FeesFile:           "configs/fees.toml",
+		FeesReloadInterval: duration{10 * time.Second},
+
+		SchedulerInterval:   duration{30 * time.Second},
+		SchedulerLease:      duration{5 * time.Minute},
+		SchedulerRetryDelay: duration{1 * time.Hour},
+		SchedulerMaxRetries: 5,
+
+		HoldTTL: duration{7 * 24 * time.Hour},
+
+		Broker:         "inproc",
+		BrokerSubject:  "money-transfer.events",
+		NATSURL:        "nats://localhost:4222",
+		OutboxInterval: duration{1 * time.Second},
+
+		WebhookInterval:    duration{5 * time.Second},
+		WebhookLease:       duration{1 * time.Minute},
+		WebhookTimeout:     duration{10 * time.Second},
+		WebhookRetryDelay:  duration{30 * time.Second},
+		WebhookMaxAttempts: 8,
+
+		ScreeningLists:      []string{"../shared/sanctions/un.xml", "../shared/sanctions/local.csv"},
+		ScreeningFlagScore:  0.88,
+		ScreeningBlockScore: 0.98,
+		ScreeningThreshold:  20000000,
+	}
+}
+
+// CheckSecrets refuses secrets that are empty or left as UnsetSecret.
+func (c *Config) CheckSecrets() error {
+	secrets := map[string]string{
+		"internal_token": c.InternalToken,
+		"card_pan_key":   c.CardPANKey,
+	}
+	for merchantID, merchant := range c.CardMerchants {
+		for terminalID, terminal := range merchant.Terminals {
+			secrets[fmt.Sprintf("key of terminal %s of %s", terminalID, merchantID)] = terminal.Key
+		}
+	}
+
+	for name, secret := range secrets {
+		if secret == "" || secret == UnsetSecret {
+			return fmt.Errorf("%s is %q, set a secret of its own", name, secret)
+		}
 	}
+	return nil
 }
@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestCheckSecrets(t *testing.T) {
+	set := func(c *Config) {
+		c.InternalToken = "token"
+		c.CardPANKey = "pan key"
+		c.CardMerchants["MERCHANT0000001"].Terminals["TERM0001"] = CardTerminal{Key: "terminal key"}
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		ok     bool
+	}{
+		{"all set", func(c *Config) {}, true},
+		{"internal token unset", func(c *Config) { c.InternalToken = UnsetSecret }, false},
+		{"internal token empty", func(c *Config) { c.InternalToken = "" }, false},
+		{"card pan key unset", func(c *Config) { c.CardPANKey = UnsetSecret }, false},
+		{"terminal key unset", func(c *Config) {
+			c.CardMerchants["MERCHANT0000001"].Terminals["TERM0001"] = CardTerminal{Key: UnsetSecret}
+		}, false},
+	}
+
+	if err := NewConfig().CheckSecrets(); err == nil {
+		t.Errorf("CheckSecrets() of the default config = nil, want an error")
+	}
+
+	for _, tt := range tests {
+		c := NewConfig()
+		set(c)
+		tt.change(c)
+
+		if err := c.CheckSecrets(); (err == nil) != tt.ok {
+			t.Errorf("%s: CheckSecrets() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
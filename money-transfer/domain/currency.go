@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+// Currencies accounts can be opened in. Amounts are always kept
+// in minor units, all supported currencies have two decimal places.
+const (
+	KZT = "KZT"
+	USD = "USD"
+	EUR = "EUR"
+	RUB = "RUB"
+)
+
+var currencies = map[string]bool{KZT: true, USD: true, EUR: true, RUB: true}
+
+// minAmounts are the smallest amounts moved at once, in minor units:
+// 150 tenge and about as much in the other currencies.
+var minAmounts = map[string]int64{KZT: 15000, USD: 50, EUR: 50, RUB: 3000}
+
+// ValidCurrency reports whether accounts can be held in the currency.
+func ValidCurrency(currency string) bool {
+	return currencies[currency]
+}
+
+// MinAmount is the smallest amount of the currency a transfer, hold or
+// cash-in may move, in minor units. Unknown currencies have no minimum
+// here, they are refused elsewhere.
+func MinAmount(currency string) int64 {
+	return minAmounts[currency]
+}
+
+// Rates are prices of currencies in the base currency, e.g. USD = "470.50"
+// when one dollar costs 470.50 tenge. Spread is the share of the rate
+// the bank keeps on every conversion, e.g. "0.01".
+// Decimals are strings so they are never rounded on the way.
+type Rates struct {
+	Base    string            `toml:"base" json:"Base"`
+	Spread  string            `toml:"spread" json:"Spread"`
+	Rates   map[string]string `toml:"rates" json:"Rates"`
+	Updated time.Time         `toml:"updated" json:"Updated"`
+}
+
+// Conversion is the result of exchanging Amount of From currency.
+// Rate is the applied rate with the spread, Converted is rounded down.
+type Conversion struct {
+	From      string `json:"From"`
+	To        string `json:"To"`
+	Amount    int64  `json:"Amount"`
+	Converted int64  `json:"Converted"`
+	Rate      string `json:"Rate"`
+}
+
+// RateStore keeps the current exchange rates.
+type RateStore interface {
+	Rates() *Rates
+	SetRates(r *Rates) error
+	Convert(from, to string, amount int64) (*Conversion, error)
+	// ToBase values amount in the base currency at the mid rate.
+	ToBase(currency string, amount int64) (int64, error)
+}
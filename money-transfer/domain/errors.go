@@ -6,10 +6,106 @@ var ErrTransReceiver = errors.New("invalid transaction receiver")
 
 var ErrTransSender = errors.New("invalid transaction sender")
 
-var ErrTransSum = errors.New("minimum sum for transaction should be higher than 150")
+var ErrTransSum = errors.New("amount is below the minimum of its currency")
 
 var ErrInvalidSum = errors.New("your balance is insufficient for this transaction")
 
+var ErrInvalidSource = errors.New("invalid funding source")
+
+var ErrCashInNotFound = errors.New("pending cash-in not found")
+
+var ErrInvalidFilter = errors.New("invalid history filter")
+
+var ErrInvalidFormat = errors.New("unsupported statement format")
+
+var ErrInvalidCurrency = errors.New("unsupported currency")
+
+var ErrInvalidRates = errors.New("invalid exchange rates")
+
+var ErrNoRate = errors.New("no exchange rate for the currency pair")
+
+var ErrInvalidFees = errors.New("invalid fee schedule")
+
+var ErrFeeChanged = errors.New("the fee has changed since the quote")
+
+// ErrFeeRequired - a transfer of a user is sent without the fee it was quoted.
+var ErrFeeRequired = errors.New("fee of the quote is required")
+
+var ErrInvalidSchedule = errors.New("invalid transfer schedule")
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+var ErrScheduleRunDone = errors.New("schedule run was already executed")
+
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+var ErrRefundExceeded = errors.New("refund exceeds what is left of the transaction")
+
+var ErrHoldNotFound = errors.New("hold not found")
+
+var ErrHoldNotActive = errors.New("hold is not active")
+
+var ErrHoldExceeded = errors.New("capture exceeds what is left of the hold")
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+var ErrWebhookAddress = errors.New("webhook address is not public")
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+var ErrInvalidRiskRules = errors.New("invalid risk rules")
+
+var ErrDecisionNotFound = errors.New("risk decision not found")
+
+var ErrDecisionResolved = errors.New("risk decision was already resolved")
+
+// ErrScreeningBlocked - a party of the transfer is on a sanctions list.
+// It doesn't tell which one, the user must not learn about the match.
+var ErrScreeningBlocked = errors.New("transfer is refused")
+
+var ErrCaseNotFound = errors.New("compliance case not found")
+
+var ErrCaseResolved = errors.New("compliance case was already resolved")
+
+var ErrInvalidIBAN = errors.New("invalid IBAN")
+
+var ErrInvalidBankCode = errors.New("bank code must be 3 digits")
+
+var ErrInvalidPAN = errors.New("invalid card number")
+
+var ErrInvalidBINRange = errors.New("BIN range must be two 6 digit BINs in order")
+
+var ErrCardNotFound = errors.New("card not found")
+
+var ErrCardNotActive = errors.New("card is blocked or expired")
+
+var ErrCardExpired = errors.New("card is expired")
+
+// ErrCardDetails - expiry date or CVV of a card message doesn't match the card.
+var ErrCardDetails = errors.New("card details don't match")
+
+var ErrInvalidCardMessage = errors.New("invalid card message")
+
+var ErrUnknownMerchant = errors.New("unknown merchant")
+
+var ErrUnknownTerminal = errors.New("unknown terminal")
+
+// ErrTerminalAuth - the MAC of a card message is missing or wrong.
+var ErrTerminalAuth = errors.New("card message is not authenticated")
+
+var ErrOriginalNotFound = errors.New("original card message not found")
+
+// ErrCardMessageReplayed - the terminal already sent a message with the RRN.
+var ErrCardMessageReplayed = errors.New("card message was already sent")
+
+// ErrCardMessageInProgress - the first copy of a repeated message isn't through yet.
+var ErrCardMessageInProgress = errors.New("card message is in progress")
+
+// ErrCardExists - a new PAN is taken by another card, a fresh one has to be drawn.
+var ErrCardExists = errors.New("card number is taken")
+
 var ErrInvalidHeader = errors.New("invalid authorization header")
 
 var ErrInvalidToken = errors.New("invalid token")
@@ -19,3 +115,15 @@ var ErrExpiredToken = errors.New("expired token")
 var ErrNoHeader = errors.New("authorization header not found")
 
 var ErrNotFound = errors.New("requester doesn't have account with this ID")
+
+var ErrUnbalancedEntry = errors.New("journal entry postings do not sum to zero")
+
+var ErrLedgerUnbalanced = errors.New("ledger is out of balance")
+
+var ErrIdempotencyConflict = errors.New("idempotency key was already used with a different request")
+
+var ErrIdempotencyInProgress = errors.New("request with this idempotency key is still in progress")
+
+var ErrAccountNotFound = errors.New("account not found")
+
+var ErrUserNotFound = errors.New("user not found")
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Types of domain events. Payload of transaction events is the Transaction,
+// of hold events the Hold, of account.created the Account.
+const (
+	EventAccountCreated      = "account.created"
+	EventTransferCompleted   = "transfer.completed"
+	EventCashInPending       = "cash_in.pending"
+	EventCashInSettled       = "cash_in.settled"
+	EventCashInCancelled     = "cash_in.cancelled"
+	EventTransactionReversed = "transaction.reversed"
+	EventTransactionRefunded = "transaction.refunded"
+	EventHoldCreated         = "hold.created"
+	EventHoldCaptured        = "hold.captured"
+	EventHoldVoided          = "hold.voided"
+)
+
+var eventTypes = []string{
+	EventAccountCreated,
+	EventTransferCompleted,
+	EventCashInPending,
+	EventCashInSettled,
+	EventCashInCancelled,
+	EventTransactionReversed,
+	EventTransactionRefunded,
+	EventHoldCreated,
+	EventHoldCaptured,
+	EventHoldVoided,
+}
+
+// ValidEventType reports whether events of the type are published.
+func ValidEventType(t string) bool {
+	for _, known := range eventTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a state change written to the outbox in the same database
+// transaction as the change itself. Accounts are the customer accounts
+// it concerns, webhooks are found by them and they are never published.
+// Events are delivered at least once, ID tells duplicates apart.
+type Event struct {
+	ID       int64           `json:"ID"`
+	Type     string          `json:"Type"`
+	Accounts []int64         `json:"-"`
+	Payload  json.RawMessage `json:"Payload"`
+	Created  time.Time       `json:"Created"`
+}
+
+// Broker carries events out of the service.
+type Broker interface {
+	// Publish returns once the broker has accepted the event.
+	Publish(ctx context.Context, e *Event) error
+	// Subscribe calls handler for every event published from now on.
+	// Handlers should return quickly, they may hold up other subscribers.
+	Subscribe(handler func(e *Event)) error
+	Close() error
+}
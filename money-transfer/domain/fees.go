@@ -0,0 +1,42 @@
+package domain
+
+// FeeTier prices transfers of amounts up to UpTo, zero UpTo has no upper bound.
+// Fee is Flat plus Percent of the amount, kept between Min and Max when they are set.
+// Amounts are in minor units of the sender's currency.
+type FeeTier struct {
+	UpTo    int64  `toml:"up_to" json:"UpTo,omitempty"`
+	Flat    int64  `toml:"flat" json:"Flat,omitempty"`
+	Percent string `toml:"percent" json:"Percent,omitempty"`
+	Min     int64  `toml:"min" json:"Min,omitempty"`
+	Max     int64  `toml:"max" json:"Max,omitempty"`
+}
+
+// FeeSchedule holds tiers of every currency ordered by UpTo.
+// Currencies without tiers are not charged.
+type FeeSchedule struct {
+	OwnAccountsFree bool                 `toml:"own_accounts_free" json:"OwnAccountsFree"`
+	Currencies      map[string][]FeeTier `toml:"currencies" json:"Currencies"`
+}
+
+// FeeStore keeps the current fee schedule.
+type FeeStore interface {
+	Schedule() *FeeSchedule
+	// Fee of sending amount, own tells that both accounts belong to the sender.
+	Fee(currency string, amount int64, own bool) (int64, error)
+}
+
+// Quote is what a transfer costs, shown before it is made.
+// The sender pays Amount and Fee, the receiver gets ReceivedAmount.
+type Quote struct {
+	SenderID         int64  `json:"-"`
+	ReceiverID       int64  `json:"-"`
+	SenderNumber     string `json:"SenderNumber"`
+	ReceiverNumber   string `json:"ReceiverNumber"`
+	Amount           int64  `json:"Amount"`
+	Fee              int64  `json:"Fee"`
+	Total            int64  `json:"Total"`
+	Currency         string `json:"Currency"`
+	ReceivedAmount   int64  `json:"ReceivedAmount"`
+	ReceivedCurrency string `json:"ReceivedCurrency"`
+	Rate             string `json:"Rate"`
+}
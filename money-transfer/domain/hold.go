@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Statuses of holds. An active hold is expired once Expires passes,
+// whatever is left of it is available to the account again.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldVoided   = "voided"
+	HoldExpired  = "expired"
+)
+
+// Hold reserves Amount on AccountID for a payment to ReceiverID.
+// The receiver captures it in one or several parts until Captured reaches Amount,
+// or voids it to release the rest. Amount is in the currency of the account.
+// Fee of the whole amount is reserved on top, every capture pays its part of it
+// and the last one what is left.
+type Hold struct {
+	ID             int64     `json:"ID"`
+	AccountID      int64     `json:"-"`
+	ReceiverID     int64     `json:"-"`
+	AccountNumber  string    `json:"AccountNumber"`
+	ReceiverNumber string    `json:"ReceiverNumber"`
+	Role           string    `json:"-"`
+	Amount         int64     `json:"Amount"`
+	Captured       int64     `json:"Captured"`
+	Fee            int64     `json:"Fee"`
+	FeeCaptured    int64     `json:"-"`
+	Currency       string    `json:"Currency"`
+	Status         string    `json:"Status"`
+	Created        time.Time `json:"Created"`
+	Expires        time.Time `json:"Expires"`
+
+	// RiskDecisionID links a hold to the risk decision that let it through.
+	RiskDecisionID int64 `json:"-"`
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// IBANs of Kazakhstan are KZ, two check digits, a 3 digit bank code
+// and a 13 character account number, 20 characters in all.
+const (
+	ibanLength     = 20
+	bankCodeLength = 3
+	accountDigits  = 13
+)
+
+// NewIBAN makes the IBAN of the seq-th account of the bank.
+func NewIBAN(bankCode string, seq int64) (string, error) {
+	if !ValidBankCode(bankCode) {
+		return "", ErrInvalidBankCode
+	}
+
+	account := fmt.Sprintf("%0*d", accountDigits, seq)
+	if seq <= 0 || len(account) > accountDigits {
+		return "", ErrInvalidIBAN
+	}
+
+	bban := bankCode + account
+
+	return fmt.Sprintf("KZ%02d%s", 98-ibanMod97(bban+"KZ00"), bban), nil
+}
+
+// ParseIBAN checks a KZ IBAN and returns it without spaces in upper case.
+func ParseIBAN(s string) (string, error) {
+	iban := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+
+	if len(iban) != ibanLength || !strings.HasPrefix(iban, "KZ") {
+		return "", ErrInvalidIBAN
+	}
+
+	for i, r := range iban {
+		digit := r >= '0' && r <= '9'
+		letter := r >= 'A' && r <= 'Z'
+
+		switch {
+		case i >= 2 && i < 4+bankCodeLength && !digit:
+			return "", ErrInvalidIBAN
+		case !digit && !letter:
+			return "", ErrInvalidIBAN
+		}
+	}
+
+	if ibanMod97(iban[4:]+iban[:4]) != 1 {
+		return "", ErrInvalidIBAN
+	}
+
+	return iban, nil
+}
+
+func ValidBankCode(code string) bool {
+	if len(code) != bankCodeLength {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ibanMod97 reads letters as 10 to 35 and takes the number modulo 97.
+func ibanMod97(s string) int {
+	var digits strings.Builder
+
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+			continue
+		}
+		digits.WriteRune(r)
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	return int(new(big.Int).Mod(n, big.NewInt(97)).Int64())
+}
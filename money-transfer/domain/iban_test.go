@@ -0,0 +1,78 @@
+package domain
+
+import "testing"
+
+func TestNewIBAN(t *testing.T) {
+	tests := []struct {
+		bankCode string
+		seq      int64
+		want     string
+		err      error
+	}{
+		{"999", 1, "KZ699990000000000001", nil},
+		{"125", 42, "KZ461250000000000042", nil},
+		{"999", 9999999999999, "KZ099999999999999999", nil},
+		{"999", 10000000000000, "", ErrInvalidIBAN},
+		{"999", 0, "", ErrInvalidIBAN},
+		{"99", 1, "", ErrInvalidBankCode},
+		{"9a9", 1, "", ErrInvalidBankCode},
+	}
+
+	for _, tt := range tests {
+		got, err := NewIBAN(tt.bankCode, tt.seq)
+		if got != tt.want || err != tt.err {
+			t.Errorf("NewIBAN(%q, %d) = %q, %v, want %q, %v", tt.bankCode, tt.seq, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseIBAN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"KZ699990000000000001", "KZ699990000000000001", nil},
+		{" kz69 9990 0000 0000 0001 ", "KZ699990000000000001", nil},
+		{"KZ461250000000000042", "KZ461250000000000042", nil},
+		// letters are allowed in the account number
+		{"KZ86125KZT5004100100", "KZ86125KZT5004100100", nil},
+
+		{"KZ689990000000000001", "", ErrInvalidIBAN}, // check digits
+		{"KZ699990000000000010", "", ErrInvalidIBAN}, // swapped digits
+		{"KZ69999000000000001", "", ErrInvalidIBAN},  // too short
+		{"GB82WEST12345698765432", "", ErrInvalidIBAN},
+		{"KZ69A990000000000001", "", ErrInvalidIBAN}, // letter in the bank code
+		{"KZ69999000000000000-", "", ErrInvalidIBAN},
+		{"", "", ErrInvalidIBAN},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIBAN(tt.in)
+		if got != tt.want || err != tt.err {
+			t.Errorf("ParseIBAN(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestIBANMod97(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		// valid IBANs moved to their end give 1
+		{"WEST12345698765432GB82", 1},
+		{"9990000000000001KZ69", 1},
+		{"0", 0},
+		{"97", 0},
+		{"98", 1},
+		{"A", 10},
+		{"Z", 35},
+	}
+
+	for _, tt := range tests {
+		if got := ibanMod97(tt.in); got != tt.want {
+			t.Errorf("ibanMod97(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package domain
+
+// Kinds of accounts. Every currency has its own set of system accounts.
+// They are not owned by any user and are the counterparties
+// for money entering or leaving the bank. FX accounts hold the position
+// of the bank in the currency after conversions.
+const (
+	AccountCustomer = "customer"
+	AccountCashIn   = "cash_in"
+	AccountFees     = "fees"
+	AccountSuspense = "suspense"
+	AccountFX       = "fx"
+)
+
+// Kinds of journal entries.
+const (
+	EntryOpening  = "opening"
+	EntryTransfer = "transfer"
+	EntryCashIn   = "cash_in"
+	// cash-in waits in the suspense account until it is settled or cancelled
+	EntryCashInSettle = "cash_in_settle"
+	EntryCashInCancel = "cash_in_cancel"
+	EntryReversal     = "reversal"
+	EntryRefund       = "refund"
+)
+
+// Posting is a single leg of a journal entry.
+// Positive amount credits the account, negative amount debits it.
+// Currency is the currency of the account, postings of an entry
+// must balance in every currency separately.
+type Posting struct {
+	ID        int64  `json:"ID,omitempty"`
+	EntryID   int64  `json:"EntryID,omitempty"`
+	AccountID int64  `json:"-"`
+	Amount    int64  `json:"Amount,omitempty"`
+	Currency  string `json:"Currency,omitempty"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rolling windows limits are tracked in.
+const (
+	LimitDay   = 24 * time.Hour
+	LimitMonth = 30 * 24 * time.Hour
+)
+
+// Names of limits.
+const (
+	LimitPerTransaction = "per_transaction"
+	LimitDaily          = "daily"
+	LimitMonthly        = "monthly"
+	LimitDailyCount     = "daily_count"
+)
+
+// Limit caps outgoing transfers. Amounts are in the base currency
+// of exchange rates, zero means no limit.
+type Limit struct {
+	PerTransaction int64 `toml:"per_transaction" json:"PerTransaction"`
+	Daily          int64 `toml:"daily" json:"Daily"`
+	Monthly        int64 `toml:"monthly" json:"Monthly"`
+	DailyCount     int64 `toml:"daily_count" json:"DailyCount"`
+}
+
+// Limits are set per role of the user, counting transfers from all of
+// the user's accounts, and per kind of account, counting the account alone.
+type Limits struct {
+	Roles    map[string]Limit `toml:"roles" json:"Roles"`
+	Accounts map[string]Limit `toml:"accounts" json:"Accounts"`
+}
+
+// Usage is what was already sent within the rolling windows.
+type Usage struct {
+	Day      int64
+	Month    int64
+	DayCount int64
+}
+
+// TransferUsage is usage of the sender and of the sending account.
+type TransferUsage struct {
+	User    Usage
+	Account Usage
+}
+
+// LimitError tells which limit a transfer would exceed
+// and how much can still be sent under it.
+type LimitError struct {
+	Scope    string `json:"Scope"`
+	Limit    string `json:"Limit"`
+	Max      int64  `json:"Max"`
+	Used     int64  `json:"Used"`
+	Headroom int64  `json:"Headroom"`
+	Currency string `json:"Currency,omitempty"`
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == LimitDailyCount {
+		return fmt.Sprintf("%s %s limit of %d transfers reached", e.Scope, e.Limit, e.Max)
+	}
+	return fmt.Sprintf("%s %s limit of %d %s exceeded, %d %s left", e.Scope, e.Limit, e.Max, e.Currency, e.Headroom, e.Currency)
+}
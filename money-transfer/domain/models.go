@@ -15,18 +15,141 @@ type User struct {
 }
 
 type Account struct {
-	ID              int64       `json:"ID,omitempty"`
+	ID              int64       `json:"-"`
+	Number          string      `json:"Number,omitempty"`
 	OwnerID         int64       `json:"OwnerID,omitempty"`
 	IIN             string      `json:"IIN,omitempty"`
 	Amount          int64       `json:"Amount,omitempty"`
+	Available       int64       `json:"Available,omitempty"`
+	Currency        string      `json:"Currency,omitempty"`
+	Kind            string      `json:"Kind,omitempty"`
 	Registered      time.Time   `json:"Registered,omitempty"`
 	LastTransaction Transaction `json:"LastTransaction,omitempty"`
 }
 
+// Kinds of transactions.
+// Reversals and refunds compensate a transfer and point to it with OriginalID.
+const (
+	TransactionTransfer = "transfer"
+	TransactionCashIn   = "cash_in"
+	TransactionReversal = "reversal"
+	TransactionRefund   = "refund"
+)
+
+// Statuses of transactions. Transfers are settled right away,
+// cash-ins may wait for their funding source.
+const (
+	StatusPending   = "pending"
+	StatusSettled   = "settled"
+	StatusCancelled = "cancelled"
+)
+
 type Transaction struct {
 	ID         int64     `json:"ID,omitempty"`
-	SenderID   int64     `json:"SenderID,omitempty"`
-	ReceiverID int64     `json:"ReceiverID,omitempty"`
+	SenderID   int64     `json:"-"`
+	ReceiverID int64     `json:"-"`
 	Amount     int64     `json:"Amount,omitempty"`
 	Date       time.Time `json:"Date,omitempty"`
+	Kind       string    `json:"Kind,omitempty"`
+	Status     string    `json:"Status,omitempty"`
+	Source     string    `json:"Source,omitempty"`
+
+	// SenderNumber and ReceiverNumber are the IBANs of both sides,
+	// empty for the system accounts of cash-ins.
+	SenderNumber   string `json:"SenderNumber,omitempty"`
+	ReceiverNumber string `json:"ReceiverNumber,omitempty"`
+
+	// Amount leaves the sender in Currency, the receiver gets
+	// ReceivedAmount in ReceivedCurrency. Rate is "1" without conversion.
+	Currency         string `json:"Currency,omitempty"`
+	ReceivedAmount   int64  `json:"ReceivedAmount,omitempty"`
+	ReceivedCurrency string `json:"ReceivedCurrency,omitempty"`
+	Rate             string `json:"Rate,omitempty"`
+	// Fee is paid by the sender on top of Amount, in Currency.
+	Fee int64 `json:"Fee,omitempty"`
+
+	// BaseAmount is Amount in the base currency, transfer limits are counted in it.
+	BaseAmount int64 `json:"-"`
+	// ScheduleRunID links a transfer to the schedule run that made it.
+	ScheduleRunID int64 `json:"-"`
+	// RiskDecisionID links a transfer to the risk decision that let it through.
+	RiskDecisionID int64 `json:"-"`
+
+	// OriginalID is the transfer a reversal or refund compensates,
+	// Compensations are reversals and refunds of a transfer, filled in history only.
+	OriginalID    int64   `json:"OriginalID,omitempty"`
+	Compensations []int64 `json:"Compensations,omitempty"`
+
+	// HoldID is the hold a capture was made from.
+	HoldID int64 `json:"HoldID,omitempty"`
+
+	// Direction and Balance are filled in account history only.
+	Direction string `json:"Direction,omitempty"`
+	Balance   *int64 `json:"Balance,omitempty"`
+}
+
+// Directions of transactions relative to an account.
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// HistoryFilter selects a page of account history.
+// Zero values mean no filtering.
+type HistoryFilter struct {
+	AccountID    int64
+	Cursor       string
+	Limit        int
+	From         time.Time
+	To           time.Time
+	Direction    string
+	Counterparty int64
+	MinAmount    int64
+	MaxAmount    int64
+}
+
+type HistoryPage struct {
+	Transactions []*Transaction `json:"Transactions"`
+	NextCursor   string         `json:"NextCursor,omitempty"`
+}
+
+// Statement is account history for a period, oldest transaction first.
+type Statement struct {
+	Account        *Account
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	TotalIn        int64
+	TotalOut       int64
+	Transactions   []*Transaction
+	Generated      time.Time
+}
+
+// Types of funding sources money is cashed in from.
+const (
+	SourceCard     = "card"
+	SourceTerminal = "terminal"
+)
+
+// CashIn is money coming into an account from outside the bank.
+type CashIn struct {
+	AccountID  int64
+	Amount     int64
+	SourceType string
+	SourceName string
+}
+
+// IdempotencyKey remembers the outcome of a request,
+// so a retried request is answered without moving money twice.
+// An unanswered key is leased to its request until Leased.
+type IdempotencyKey struct {
+	Key         string
+	OwnerID     int64
+	Fingerprint string
+	StatusCode  int
+	Header      map[string][]string
+	Response    []byte
+	Created     time.Time
+	Leased      time.Time
 }
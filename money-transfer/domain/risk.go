@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Outcomes of the risk check, from the mildest. A transfer held for review
+// or blocked is not made, it waits in the review queue for an admin.
+const (
+	RiskAllow  = "allow"
+	RiskReview = "review"
+	RiskBlock  = "block"
+)
+
+// Kinds of risk rules.
+const (
+	// RuleVelocity fires when the user has sent Count transfers within Window.
+	RuleVelocity = "velocity"
+	// RuleNewReceiver fires on a first transfer of MinAmount or more
+	// to an account of somebody else.
+	RuleNewReceiver = "new_receiver"
+	// RuleUnusualAmount fires when the amount is over Factor times the average
+	// of the user's transfers within Window, once there are MinHistory of them.
+	RuleUnusualAmount = "unusual_amount"
+	// RuleNight fires on transfers of MinAmount or more made between FromHour
+	// and ToHour in Timezone.
+	RuleNight = "night"
+)
+
+// RiskRule is a rule of the risk check. Amounts are in the base currency.
+type RiskRule struct {
+	Name       string   `toml:"name"`
+	Kind       string   `toml:"kind"`
+	Action     string   `toml:"action"`
+	Window     duration `toml:"window"`
+	Count      int      `toml:"count"`
+	MinAmount  int64    `toml:"min_amount"`
+	Factor     float64  `toml:"factor"`
+	MinHistory int      `toml:"min_history"`
+	FromHour   int      `toml:"from_hour"`
+	ToHour     int      `toml:"to_hour"`
+	Timezone   string   `toml:"timezone"`
+}
+
+// RiskRules are checked in the order of the file.
+type RiskRules struct {
+	Rules []RiskRule `toml:"rule"`
+}
+
+// Statuses of risk decisions. Decisions to allow are approved right away,
+// the others stay open until an admin approves or rejects them.
+const (
+	DecisionOpen     = "open"
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+)
+
+// Kinds of risk decisions, what is made when the decision is approved.
+const (
+	DecisionTransfer = "transfer"
+	DecisionHold     = "hold"
+)
+
+// RiskDecision is the outcome of the risk check of a transfer or a hold.
+// Rule is the rule that decided it, Fired are all rules that fired.
+// ScheduleRunID is the schedule run the transfer was held in.
+type RiskDecision struct {
+	ID             int64      `json:"ID"`
+	Kind           string     `json:"Kind"`
+	OwnerID        int64      `json:"OwnerID"`
+	Role           string     `json:"-"`
+	SenderID       int64      `json:"-"`
+	ReceiverID     int64      `json:"-"`
+	SenderNumber   string     `json:"SenderNumber"`
+	ReceiverNumber string     `json:"ReceiverNumber"`
+	Amount         int64      `json:"Amount"`
+	Currency       string     `json:"Currency"`
+	BaseAmount     int64      `json:"BaseAmount"`
+	Outcome        string     `json:"Outcome"`
+	Rule           string     `json:"Rule,omitempty"`
+	Fired          []string   `json:"Fired"`
+	Reason         string     `json:"Reason,omitempty"`
+	Status         string     `json:"Status"`
+	TransactionID  int64      `json:"TransactionID,omitempty"`
+	HoldID         int64      `json:"HoldID,omitempty"`
+	ScheduleRunID  int64      `json:"ScheduleRunID,omitempty"`
+	Created        time.Time  `json:"Created"`
+	Resolved       *time.Time `json:"Resolved,omitempty"`
+	ResolvedBy     int64      `json:"ResolvedBy,omitempty"`
+}
+
+// RiskChecker decides whether a transfer may be made.
+// It fills Outcome, Rule, Fired and Reason of the decision.
+type RiskChecker interface {
+	Assess(ctx context.Context, d *RiskDecision) error
+}
+
+// RiskHistory is what the risk check knows about the past of a user.
+type RiskHistory interface {
+	// TransfersSince counts transfers sent from accounts of the owner since the moment.
+	TransfersSince(ctx context.Context, ownerID int64, since time.Time) (int, error)
+	// KnownReceiver reports whether the owner has sent to the account before or owns it.
+	KnownReceiver(ctx context.Context, ownerID, receiverID int64) (bool, error)
+	// AverageTransfer is the average base amount of transfers of the owner since the moment.
+	AverageTransfer(ctx context.Context, ownerID int64, since time.Time) (int64, int, error)
+}
+
+// RiskError tells that a transfer was held for review or blocked.
+type RiskError struct {
+	DecisionID int64  `json:"DecisionID"`
+	Outcome    string `json:"Outcome"`
+	Rule       string `json:"Rule"`
+}
+
+func (e *RiskError) Error() string {
+	if e.Outcome == RiskReview {
+		return "transfer is held for review by rule " + e.Rule
+	}
+	return "transfer is blocked by rule " + e.Rule
+}
@@ -0,0 +1,64 @@
+package domain
+
+import "time"
+
+// Statuses of transfer schedules.
+const (
+	ScheduleActive    = "active"
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+)
+
+// Statuses of schedule runs. A run is retrying when it failed
+// for insufficient funds and the same occurrence will be tried again.
+// A run is pending while its transfer is held for risk review,
+// it succeeds or fails when the decision is approved or rejected.
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunRetrying  = "retrying"
+	RunPending   = "pending"
+	RunFailed    = "failed"
+)
+
+// Schedule is a standing order. Without Cron it runs once at Start,
+// otherwise at every Cron occurrence from Start until Until
+// or until Count occurrences have passed, whichever comes first.
+// Role is the role of the owner when the schedule was made, runs are
+// limited by the role the owner has at the time of the run.
+type Schedule struct {
+	ID             int64      `json:"ID"`
+	OwnerID        int64      `json:"OwnerID"`
+	Role           string     `json:"-"`
+	SenderID       int64      `json:"-"`
+	ReceiverID     int64      `json:"-"`
+	SenderNumber   string     `json:"SenderNumber"`
+	ReceiverNumber string     `json:"ReceiverNumber"`
+	Amount         int64      `json:"Amount"`
+	Cron           string     `json:"Cron,omitempty"`
+	Start          time.Time  `json:"Start"`
+	Until          *time.Time `json:"Until,omitempty"`
+	Count          int        `json:"Count,omitempty"`
+	Remaining      int        `json:"Remaining,omitempty"`
+	Status         string     `json:"Status"`
+	Created        time.Time  `json:"Created"`
+
+	// Occurrence is the one being paid, Attempt counts its retries.
+	// NextRun is when the scheduler looks at the schedule next.
+	Occurrence time.Time `json:"Occurrence"`
+	Attempt    int       `json:"Attempt"`
+	NextRun    time.Time `json:"NextRun"`
+}
+
+// ScheduleRun is a single attempt to pay an occurrence.
+type ScheduleRun struct {
+	ID            int64      `json:"ID"`
+	ScheduleID    int64      `json:"ScheduleID"`
+	Occurrence    time.Time  `json:"Occurrence"`
+	Attempt       int        `json:"Attempt"`
+	Status        string     `json:"Status"`
+	TransactionID int64      `json:"TransactionID,omitempty"`
+	Error         string     `json:"Error,omitempty"`
+	Started       time.Time  `json:"Started"`
+	Finished      *time.Time `json:"Finished,omitempty"`
+}
@@ -1,14 +1,92 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Transfer interface {
 	CreateAccount(ctx context.Context, account *Account) error
 	FindAccount(ctx context.Context, ID int64) (*Account, error)
+	// ResolveAccount takes an IBAN and returns the internal ID of the account.
+	ResolveAccount(ctx context.Context, ref string) (int64, error)
+	// ResolveReceiver takes what ResolveAccount takes or the PAN of an active card,
+	// a PAN is only ever looked up as a card.
+	ResolveReceiver(ctx context.Context, ref string) (int64, error)
 	GetAccounts(ctx context.Context, OwnerID int64) ([]*Account, error)
-	ChangeAccountSum(ctx context.Context, accountID, newValue int64) error
-	AccountTransactions(ctx context.Context, requester, accountID int64) ([]*Transaction, error)
-	CreateTransaction(ctx context.Context, requester, SenderID, ReceiverID, Value int64) error
+	CashIn(ctx context.Context, requester int64, c *CashIn) (*Transaction, error)
+	SettleCashIn(ctx context.Context, ID int64) (*Transaction, error)
+	CancelCashIn(ctx context.Context, ID int64) (*Transaction, error)
+	AccountTransactions(ctx context.Context, requester int64, f *HistoryFilter) (*HistoryPage, error)
+	// AnyAccountTransactions skips the ownership check, it is meant for admins.
+	AnyAccountTransactions(ctx context.Context, f *HistoryFilter) (*HistoryPage, error)
+	Statement(ctx context.Context, requester, accountID int64, from, to time.Time) (*Statement, error)
+	// CreateTransaction converts Value to the currency of the receiver when it differs.
+	// Transfers over the limits of the requester's role or the account kind fail with *LimitError.
+	CreateTransaction(ctx context.Context, requester *User, SenderID, ReceiverID, Value int64) (*Transaction, error)
+	// CreateQuotedTransaction is CreateTransaction that fails with ErrFeeChanged
+	// when the current fee is not Fee the user was quoted.
+	CreateQuotedTransaction(ctx context.Context, requester *User, SenderID, ReceiverID, Value, Fee int64) (*Transaction, error)
+	// QuoteTransaction tells the fee and the converted amount of a transfer without making it.
+	QuoteTransaction(ctx context.Context, requester *User, SenderID, ReceiverID, Value int64) (*Quote, error)
+	// ReverseTransaction returns what is left of a transfer and its fee to the sender, it is meant for admins.
+	ReverseTransaction(ctx context.Context, ID int64) (*Transaction, error)
+	// RefundTransaction lets the receiver send back a part of a transfer, amount is in the receiver's currency.
+	RefundTransaction(ctx context.Context, requester, ID, amount int64) (*Transaction, error)
+	// CreateHold reserves money on an account of the requester for a payment to h.ReceiverID.
+	CreateHold(ctx context.Context, requester *User, h *Hold) error
+	// Holds lists holds placed on an account of the requester or in its favour.
+	Holds(ctx context.Context, requester, accountID int64) ([]*Hold, error)
+	// CaptureHold lets the receiver take amount of a hold, zero amount is everything that is left.
+	CaptureHold(ctx context.Context, requester, ID, amount int64) (*Transaction, error)
+	// VoidHold lets the receiver release what is left of a hold.
+	VoidHold(ctx context.Context, requester, ID int64) error
+	// IssueCard issues a card to an account of the requester, PAN and CVV are set only here.
+	IssueCard(ctx context.Context, requester, accountID int64) (*Card, error)
+	Cards(ctx context.Context, requester int64) ([]*Card, error)
+	BlockCard(ctx context.Context, requester, ID int64) (*Card, error)
+	UnblockCard(ctx context.Context, requester, ID int64) (*Card, error)
+	// ProcessCardMessage authorizes, completes or reverses a card payment to a merchant.
+	// It fills the outcome of m, a declined message fails with the reason.
+	ProcessCardMessage(ctx context.Context, m *CardMessage) error
+	CheckLedger(ctx context.Context) error
+	CreateSchedule(ctx context.Context, requester *User, s *Schedule) error
+	Schedules(ctx context.Context, requester int64) ([]*Schedule, error)
+	ScheduleRuns(ctx context.Context, requester, scheduleID int64) ([]*ScheduleRun, error)
+	CancelSchedule(ctx context.Context, requester, scheduleID int64) error
+	// RunScheduler executes due schedules until ctx is done.
+	RunScheduler(ctx context.Context)
+	// RunRelay publishes events from the outbox until ctx is done.
+	RunRelay(ctx context.Context)
+	// CreateWebhook registers a webhook on an account of the requester.
+	CreateWebhook(ctx context.Context, requester int64, w *Webhook) error
+	// AnyCreateWebhook skips the ownership check, it is meant for admins.
+	AnyCreateWebhook(ctx context.Context, w *Webhook) error
+	Webhooks(ctx context.Context, requester int64) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, requester, ID int64) error
+	// WebhookDeliveries lists deliveries of a webhook of the requester, empty status is every status.
+	WebhookDeliveries(ctx context.Context, requester, webhookID int64, status string) ([]*WebhookDelivery, error)
+	// AnyDeliveries lists deliveries of every webhook, it is meant for admins.
+	AnyDeliveries(ctx context.Context, status string) ([]*WebhookDelivery, error)
+	RedeliverDelivery(ctx context.Context, requester, ID int64) (*WebhookDelivery, error)
+	// AnyRedeliverDelivery skips the ownership check, it is meant for admins.
+	AnyRedeliverDelivery(ctx context.Context, ID int64) (*WebhookDelivery, error)
+	// RunWebhooks sends due webhook deliveries until ctx is done.
+	RunWebhooks(ctx context.Context)
+	// RiskQueue lists transfers held for review or blocked, empty status is every status.
+	RiskQueue(ctx context.Context, status string) ([]*RiskDecision, error)
+	// ApproveRiskDecision makes the transfer of an open decision, it is meant for admins.
+	ApproveRiskDecision(ctx context.Context, admin *User, ID int64) (*RiskDecision, error)
+	RejectRiskDecision(ctx context.Context, admin *User, ID int64) (*RiskDecision, error)
+	// ComplianceCases lists transfers flagged or blocked by screening, empty status is every status.
+	ComplianceCases(ctx context.Context, status string) ([]*ComplianceCase, error)
+	// ResolveCase clears or confirms an open case, it is meant for compliance officers.
+	ResolveCase(ctx context.Context, officer *User, ID int64, status, note string) (*ComplianceCase, error)
+	Rates() *Rates
+	FeeSchedule() *FeeSchedule
+	SetRates(r *Rates) error
+	StartIdempotent(ctx context.Context, key *IdempotencyKey) (*IdempotencyKey, error)
+	FinishIdempotent(ctx context.Context, key *IdempotencyKey) error
 	Close()
 }
 
@@ -20,9 +98,92 @@ type Repository interface {
 	FindAccount(ctx context.Context, ID int64) (*Account, error)
 	GetAccounts(ctx context.Context, OwnerID int64) ([]*Account, error)
 	GetLastTransaction(ctx context.Context, accountID int64) (*Transaction, error)
-	AccountTransactions(ctx context.Context, accountID int64) ([]*Transaction, error)
-	ChangeAccountSum(ctx context.Context, accountID, newValue int64) error
-	CreateTransaction(ctx context.Context, SenderID, ReceiverID, Value int64) error
+	AccountTransactions(ctx context.Context, f *HistoryFilter) (*HistoryPage, error)
+	BalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error)
+	// AccountTurnover sums money in and out of the account booked within [from, to).
+	AccountTurnover(ctx context.Context, accountID int64, from, to time.Time) (in, out int64, err error)
+	CreateCashIn(ctx context.Context, t *Transaction) error
+	CompleteCashIn(ctx context.Context, ID int64, status string) (*Transaction, error)
+	// CreateTransaction calls check with the sender's usage under the same locks
+	// that move the money, so concurrent transfers can't slip past the limits.
+	CreateTransaction(ctx context.Context, t *Transaction, check func(u *TransferUsage) error) error
+	FindTransaction(ctx context.Context, ID int64) (*Transaction, error)
+	// CreateCompensation sends amount of a transfer back at the rate of the transfer.
+	// Zero amount is everything that is left, a reversal returns the fee too.
+	CreateCompensation(ctx context.Context, originalID int64, kind string, amount int64) (*Transaction, error)
+	// CreateHold calls check like CreateTransaction, holds don't count in usage.
+	CreateHold(ctx context.Context, h *Hold, check func(u *TransferUsage) error) error
+	FindHold(ctx context.Context, ID int64) (*Hold, error)
+	Holds(ctx context.Context, accountID int64) ([]*Hold, error)
+	// CaptureHold makes transfer t out of the hold t.HoldID,
+	// last releases what is left of the hold with it.
+	CaptureHold(ctx context.Context, t *Transaction, last bool, check func(u *TransferUsage) error) error
+	VoidHold(ctx context.Context, ID int64) error
+	// RelayEvents hands unpublished outbox events to publish and marks the accepted ones.
+	RelayEvents(ctx context.Context, limit int, publish func(e *Event) error) (int, error)
+	CreateWebhook(ctx context.Context, w *Webhook) error
+	FindWebhook(ctx context.Context, ID int64) (*Webhook, error)
+	Webhooks(ctx context.Context, ownerID int64) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, ID int64) error
+	// EnqueueDeliveries adds a delivery of the event for every webhook that wants it.
+	EnqueueDeliveries(ctx context.Context, e *Event) (int, error)
+	// ClaimDeliveries takes due deliveries and postpones them by lease,
+	// so other instances skip them while they are sent.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error)
+	FinishDelivery(ctx context.Context, d *WebhookDelivery) error
+	FindDelivery(ctx context.Context, ID int64) (*WebhookDelivery, error)
+	Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]*WebhookDelivery, error)
+	RedeliverDelivery(ctx context.Context, ID int64) (*WebhookDelivery, error)
 	AccountExists(ctx context.Context, accountID int64) bool
+	// NextAccountNumber takes the next number of the account number sequence.
+	NextAccountNumber(ctx context.Context) (int64, error)
+	// AccountIDByNumber finds the internal ID of the account with the IBAN.
+	AccountIDByNumber(ctx context.Context, number string) (int64, error)
+	// CreateCard fails with ErrCardExists when the PAN hash is taken.
+	CreateCard(ctx context.Context, c *Card) error
+	FindCard(ctx context.Context, ID int64) (*Card, error)
+	CardByPAN(ctx context.Context, panHash string) (*Card, error)
+	Cards(ctx context.Context, ownerID int64) ([]*Card, error)
+	SetCardStatus(ctx context.Context, ID int64, status string) error
+	// ReserveCardMessage stores the message as pending before it is processed,
+	// ErrCardMessageReplayed when the terminal sent it before.
+	ReserveCardMessage(ctx context.Context, m *CardMessage) error
+	// ApproveCardMessage stores the outcome of a reserved message.
+	ApproveCardMessage(ctx context.Context, m *CardMessage) error
+	// ReleaseCardMessage drops a reserved message that was declined.
+	ReleaseCardMessage(ctx context.Context, ID int64) error
+	// FindCardMessage finds the approved message of the kind with the RRN from the terminal.
+	FindCardMessage(ctx context.Context, terminalID, RRN, kind string) (*CardMessage, error)
+	CheckLedger(ctx context.Context) error
+	CreateSchedule(ctx context.Context, s *Schedule) error
+	FindSchedule(ctx context.Context, ID int64) (*Schedule, error)
+	Schedules(ctx context.Context, ownerID int64) ([]*Schedule, error)
+	ScheduleRuns(ctx context.Context, scheduleID int64) ([]*ScheduleRun, error)
+	CancelSchedule(ctx context.Context, ownerID, ID int64) error
+	// ClaimSchedules takes due schedules and postpones them by lease,
+	// so other instances skip them while they run.
+	ClaimSchedules(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Schedule, error)
+	// StartScheduleRun returns the run of the current occurrence and attempt,
+	// creating it when needed. TransactionID is set when the run already made its transfer.
+	StartScheduleRun(ctx context.Context, s *Schedule) (*ScheduleRun, error)
+	// FinishScheduleRun stores the outcome of the run and the next state of the schedule.
+	FinishScheduleRun(ctx context.Context, run *ScheduleRun, s *Schedule) error
+	// ReserveIdempotencyKey takes over a key whose lease ran out before it was answered.
+	ReserveIdempotencyKey(ctx context.Context, key *IdempotencyKey, ttl time.Duration) (*IdempotencyKey, error)
+	SaveIdempotencyKey(ctx context.Context, key *IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, ownerID int64, key string) error
+	RiskHistory
+	CreateRiskDecision(ctx context.Context, d *RiskDecision) error
+	FindRiskDecision(ctx context.Context, ID int64) (*RiskDecision, error)
+	// RiskDecisions lists decisions to review or block newest first, empty status is every status.
+	RiskDecisions(ctx context.Context, status string, limit int) ([]*RiskDecision, error)
+	// ResolveRiskDecision stores the status of an open decision, a decision
+	// with a transfer made on it can't be rejected.
+	ResolveRiskDecision(ctx context.Context, d *RiskDecision) error
+	CreateCase(ctx context.Context, c *ComplianceCase) error
+	// Cases lists compliance cases newest first, empty status is every status.
+	Cases(ctx context.Context, status string, limit int) ([]*ComplianceCase, error)
+	// ResolveCase stores the status, note and resolver of an open case.
+	ResolveCase(ctx context.Context, c *ComplianceCase) error
 	CloseConnection()
 }
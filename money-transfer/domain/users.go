@@ -0,0 +1,13 @@
+package domain
+
+import "context"
+
+// UserDirectory reads users from auth-service, which keeps them.
+// Roles change there, so work done later on behalf of a user
+// asks for the current role instead of the one the user had at the time.
+type UserDirectory interface {
+	// Role returns the current role of the user, ErrUserNotFound when there is no such user.
+	Role(ctx context.Context, ID int64) (string, error)
+	// User returns the user with its current role and name, ErrUserNotFound when there is no such user.
+	User(ctx context.Context, ID int64) (*User, error)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Statuses of webhook deliveries. A delivery is dead when it ran out
+// of attempts, it waits in the dead-letter list until it is redelivered.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook receives events of an account at URL. Without Events it gets
+// every event of the account. Secret signs the deliveries,
+// it is shown only when the webhook is created.
+type Webhook struct {
+	ID            int64     `json:"ID"`
+	OwnerID       int64     `json:"OwnerID"`
+	AccountID     int64     `json:"-"`
+	AccountNumber string    `json:"AccountNumber"`
+	URL           string    `json:"URL"`
+	Secret        string    `json:"Secret,omitempty"`
+	Events        []string  `json:"Events"`
+	Created       time.Time `json:"Created"`
+}
+
+// WebhookDelivery is an event on its way to a webhook.
+type WebhookDelivery struct {
+	ID           int64      `json:"ID"`
+	WebhookID    int64      `json:"WebhookID"`
+	EventID      int64      `json:"EventID"`
+	EventType    string     `json:"EventType"`
+	Status       string     `json:"Status"`
+	Attempts     int        `json:"Attempts"`
+	NextAttempt  time.Time  `json:"NextAttempt"`
+	ResponseCode int        `json:"ResponseCode,omitempty"`
+	LastError    string     `json:"LastError,omitempty"`
+	Created      time.Time  `json:"Created"`
+	Delivered    *time.Time `json:"Delivered,omitempty"`
+
+	// Webhook and Event are filled for the dispatcher only.
+	Webhook *Webhook `json:"-"`
+	Event   *Event   `json:"-"`
+}
@@ -1,21 +1,491 @@
+-- ID is the internal key, Number is the IBAN customers know the account by.
+-- System accounts have no number.
 CREATE TABLE IF NOT EXISTS accounts (
     ID BIGSERIAL NOT NULL PRIMARY KEY,
+    Number VARCHAR(20) UNIQUE,
     OwnerID BIGSERIAL NOT NULL,
     IIN VARCHAR NOT NULL,
     Amount BIGSERIAL NOT NULL,
-    Registered TIMESTAMP NOT NULL
+    Kind VARCHAR NOT NULL DEFAULT 'customer',
+    Currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+    Registered TIMESTAMP NOT NULL,
+    CONSTRAINT accounts_amount_non_negative CHECK (Amount >= 0 OR Kind <> 'customer')
 );
 
+-- Amounts were whole tenge before currencies came and are minor units
+-- since, databases without the Currency column are scaled once here.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema()
+            AND table_name = 'accounts' AND column_name = 'currency'
+    ) THEN
+        UPDATE accounts SET Amount = Amount * 100;
+        UPDATE transactions SET Amount = Amount * 100;
+        IF to_regclass('postings') IS NOT NULL THEN
+            UPDATE postings SET Amount = Amount * 100;
+        END IF;
+    END IF;
+END $$;
+
+-- Databases made before the ledger, currencies and account numbers
+-- get the columns here, Number is backfilled after account_number_seq.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS Number VARCHAR(20) UNIQUE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS Kind VARCHAR NOT NULL DEFAULT 'customer';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS Currency VARCHAR(3) NOT NULL DEFAULT 'KZT';
+
+DO $$
+BEGIN
+    ALTER TABLE accounts ADD CONSTRAINT accounts_amount_non_negative CHECK (Amount >= 0 OR Kind <> 'customer');
+EXCEPTION WHEN duplicate_object THEN
+    NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    Kind VARCHAR NOT NULL,
+    Description VARCHAR NOT NULL,
+    Created TIMESTAMP NOT NULL
+);
+
+-- Positive amounts credit the account, negative amounts debit it.
+-- Postings of every journal entry must sum to zero.
+CREATE TABLE IF NOT EXISTS postings (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    EntryID BIGINT NOT NULL,
+    AccountID BIGINT NOT NULL,
+    Amount BIGINT NOT NULL,
+    FOREIGN KEY (EntryID) REFERENCES journal_entries (ID),
+    FOREIGN KEY (AccountID) REFERENCES accounts (ID)
+);
+
+CREATE INDEX IF NOT EXISTS postings_entry_idx ON postings (EntryID);
+CREATE INDEX IF NOT EXISTS postings_account_idx ON postings (AccountID);
+
+-- Money reserved for a payment to ReceiverID. Holds don't post to the ledger,
+-- the available balance of an account is Amount less Amount - Captured and
+-- Fee - FeeCaptured of its active holds that haven't expired yet. Fee is the
+-- fee of the whole amount, captures pay their part of it.
+CREATE TABLE IF NOT EXISTS holds (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    AccountID BIGINT NOT NULL,
+    ReceiverID BIGINT NOT NULL,
+    Role VARCHAR NOT NULL,
+    Amount BIGINT NOT NULL,
+    Captured BIGINT NOT NULL DEFAULT 0,
+    Fee BIGINT NOT NULL DEFAULT 0,
+    FeeCaptured BIGINT NOT NULL DEFAULT 0,
+    Currency VARCHAR(3) NOT NULL,
+    Status VARCHAR NOT NULL DEFAULT 'active',
+    Created TIMESTAMP NOT NULL,
+    Expires TIMESTAMP NOT NULL,
+    CONSTRAINT holds_captured_within_amount CHECK (Captured >= 0 AND Captured <= Amount),
+    FOREIGN KEY (AccountID) REFERENCES accounts (ID),
+    FOREIGN KEY (ReceiverID) REFERENCES accounts (ID)
+);
+
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS Fee BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS FeeCaptured BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS holds_active_idx ON holds (AccountID, Expires) WHERE Status = 'active';
+CREATE INDEX IF NOT EXISTS holds_receiver_idx ON holds (ReceiverID);
+
+-- Virtual cards of accounts. PANHash is an HMAC of the PAN under card_pan_key,
+-- the PAN and the CVV themselves are never stored.
+CREATE TABLE IF NOT EXISTS cards (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    AccountID BIGINT NOT NULL,
+    OwnerID BIGINT NOT NULL,
+    PANHash VARCHAR(64) NOT NULL UNIQUE,
+    BIN VARCHAR(6) NOT NULL,
+    Last4 VARCHAR(4) NOT NULL,
+    CVVHash VARCHAR NOT NULL,
+    Status VARCHAR NOT NULL DEFAULT 'active',
+    Created TIMESTAMP NOT NULL,
+    Expires TIMESTAMP NOT NULL,
+    FOREIGN KEY (AccountID) REFERENCES accounts (ID)
+);
+
+CREATE INDEX IF NOT EXISTS cards_owner_idx ON cards (OwnerID);
+
+-- Card messages of terminals. A message is reserved as pending before it
+-- is processed and approved after, a declined one is deleted. A repeated
+-- message is answered from here, a reversal finds the message it undoes
+-- by terminal and RRN. CardID is NULL until the message is approved.
+CREATE TABLE IF NOT EXISTS card_messages (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    Kind VARCHAR NOT NULL,
+    Status VARCHAR NOT NULL DEFAULT 'approved',
+    TerminalID VARCHAR(8) NOT NULL,
+    MerchantID VARCHAR(15) NOT NULL,
+    RRN VARCHAR(12) NOT NULL,
+    Amount BIGINT NOT NULL,
+    Currency VARCHAR(3) NOT NULL,
+    CardID BIGINT,
+    HoldID BIGINT,
+    TransactionID BIGINT,
+    Created TIMESTAMP NOT NULL,
+    CONSTRAINT card_messages_terminal_rrn_key UNIQUE (TerminalID, RRN, Kind),
+    FOREIGN KEY (CardID) REFERENCES cards (ID),
+    FOREIGN KEY (HoldID) REFERENCES holds (ID)
+);
+
+ALTER TABLE card_messages ADD COLUMN IF NOT EXISTS Status VARCHAR NOT NULL DEFAULT 'approved';
+ALTER TABLE card_messages ALTER COLUMN CardID DROP NOT NULL;
+
+-- Outcomes of the risk check of transfers and holds. Decisions to review or
+-- block stay open until an admin resolves them, the transfer or hold made on
+-- approval points back with transactions.RiskDecisionID or holds.RiskDecisionID.
+CREATE TABLE IF NOT EXISTS risk_decisions (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    OwnerID BIGINT NOT NULL,
+    Role VARCHAR NOT NULL,
+    SenderID BIGINT NOT NULL,
+    ReceiverID BIGINT NOT NULL,
+    Amount BIGINT NOT NULL,
+    Currency VARCHAR(3) NOT NULL,
+    BaseAmount BIGINT NOT NULL,
+    Outcome VARCHAR NOT NULL,
+    Rule VARCHAR NOT NULL DEFAULT '',
+    Fired VARCHAR[] NOT NULL,
+    Reason VARCHAR NOT NULL DEFAULT '',
+    Status VARCHAR NOT NULL,
+    Created TIMESTAMP NOT NULL,
+    Resolved TIMESTAMP,
+    ResolvedBy BIGINT NOT NULL DEFAULT 0,
+    ScheduleRunID BIGINT,
+    Kind VARCHAR NOT NULL DEFAULT 'transfer',
+    FOREIGN KEY (SenderID) REFERENCES accounts (ID),
+    FOREIGN KEY (ReceiverID) REFERENCES accounts (ID)
+);
+
+ALTER TABLE risk_decisions ADD COLUMN IF NOT EXISTS ScheduleRunID BIGINT;
+ALTER TABLE risk_decisions ADD COLUMN IF NOT EXISTS Kind VARCHAR NOT NULL DEFAULT 'transfer';
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS RiskDecisionID BIGINT UNIQUE REFERENCES risk_decisions (ID);
+
+CREATE INDEX IF NOT EXISTS risk_decisions_queue_idx ON risk_decisions (ID) WHERE Outcome <> 'allow';
+
+-- Transfers flagged or blocked by sanctions screening, Matches are
+-- domain.ScreeningMatch values. Blocked transfers are never made.
+CREATE TABLE IF NOT EXISTS compliance_cases (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    OwnerID BIGINT NOT NULL,
+    SenderID BIGINT NOT NULL,
+    ReceiverID BIGINT NOT NULL,
+    Amount BIGINT NOT NULL,
+    Currency VARCHAR(3) NOT NULL,
+    BaseAmount BIGINT NOT NULL,
+    SenderIIN VARCHAR NOT NULL,
+    ReceiverIIN VARCHAR NOT NULL,
+    Action VARCHAR NOT NULL,
+    Matches JSONB NOT NULL,
+    Status VARCHAR NOT NULL,
+    Note VARCHAR NOT NULL DEFAULT '',
+    Created TIMESTAMP NOT NULL,
+    Resolved TIMESTAMP,
+    ResolvedBy BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE compliance_cases ADD COLUMN IF NOT EXISTS SenderName VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE compliance_cases ADD COLUMN IF NOT EXISTS ReceiverName VARCHAR NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS compliance_cases_status_idx ON compliance_cases (Status, ID);
+
 CREATE TABLE IF NOT EXISTS transactions (
     ID BIGSERIAL NOT NULL PRIMARY KEY,
     SenderID BIGSERIAL NOT NULL,
     ReceiverID BIGSERIAL NOT NULL,
     Amount BIGSERIAL NOT NULL,
     Date TIMESTAMP NOT NULL,
+    EntryID BIGINT NOT NULL,
+    Kind VARCHAR NOT NULL DEFAULT 'transfer',
+    Status VARCHAR NOT NULL DEFAULT 'settled',
+    Source VARCHAR NOT NULL DEFAULT '',
+    Currency VARCHAR(3) NOT NULL,
+    ReceivedAmount BIGINT NOT NULL,
+    ReceivedCurrency VARCHAR(3) NOT NULL,
+    Rate VARCHAR NOT NULL,
+    BaseAmount BIGINT NOT NULL DEFAULT 0,
+    Fee BIGINT NOT NULL DEFAULT 0,
+    ScheduleRunID BIGINT UNIQUE,
+    OriginalID BIGINT,
+    HoldID BIGINT,
+    RiskDecisionID BIGINT UNIQUE,
+    FOREIGN KEY (SenderID) REFERENCES accounts (ID),
+    FOREIGN KEY (ReceiverID) REFERENCES accounts (ID),
+    FOREIGN KEY (EntryID) REFERENCES journal_entries (ID),
+    FOREIGN KEY (OriginalID) REFERENCES transactions (ID),
+    FOREIGN KEY (HoldID) REFERENCES holds (ID),
+    FOREIGN KEY (RiskDecisionID) REFERENCES risk_decisions (ID)
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS EntryID BIGINT REFERENCES journal_entries (ID);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS Kind VARCHAR NOT NULL DEFAULT 'transfer';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS Status VARCHAR NOT NULL DEFAULT 'settled';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS Source VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS Currency VARCHAR(3);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS ReceivedAmount BIGINT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS ReceivedCurrency VARCHAR(3);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS Rate VARCHAR;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS BaseAmount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS Fee BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS ScheduleRunID BIGINT UNIQUE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS OriginalID BIGINT REFERENCES transactions (ID);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS HoldID BIGINT REFERENCES holds (ID);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS RiskDecisionID BIGINT UNIQUE REFERENCES risk_decisions (ID);
+
+-- Transfers made before currencies were all in KZT.
+UPDATE transactions SET
+    Currency = 'KZT',
+    ReceivedAmount = Amount,
+    ReceivedCurrency = 'KZT',
+    Rate = '1',
+    BaseAmount = Amount
+WHERE Currency IS NULL;
+
+ALTER TABLE transactions
+    ALTER COLUMN Currency SET NOT NULL,
+    ALTER COLUMN ReceivedAmount SET NOT NULL,
+    ALTER COLUMN ReceivedCurrency SET NOT NULL,
+    ALTER COLUMN Rate SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_original_idx ON transactions (OriginalID);
+
+-- Transfer limits sum what an account sent in rolling windows.
+CREATE INDEX IF NOT EXISTS transactions_sender_date_idx ON transactions (SenderID, Date);
+
+-- Standing orders. NextRun is when the scheduler looks at the schedule next,
+-- Occurrence is the one being paid and Attempt counts its retries.
+CREATE TABLE IF NOT EXISTS schedules (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    OwnerID BIGINT NOT NULL,
+    Role VARCHAR NOT NULL,
+    SenderID BIGINT NOT NULL,
+    ReceiverID BIGINT NOT NULL,
+    Amount BIGINT NOT NULL,
+    Cron VARCHAR NOT NULL DEFAULT '',
+    Start TIMESTAMP NOT NULL,
+    Until TIMESTAMP,
+    Count INT NOT NULL DEFAULT 0,
+    Remaining INT NOT NULL DEFAULT 0,
+    Status VARCHAR NOT NULL DEFAULT 'active',
+    Occurrence TIMESTAMP NOT NULL,
+    Attempt INT NOT NULL DEFAULT 0,
+    NextRun TIMESTAMP NOT NULL,
+    Created TIMESTAMP NOT NULL,
     FOREIGN KEY (SenderID) REFERENCES accounts (ID),
     FOREIGN KEY (ReceiverID) REFERENCES accounts (ID)
 );
 
-INSERT INTO accounts(ID, OwnerID, IIN, Amount, Registered)
-VALUES (4405211239547816, 999, '921115350186', 1000000000, '2022-01-03 11:51:40.244153')
-ON CONFLICT DO NOTHING;
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS schedules_due_idx ON schedules (NextRun) WHERE Status = 'active';
+CREATE INDEX IF NOT EXISTS schedules_owner_idx ON schedules (OwnerID);
+
+-- Every attempt to pay an occurrence. The transfer made by a run
+-- points back to it with transactions.ScheduleRunID.
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    ScheduleID BIGINT NOT NULL,
+    Occurrence TIMESTAMP NOT NULL,
+    Attempt INT NOT NULL,
+    Status VARCHAR NOT NULL,
+    Error VARCHAR NOT NULL DEFAULT '',
+    Started TIMESTAMP NOT NULL,
+    Finished TIMESTAMP,
+    UNIQUE (ScheduleID, Occurrence, Attempt),
+    FOREIGN KEY (ScheduleID) REFERENCES schedules (ID)
+);
+
+-- Events are written here in the same transaction as the change they describe
+-- and published to the broker by the relay. Published is set once the broker took them.
+CREATE TABLE IF NOT EXISTS outbox (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    Type VARCHAR NOT NULL,
+    Accounts BIGINT[] NOT NULL,
+    Payload JSONB NOT NULL,
+    Created TIMESTAMP NOT NULL,
+    Published TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (ID) WHERE Published IS NULL;
+
+-- Endpoints that get events of an account, all events when Events is empty.
+CREATE TABLE IF NOT EXISTS webhooks (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    OwnerID BIGINT NOT NULL,
+    AccountID BIGINT NOT NULL,
+    URL VARCHAR NOT NULL,
+    Secret VARCHAR NOT NULL,
+    Events VARCHAR[] NOT NULL,
+    Created TIMESTAMP NOT NULL,
+    FOREIGN KEY (AccountID) REFERENCES accounts (ID)
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_account_idx ON webhooks (AccountID);
+CREATE INDEX IF NOT EXISTS webhooks_owner_idx ON webhooks (OwnerID);
+
+-- An event on its way to a webhook. Every instance hears every event,
+-- the unique key keeps one delivery of it per webhook.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    ID BIGSERIAL NOT NULL PRIMARY KEY,
+    WebhookID BIGINT NOT NULL,
+    EventID BIGINT NOT NULL,
+    EventType VARCHAR NOT NULL,
+    Status VARCHAR NOT NULL DEFAULT 'pending',
+    Attempts INT NOT NULL DEFAULT 0,
+    NextAttempt TIMESTAMP NOT NULL,
+    ResponseCode INT NOT NULL DEFAULT 0,
+    LastError VARCHAR NOT NULL DEFAULT '',
+    Created TIMESTAMP NOT NULL,
+    Delivered TIMESTAMP,
+    UNIQUE (WebhookID, EventID),
+    FOREIGN KEY (WebhookID) REFERENCES webhooks (ID) ON DELETE CASCADE,
+    FOREIGN KEY (EventID) REFERENCES outbox (ID)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (NextAttempt) WHERE Status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_dead_idx ON webhook_deliveries (WebhookID) WHERE Status = 'dead';
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    Key VARCHAR NOT NULL,
+    OwnerID BIGINT NOT NULL,
+    Fingerprint VARCHAR NOT NULL,
+    StatusCode INT NOT NULL DEFAULT 0,
+    Header JSONB,
+    Response BYTEA,
+    Created TIMESTAMP NOT NULL,
+    Leased TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (OwnerID, Key)
+);
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS Header JSONB;
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS Leased TIMESTAMP NOT NULL DEFAULT now();
+
+-- System accounts are owned by nobody (OwnerID 0) and may go negative.
+-- Every currency has its own set, FX accounts hold the currency position.
+INSERT INTO accounts(ID, OwnerID, IIN, Amount, Kind, Currency, Registered)
+VALUES
+    (1, 0, '', 0, 'cash_in', 'KZT', '2022-01-03 00:00:00'),
+    (2, 0, '', 0, 'fees', 'KZT', '2022-01-03 00:00:00'),
+    (3, 0, '', 0, 'suspense', 'KZT', '2022-01-03 00:00:00'),
+    (4, 0, '', 0, 'fx', 'KZT', '2022-01-03 00:00:00'),
+    (5, 0, '', 0, 'cash_in', 'USD', '2022-01-03 00:00:00'),
+    (6, 0, '', 0, 'fees', 'USD', '2022-01-03 00:00:00'),
+    (7, 0, '', 0, 'suspense', 'USD', '2022-01-03 00:00:00'),
+    (8, 0, '', 0, 'fx', 'USD', '2022-01-03 00:00:00'),
+    (9, 0, '', 0, 'cash_in', 'EUR', '2022-01-03 00:00:00'),
+    (10, 0, '', 0, 'fees', 'EUR', '2022-01-03 00:00:00'),
+    (11, 0, '', 0, 'suspense', 'EUR', '2022-01-03 00:00:00'),
+    (12, 0, '', 0, 'fx', 'EUR', '2022-01-03 00:00:00'),
+    (13, 0, '', 0, 'cash_in', 'RUB', '2022-01-03 00:00:00'),
+    (14, 0, '', 0, 'fees', 'RUB', '2022-01-03 00:00:00'),
+    (15, 0, '', 0, 'suspense', 'RUB', '2022-01-03 00:00:00'),
+    (16, 0, '', 0, 'fx', 'RUB', '2022-01-03 00:00:00')
+ON CONFLICT DO NOTHING;
+
+CREATE UNIQUE INDEX IF NOT EXISTS accounts_system_idx ON accounts (Kind, Currency) WHERE OwnerID = 0;
+
+-- Account numbers of the bank are taken in order, the demo account has the first one.
+CREATE SEQUENCE IF NOT EXISTS account_number_seq;
+
+INSERT INTO accounts(ID, Number, OwnerID, IIN, Amount, Registered)
+VALUES (4405211239547816, 'KZ699990000000000001', 999, '921115350186', 0, '2022-01-03 11:51:40.244153')
+ON CONFLICT (ID) DO UPDATE SET Number = COALESCE(accounts.Number, EXCLUDED.Number);
+
+SELECT setval('account_number_seq', GREATEST(last_value, 1)) FROM account_number_seq;
+
+-- Customer accounts made before the numbering get the next numbers in the
+-- order they were opened, under the bank code of configs/server.toml.
+-- The check digits are 98 less the BBAN followed by KZ00 (2035 00) modulo 97.
+WITH numbered AS (
+    SELECT ID, '999' || lpad(nextval('account_number_seq')::text, 13, '0') AS BBAN
+    FROM (
+        SELECT ID FROM accounts
+        WHERE Number IS NULL AND Kind = 'customer'
+        ORDER BY Registered, ID
+    ) unnumbered
+)
+UPDATE accounts SET Number = 'KZ' || lpad((98 - mod((numbered.BBAN || '203500')::numeric, 97))::text, 2, '0') || numbered.BBAN
+FROM numbered
+WHERE accounts.ID = numbered.ID;
+
+-- accounts made before the sequence took over have random 16 digit IDs,
+-- new ones count on from the system accounts
+SELECT setval(pg_get_serial_sequence('accounts', 'id'), GREATEST(last_value, 100)) FROM accounts_id_seq;
+
+-- Balances of accounts made before the ledger include top-ups that were
+-- never posted, they open with the difference from the cash-in account.
+-- Transfers not posted yet count as posted, they are posted below so the
+-- running balance starts from the opening entry.
+WITH moves AS (
+    SELECT AccountID, SUM(Amount) AS Amount
+    FROM (
+        SELECT AccountID, Amount FROM postings
+        UNION ALL
+        SELECT SenderID, -Amount FROM transactions WHERE EntryID IS NULL
+        UNION ALL
+        SELECT ReceiverID, Amount FROM transactions WHERE EntryID IS NULL
+    ) legs
+    GROUP BY AccountID
+), opening AS (
+    SELECT a.ID, a.Currency, a.Registered,
+        a.Amount - COALESCE(m.Amount, 0) AS Amount,
+        nextval(pg_get_serial_sequence('journal_entries', 'id')) AS EntryID
+    FROM accounts a
+    LEFT JOIN moves m ON m.AccountID = a.ID
+    WHERE a.Kind = 'customer' AND a.Amount <> COALESCE(m.Amount, 0)
+), entries AS (
+    INSERT INTO journal_entries(ID, Kind, Description, Created)
+    SELECT EntryID, 'opening', 'opening balance', Registered FROM opening
+), legs AS (
+    INSERT INTO postings(EntryID, AccountID, Amount)
+    SELECT o.EntryID, c.ID, -o.Amount
+    FROM opening o
+    JOIN accounts c ON c.OwnerID = 0 AND c.Kind = 'cash_in' AND c.Currency = o.Currency
+    UNION ALL
+    SELECT EntryID, ID, Amount FROM opening
+)
+UPDATE accounts SET Amount = accounts.Amount - cash_in.Amount
+FROM (SELECT Currency, SUM(Amount) AS Amount FROM opening GROUP BY Currency) cash_in
+WHERE accounts.OwnerID = 0 AND accounts.Kind = 'cash_in' AND accounts.Currency = cash_in.Currency;
+
+-- Transfers made before the ledger get the journal entry they would have
+-- been posted with, numbered after the opening entries.
+WITH legacy AS (
+    SELECT ID, SenderID, ReceiverID, Amount, Date,
+        nextval(pg_get_serial_sequence('journal_entries', 'id')) AS EntryID
+    FROM transactions
+    WHERE EntryID IS NULL
+    ORDER BY Date, ID
+), entries AS (
+    INSERT INTO journal_entries(ID, Kind, Description, Created)
+    SELECT EntryID, 'transfer', 'transfer from ' || SenderID || ' to ' || ReceiverID, Date FROM legacy
+), legs AS (
+    INSERT INTO postings(EntryID, AccountID, Amount)
+    SELECT EntryID, SenderID, -Amount FROM legacy
+    UNION ALL
+    SELECT EntryID, ReceiverID, Amount FROM legacy
+)
+UPDATE transactions SET EntryID = legacy.EntryID
+FROM legacy
+WHERE transactions.ID = legacy.ID;
+
+ALTER TABLE transactions ALTER COLUMN EntryID SET NOT NULL;
+
+-- Opening balance of the demo account comes from the cash-in account.
+WITH entry AS (
+    INSERT INTO journal_entries(Kind, Description, Created)
+    SELECT 'opening', 'opening balance', '2022-01-03 11:51:40.244153'
+    WHERE NOT EXISTS (SELECT 1 FROM postings WHERE AccountID = 4405211239547816)
+    RETURNING ID
+), legs AS (
+    INSERT INTO postings(EntryID, AccountID, Amount)
+    SELECT ID, 1, -1000000000 FROM entry
+    UNION ALL
+    SELECT ID, 4405211239547816, 1000000000 FROM entry
+    RETURNING AccountID, Amount
+)
+UPDATE accounts SET Amount = accounts.Amount + legs.Amount
+FROM legs
+WHERE accounts.ID = legs.AccountID;
+
@@ -0,0 +1,20 @@
+// Package broker carries domain events published by the outbox relay.
+package broker
+
+import (
+	"fmt"
+
+	"money-transfer/domain"
+)
+
+// New returns the broker named in the config.
+func New(c *domain.Config) (domain.Broker, error) {
+	switch c.Broker {
+	case "", "inproc":
+		return NewInProc(), nil
+	case "nats":
+		return DialNATS(c.NATSURL, c.BrokerSubject)
+	default:
+		return nil, fmt.Errorf("unknown broker %q", c.Broker)
+	}
+}
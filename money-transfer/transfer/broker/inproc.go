@@ -0,0 +1,43 @@
+package broker
+
+import (
+	"context"
+	"sync"
+
+	"money-transfer/domain"
+)
+
+// inProc hands events to subscribers of the same process.
+// Nothing leaves the process, it is meant for a single instance.
+type inProc struct {
+	mu       sync.RWMutex
+	handlers []func(e *domain.Event)
+}
+
+func NewInProc() domain.Broker {
+	return &inProc{}
+}
+
+// Publish calls the subscribers one by one before it returns.
+func (b *inProc) Publish(ctx context.Context, e *domain.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, h := range b.handlers {
+		h(e)
+	}
+
+	return nil
+}
+
+func (b *inProc) Subscribe(handler func(e *domain.Event)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *inProc) Close() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+)
+
+// testNATSURL names the NATS server the tests of the package run against:
+//
+//	MONEY_TRANSFER_TEST_NATS_URL=nats://localhost:4222 go test ./transfer/broker
+const testNATSURL = "MONEY_TRANSFER_TEST_NATS_URL"
+
+// testNATS connects to the test server on a subject of its own,
+// so parallel runs don't see each other's events.
+func testNATS(t *testing.T) domain.Broker {
+	t.Helper()
+
+	url := os.Getenv(testNATSURL)
+	if url == "" {
+		t.Skip(testNATSURL + " is not set")
+	}
+
+	b, err := DialNATS(url, fmt.Sprintf("money-transfer.test.%d", time.Now().UnixNano()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	return b
+}
+
+func TestNATSPublishSubscribe(t *testing.T) {
+	b := testNATS(t)
+
+	received := make(chan *domain.Event, 10)
+	if err := b.Subscribe(func(e *domain.Event) { received <- e }); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := &domain.Event{
+		ID:      42,
+		Type:    domain.EventTransferCompleted,
+		Payload: json.RawMessage(`{"Amount":15000}`),
+		Created: time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	if err := b.Publish(context.Background(), sent); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-received:
+		if e.ID != sent.ID || e.Type != sent.Type || string(e.Payload) != string(sent.Payload) || !e.Created.Equal(sent.Created) {
+			t.Errorf("received %+v, want %+v", e, sent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received")
+	}
+}
+
+func TestNATSPublishAfterClose(t *testing.T) {
+	b := testNATS(t)
+	b.Close()
+
+	if err := b.Publish(context.Background(), &domain.Event{ID: 1, Type: domain.EventTransferCompleted}); err == nil {
+		t.Error("publish on a closed connection succeeded")
+	}
+}
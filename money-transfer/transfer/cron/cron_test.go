@@ -0,0 +1,143 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseField(t *testing.T) {
+	minute, dom, dow := fields[0], fields[2], fields[4]
+
+	tests := []struct {
+		spec  string
+		field field
+		want  uint64
+	}{
+		{"5", minute, bits(5)},
+		{"1-4", minute, bits(1, 2, 3, 4)},
+		{"1,15,30", minute, bits(1, 15, 30)},
+		{"*/15", minute, bits(0, 15, 30, 45)},
+		{"1-20/5", minute, bits(1, 6, 11, 16)},
+		{"5/20", minute, bits(5, 25, 45)},
+		{"0-5/2,30", minute, bits(0, 2, 4, 30)},
+		{"*", dom, bits(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
+			17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31)},
+		{"*/10", dom, bits(1, 11, 21, 31)},
+		{"1-5", dow, bits(1, 2, 3, 4, 5)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseField(tt.spec, tt.field)
+		if err != nil {
+			t.Errorf("parseField(%q, %s) error = %v", tt.spec, tt.field.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseField(%q, %s) = %b, want %b", tt.spec, tt.field.name, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	e, err := Parse("0 9 1 * 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sunday is 0 whichever way it is written
+	if e.dow != bits(0) {
+		t.Errorf("day of week = %b, want %b", e.dow, bits(0))
+	}
+	if e.domAny || e.dowAny {
+		t.Errorf("domAny = %v, dowAny = %v, want false, false", e.domAny, e.dowAny)
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+		"*/x * * * *",
+		"1,,2 * * * *",
+	}
+
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) error = nil, want an error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"5/20 * * * *", "2024-01-01 10:26", "2024-01-01 10:45"},
+		{"1-20/5 * * * *", "2024-01-01 10:16", "2024-01-01 11:01"},
+		// strictly after the moment
+		{"0 9 1 * *", "2024-01-01 09:00", "2024-02-01 09:00"},
+		{"0 9 1 * *", "2024-01-01 08:59", "2024-01-01 09:00"},
+		{"0 12 * 1,7 *", "2024-02-01 00:00", "2024-07-01 12:00"},
+		// weekdays, 2024-01-05 is a Friday
+		{"0 9 * * 1-5", "2024-01-05 10:00", "2024-01-08 09:00"},
+		{"0 0 * * 7", "2024-01-01 00:00", "2024-01-07 00:00"},
+		{"0 0 * * 0", "2024-01-01 00:00", "2024-01-07 00:00"},
+		// both days restricted, either matches: the 15th or a Monday
+		{"30 8 15 * 1", "2024-02-06 00:00", "2024-02-12 08:30"},
+		{"30 8 15 * 1", "2024-02-13 00:00", "2024-02-15 08:30"},
+		// the 31st is skipped in shorter months
+		{"0 0 31 * *", "2024-04-01 00:00", "2024-05-31 00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		{"59 23 31 12 *", "2024-12-31 23:58", "2024-12-31 23:59"},
+		// never
+		{"0 0 31 2 *", "2024-01-01 00:00", ""},
+		{"0 0 30 2 *", "2024-01-01 00:00", ""},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.spec, err)
+			continue
+		}
+
+		want := time.Time{}
+		if tt.want != "" {
+			want = at(tt.want)
+		}
+
+		if got := e.Next(at(tt.from)); !got.Equal(want) {
+			t.Errorf("%q after %s = %v, want %v", tt.spec, tt.from, got, want)
+		}
+	}
+}
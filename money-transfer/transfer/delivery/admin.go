@@ -0,0 +1,188 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"money-transfer/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (th *TransferHanlder) AdminUserAccounts(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	accounts, err := th.usecase.GetAccounts(r.Context(), ownerID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AdminUserAccounts")
+		return
+	}
+
+	writeJSON(w, accounts)
+}
+
+func (th *TransferHanlder) AdminAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := th.accountRef(r, chi.URLParam(r, "id"))
+	if err != nil && err != domain.ErrAccountNotFound {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var account *domain.Account
+	if err == nil {
+		account, err = th.usecase.FindAccount(r.Context(), accountID)
+	}
+	if err == domain.ErrAccountNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AdminAccount")
+		return
+	}
+
+	writeJSON(w, account)
+}
+
+func (th *TransferHanlder) AdminAccountHistory(w http.ResponseWriter, r *http.Request) {
+	accountID, err := th.accountRef(r, chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filter, err := th.historyFilter(r, accountID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	history, err := th.usecase.AnyAccountTransactions(r.Context(), filter)
+	switch err {
+	case nil:
+	case domain.ErrAccountNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	case domain.ErrInvalidFilter:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AdminAccountHistory")
+		return
+	}
+
+	writeJSON(w, history)
+}
+
+func (th *TransferHanlder) AdminSettleCashIn(w http.ResponseWriter, r *http.Request) {
+	th.completeCashIn(w, r, th.usecase.SettleCashIn)
+}
+
+func (th *TransferHanlder) AdminCancelCashIn(w http.ResponseWriter, r *http.Request) {
+	th.completeCashIn(w, r, th.usecase.CancelCashIn)
+}
+
+func (th *TransferHanlder) completeCashIn(w http.ResponseWriter, r *http.Request, complete func(context.Context, int64) (*domain.Transaction, error)) {
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t, err := complete(r.Context(), ID)
+	if err == domain.ErrCashInNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("completeCashIn")
+		return
+	}
+
+	writeJSON(w, t)
+}
+
+// AdminReverseTransaction returns what is left of a transfer to its sender.
+func (th *TransferHanlder) AdminReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t, err := th.usecase.ReverseTransaction(r.Context(), ID)
+	switch err {
+	case nil:
+	case domain.ErrTransactionNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	case domain.ErrRefundExceeded, domain.ErrInvalidSum:
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AdminReverseTransaction")
+		return
+	}
+
+	log.Info().Int64("transaction", ID).Int64("reversal", t.ID).Msg("transaction reversed")
+
+	writeJSON(w, t)
+}
+
+// AdminSetRates replaces exchange rates with the ones from JSON body.
+func (th *TransferHanlder) AdminSetRates(w http.ResponseWriter, r *http.Request) {
+	rates := &domain.Rates{}
+
+	if err := json.NewDecoder(r.Body).Decode(rates); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	err := th.usecase.SetRates(rates)
+	if err == domain.ErrInvalidRates {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AdminSetRates")
+		return
+	}
+
+	log.Info().Str("base", rates.Base).Str("spread", rates.Spread).Interface("rates", rates.Rates).Msg("exchange rates updated")
+
+	writeJSON(w, th.usecase.Rates())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	reply, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("error with marshal")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// IssueCard issues a card to accountID. The answer is the only place the PAN
+// and the CVV are shown, so it is not kept for idempotent replays.
+func (th *TransferHanlder) IssueCard(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := th.accountRef(r, r.FormValue("accountID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c, err := th.usecase.IssueCard(r.Context(), u.ID, accountID)
+	if !cardError(w, err, "IssueCard") {
+		return
+	}
+
+	log.Info().Int64("card", c.ID).Int64("account", c.AccountID).Msg("card issued")
+
+	writeJSON(w, c)
+}
+
+func (th *TransferHanlder) Cards(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cards, err := th.usecase.Cards(r.Context(), u.ID)
+	if !cardError(w, err, "Cards") {
+		return
+	}
+
+	writeJSON(w, cards)
+}
+
+func (th *TransferHanlder) BlockCard(w http.ResponseWriter, r *http.Request) {
+	th.setCardStatus(w, r, th.usecase.BlockCard, "BlockCard")
+}
+
+func (th *TransferHanlder) UnblockCard(w http.ResponseWriter, r *http.Request) {
+	th.setCardStatus(w, r, th.usecase.UnblockCard, "UnblockCard")
+}
+
+func (th *TransferHanlder) setCardStatus(w http.ResponseWriter, r *http.Request,
+	set func(ctx context.Context, requester, ID int64) (*domain.Card, error), op string) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c, err := set(r.Context(), u.ID, ID)
+	if !cardError(w, err, op) {
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+// cardError answers errors of card operations, it returns false when there was one.
+func cardError(w http.ResponseWriter, err error, op string) bool {
+	switch err {
+	case nil:
+		return true
+	case domain.ErrNotFound, domain.ErrCardNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	case domain.ErrCardNotActive:
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg(op)
+	}
+
+	return false
+}
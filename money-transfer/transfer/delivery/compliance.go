@@ -0,0 +1,72 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// AdminComplianceCases lists transfers matched by screening, status=open by default.
+func (th *TransferHanlder) AdminComplianceCases(w http.ResponseWriter, r *http.Request) {
+	status := r.FormValue("status")
+	if status == "" {
+		status = domain.CaseOpen
+	}
+
+	cases, err := th.usecase.ComplianceCases(r.Context(), status)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AdminComplianceCases")
+		return
+	}
+
+	writeJSON(w, cases)
+}
+
+// AdminClearCase closes a case as a false positive, takes an optional note.
+func (th *TransferHanlder) AdminClearCase(w http.ResponseWriter, r *http.Request) {
+	th.resolveCase(w, r, domain.CaseCleared)
+}
+
+// AdminConfirmCase closes a case as a true match, takes an optional note.
+func (th *TransferHanlder) AdminConfirmCase(w http.ResponseWriter, r *http.Request) {
+	th.resolveCase(w, r, domain.CaseConfirmed)
+}
+
+func (th *TransferHanlder) resolveCase(w http.ResponseWriter, r *http.Request, status string) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c, err := th.usecase.ResolveCase(r.Context(), u, ID, status, r.FormValue("note"))
+	switch err {
+	case nil:
+	case domain.ErrCaseNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	case domain.ErrCaseResolved:
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("resolveCase")
+		return
+	}
+
+	writeJSON(w, c)
+}
@@ -0,0 +1,175 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateHold reserves money for a payment. Takes the fields of SendMoney,
+// senderID is the account the money is held on.
+func (th *TransferHanlder) CreateHold(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sender, err := th.accountRef(r, r.FormValue("senderID"))
+	receiver, err2 := th.receiverRef(r, r.FormValue("recieverID"))
+	amount, err3 := strconv.ParseInt(r.FormValue("amount"), 10, 64)
+
+	if err != nil || err2 != nil || err3 != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h := &domain.Hold{
+		AccountID:  sender,
+		ReceiverID: receiver,
+		Amount:     amount,
+	}
+
+	err = th.usecase.CreateHold(r.Context(), u, h)
+
+	if writeRiskError(w, err) {
+		return
+	}
+
+	if err == domain.ErrScreeningBlocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	var limitErr *domain.LimitError
+	if errors.As(err, &limitErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(limitErr)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	writeJSON(w, h)
+}
+
+func (th *TransferHanlder) AccountHolds(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := th.accountRef(r, chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	holds, err := th.usecase.Holds(r.Context(), u.ID, accountID)
+	if err == domain.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AccountHolds")
+		return
+	}
+
+	writeJSON(w, holds)
+}
+
+// CaptureHold transfers amount of a hold to the receiver,
+// without amount everything that is left of the hold.
+func (th *TransferHanlder) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var amount int64
+	if v := r.FormValue("amount"); v != "" {
+		if amount, err = strconv.ParseInt(v, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	t, err := th.usecase.CaptureHold(r.Context(), u.ID, ID, amount)
+
+	var limitErr *domain.LimitError
+	if errors.As(err, &limitErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(limitErr)
+		return
+	}
+	if !holdError(w, err, "CaptureHold") {
+		return
+	}
+
+	writeJSON(w, t)
+}
+
+func (th *TransferHanlder) VoidHold(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !holdError(w, th.usecase.VoidHold(r.Context(), u.ID, ID), "VoidHold") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// holdError answers errors of hold operations, it returns false when there was one.
+func holdError(w http.ResponseWriter, err error, op string) bool {
+	switch err {
+	case nil:
+		return true
+	case domain.ErrHoldNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	case domain.ErrHoldNotActive, domain.ErrHoldExceeded, domain.ErrInvalidSum:
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+	case domain.ErrTransSum, domain.ErrNoRate:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg(op)
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package delivery
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
 	"net/http"
 	"strconv"
@@ -12,6 +13,8 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"money-transfer/domain"
+	"shared/iin"
+	"shared/rbac"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -42,9 +45,53 @@ func NewTransactionHandler(c *domain.Config, router *chi.Mux, tu domain.Transfer
 	router.With(m.CheckAuthMiddleware).Get("/main", handler.IndexPage(tmpl))
 	router.With(m.CheckAuthMiddleware).Get("/accounts", handler.AccountsInfo)
 	router.With(m.CheckAuthMiddleware).Post("/accounts", handler.CreateAccount)
+	router.With(m.CheckAuthMiddleware).Get("/accounts/history", handler.TransactionsHistory)
 	router.With(m.CheckAuthMiddleware).Post("/accounts/history", handler.TransactionsHistory)
-	router.With(m.CheckAuthMiddleware).Post("/transaction", handler.SendMoney)
-	router.With(m.CheckAuthMiddleware).Post("/increment", handler.TopUpAccount)
+	router.With(m.CheckAuthMiddleware).Get("/accounts/{id}/statement", handler.AccountStatement)
+	router.With(m.CheckAuthMiddleware).Post("/transaction", handler.Idempotent(handler.SendMoney))
+	router.With(m.CheckAuthMiddleware).Get("/transaction/quote", handler.QuoteTransfer)
+	router.With(m.CheckAuthMiddleware).Post("/transactions/{id}/refund", handler.Idempotent(handler.RefundTransfer))
+	router.With(m.CheckAuthMiddleware).Post("/increment", handler.Idempotent(handler.TopUpAccount))
+	router.With(m.CheckAuthMiddleware).Get("/accounts/{id}/holds", handler.AccountHolds)
+	router.With(m.CheckAuthMiddleware).Post("/holds", handler.Idempotent(handler.CreateHold))
+	router.With(m.CheckAuthMiddleware).Post("/holds/{id}/capture", handler.Idempotent(handler.CaptureHold))
+	router.With(m.CheckAuthMiddleware).Post("/holds/{id}/void", handler.VoidHold)
+	router.With(m.CheckAuthMiddleware).Get("/cards", handler.Cards)
+	router.With(m.CheckAuthMiddleware).Post("/cards", handler.IssueCard)
+	router.With(m.CheckAuthMiddleware).Post("/cards/{id}/block", handler.BlockCard)
+	router.With(m.CheckAuthMiddleware).Post("/cards/{id}/unblock", handler.UnblockCard)
+	router.With(m.CheckAuthMiddleware).Get("/schedules", handler.Schedules)
+	router.With(m.CheckAuthMiddleware).Post("/schedules", handler.Idempotent(handler.CreateSchedule))
+	router.With(m.CheckAuthMiddleware).Get("/schedules/{id}/runs", handler.ScheduleRuns)
+	router.With(m.CheckAuthMiddleware).Delete("/schedules/{id}", handler.CancelSchedule)
+	router.With(m.CheckAuthMiddleware).Get("/webhooks", handler.Webhooks)
+	router.With(m.CheckAuthMiddleware).Post("/webhooks", handler.Idempotent(handler.CreateWebhook))
+	router.With(m.CheckAuthMiddleware).Delete("/webhooks/{id}", handler.DeleteWebhook)
+	router.With(m.CheckAuthMiddleware).Get("/webhooks/{id}/deliveries", handler.WebhookDeliveries)
+	router.With(m.CheckAuthMiddleware).Post("/webhook-deliveries/{id}/redeliver", handler.RedeliverDelivery)
+	router.With(m.CheckAuthMiddleware).Get("/rates", handler.Rates)
+	router.With(m.CheckAuthMiddleware).Get("/fees", handler.Fees)
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(m.CheckAuthMiddleware)
+
+		r.With(m.RequirePermission(rbac.PermAccountsRead)).Get("/users/{id}/accounts", handler.AdminUserAccounts)
+		r.With(m.RequirePermission(rbac.PermAccountsRead)).Get("/accounts/{id}", handler.AdminAccount)
+		r.With(m.RequirePermission(rbac.PermTransactionsRead)).Get("/accounts/{id}/history", handler.AdminAccountHistory)
+		r.With(m.RequirePermission(rbac.PermCashInsSettle)).Post("/cash-ins/{id}/settle", handler.AdminSettleCashIn)
+		r.With(m.RequirePermission(rbac.PermCashInsSettle)).Post("/cash-ins/{id}/cancel", handler.AdminCancelCashIn)
+		r.With(m.RequirePermission(rbac.PermTransactionsReverse)).Post("/transactions/{id}/reverse", handler.AdminReverseTransaction)
+		r.With(m.RequirePermission(rbac.PermRatesWrite)).Put("/rates", handler.AdminSetRates)
+		r.With(m.RequirePermission(rbac.PermWebhooksWrite)).Post("/webhooks", handler.AdminCreateWebhook)
+		r.With(m.RequirePermission(rbac.PermWebhooksWrite)).Get("/webhook-deliveries", handler.AdminDeliveries)
+		r.With(m.RequirePermission(rbac.PermWebhooksWrite)).Post("/webhook-deliveries/{id}/redeliver", handler.AdminRedeliverDelivery)
+		r.With(m.RequirePermission(rbac.PermRiskReview)).Get("/risk/decisions", handler.AdminRiskQueue)
+		r.With(m.RequirePermission(rbac.PermRiskReview)).Post("/risk/decisions/{id}/approve", handler.AdminApproveRiskDecision)
+		r.With(m.RequirePermission(rbac.PermRiskReview)).Post("/risk/decisions/{id}/reject", handler.AdminRejectRiskDecision)
+		r.With(m.RequirePermission(rbac.PermComplianceReview)).Get("/compliance/cases", handler.AdminComplianceCases)
+		r.With(m.RequirePermission(rbac.PermComplianceReview)).Post("/compliance/cases/{id}/clear", handler.AdminClearCase)
+		r.With(m.RequirePermission(rbac.PermComplianceReview)).Post("/compliance/cases/{id}/confirm", handler.AdminConfirmCase)
+	})
 	return nil
 }
 
@@ -117,10 +164,16 @@ func (th *TransferHanlder) CreateAccount(w http.ResponseWriter, r *http.Request)
 		OwnerID:    u.ID,
 		IIN:        IIN,
 		Amount:     0,
+		Currency:   r.FormValue("currency"),
 		Registered: time.Now(),
 	}
 
 	err := th.usecase.CreateAccount(r.Context(), &account)
+	if err == domain.ErrInvalidCurrency || err == iin.ErrInvalid {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -141,8 +194,8 @@ func (th *TransferHanlder) SendMoney(w http.ResponseWriter, r *http.Request) {
 	to := r.FormValue("recieverID")
 	value := r.FormValue("amount")
 
-	sender, err := strconv.ParseInt(from, 10, 64)
-	receiver, err2 := strconv.ParseInt(to, 10, 64)
+	sender, err := th.accountRef(r, from)
+	receiver, err2 := th.receiverRef(r, to)
 	amount, err3 := strconv.ParseInt(value, 10, 64)
 
 	if err != nil || err2 != nil || err3 != nil {
@@ -150,40 +203,160 @@ func (th *TransferHanlder) SendMoney(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = th.usecase.CreateTransaction(r.Context(), u.ID, sender, receiver, amount)
+	// fee of the quote the user saw, the transfer is refused when it has changed since,
+	// so nobody pays a fee they were not shown
+	if r.FormValue("fee") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(domain.ErrFeeRequired.Error()))
+		return
+	}
+
+	fee, err := strconv.ParseInt(r.FormValue("fee"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t, err := th.usecase.CreateQuotedTransaction(r.Context(), u, sender, receiver, amount, fee)
+
+	if writeRiskError(w, err) {
+		return
+	}
+
+	if err == domain.ErrFeeChanged {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err == domain.ErrScreeningBlocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	var limitErr *domain.LimitError
+	if errors.As(err, &limitErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(limitErr)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Success"))
+	writeJSON(w, t)
+}
+
+// QuoteTransfer shows the fee and the converted amount
+// of a transfer before the user sends it. Takes the same fields as SendMoney.
+func (th *TransferHanlder) QuoteTransfer(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sender, err := th.accountRef(r, r.FormValue("senderID"))
+	receiver, err2 := th.receiverRef(r, r.FormValue("recieverID"))
+	amount, err3 := strconv.ParseInt(r.FormValue("amount"), 10, 64)
+
+	if err != nil || err2 != nil || err3 != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	q, err := th.usecase.QuoteTransaction(r.Context(), u, sender, receiver, amount)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	writeJSON(w, q)
+}
+
+// RefundTransfer sends a part of a received transfer back to its sender.
+// Amount is in the currency of the receiving account.
+func (th *TransferHanlder) RefundTransfer(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	amount, err2 := strconv.ParseInt(r.FormValue("amount"), 10, 64)
+	if err != nil || err2 != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t, err := th.usecase.RefundTransaction(r.Context(), u.ID, ID, amount)
+	switch err {
+	case nil:
+	case domain.ErrTransactionNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	case domain.ErrRefundExceeded, domain.ErrInvalidSum:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("RefundTransfer")
+		return
+	}
+
+	writeJSON(w, t)
 }
 
+// Fees returns the current fee schedule.
+func (th *TransferHanlder) Fees(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, th.usecase.FeeSchedule())
+}
+
+// TopUpAccount cashes money in to an account of the user
+// from a card or a terminal.
 func (th *TransferHanlder) TopUpAccount(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	acc := r.FormValue("accountID")
 	value := r.FormValue("amount")
 
-	accountID, err := strconv.ParseInt(acc, 10, 64)
+	accountID, err := th.accountRef(r, acc)
 	amount, err2 := strconv.ParseInt(value, 10, 64)
 	if err != nil || err2 != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	err = th.usecase.ChangeAccountSum(r.Context(), accountID, amount)
+	t, err := th.usecase.CashIn(r.Context(), u.ID, &domain.CashIn{
+		AccountID:  accountID,
+		Amount:     amount,
+		SourceType: r.FormValue("source"),
+		SourceName: r.FormValue("sourceName"),
+	})
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Success"))
+	writeJSON(w, t)
 }
 
+// TransactionsHistory returns a page of account history.
+// Query parameters: accountID, cursor, limit, from, to, direction (in/out),
+// counterparty, min and max amount.
 func (th *TransferHanlder) TransactionsHistory(w http.ResponseWriter, r *http.Request) {
 	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
 	if !ok {
@@ -192,13 +365,20 @@ func (th *TransferHanlder) TransactionsHistory(w http.ResponseWriter, r *http.Re
 	}
 
 	acc := r.FormValue("accountID")
-	accountID, err := strconv.ParseInt(acc, 10, 64)
+	accountID, err := th.accountRef(r, acc)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	history, err := th.usecase.AccountTransactions(r.Context(), u.ID, accountID)
+	filter, err := th.historyFilter(r, accountID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	history, err := th.usecase.AccountTransactions(r.Context(), u.ID, filter)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -215,3 +395,83 @@ func (th *TransferHanlder) TransactionsHistory(w http.ResponseWriter, r *http.Re
 
 	w.Write(reply)
 }
+
+// Rates returns the current exchange rates.
+func (th *TransferHanlder) Rates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, th.usecase.Rates())
+}
+
+// accountRef takes an account by its IBAN.
+func (th *TransferHanlder) accountRef(r *http.Request, ref string) (int64, error) {
+	return th.usecase.ResolveAccount(r.Context(), ref)
+}
+
+// receiverRef takes what accountRef takes or the PAN of an active card.
+func (th *TransferHanlder) receiverRef(r *http.Request, ref string) (int64, error) {
+	return th.usecase.ResolveReceiver(r.Context(), ref)
+}
+
+// historyFilter reads history filters from the request.
+// Dates are either RFC 3339 timestamps or days, "to" day is included.
+func (th *TransferHanlder) historyFilter(r *http.Request, accountID int64) (*domain.HistoryFilter, error) {
+	f := &domain.HistoryFilter{
+		AccountID: accountID,
+		Cursor:    r.FormValue("cursor"),
+		Direction: r.FormValue("direction"),
+	}
+
+	var err error
+
+	if v := r.FormValue("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, domain.ErrInvalidFilter
+		}
+	}
+
+	if f.From, err = parseDate(r.FormValue("from"), false); err != nil {
+		return nil, err
+	}
+
+	if f.To, err = parseDate(r.FormValue("to"), true); err != nil {
+		return nil, err
+	}
+
+	if v := r.FormValue("counterparty"); v != "" {
+		if f.Counterparty, err = th.accountRef(r, v); err != nil {
+			return nil, domain.ErrInvalidFilter
+		}
+	}
+
+	for name, dst := range map[string]*int64{
+		"min": &f.MinAmount,
+		"max": &f.MaxAmount,
+	} {
+		if v := r.FormValue(name); v != "" {
+			if *dst, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, domain.ErrInvalidFilter
+			}
+		}
+	}
+
+	return f, nil
+}
+
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, domain.ErrInvalidFilter
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,136 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"money-transfer/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+const idempotencyHeader = "Idempotency-Key"
+
+// recorder keeps a copy of the response written by a handler.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = r.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotent answers a retried request with the response of the first one,
+// so money doesn't move twice. Requests without Idempotency-Key are passed as is.
+func (th *TransferHanlder) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(idempotencyHeader)
+		if name == "" {
+			next(w, r)
+			return
+		}
+
+		if len(name) > 255 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("idempotency key is too long"))
+			return
+		}
+
+		u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		key := &domain.IdempotencyKey{
+			Key:         name,
+			OwnerID:     u.ID,
+			Fingerprint: fingerprint(r),
+		}
+
+		stored, err := th.usecase.StartIdempotent(r.Context(), key)
+		switch err {
+		case nil:
+		case domain.ErrIdempotencyConflict, domain.ErrIdempotencyInProgress:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(err.Error()))
+			return
+		default:
+			log.Warn().Err(err).Msg("StartIdempotent")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if stored != nil {
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.Response)
+			return
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				// a request that crashed is not remembered, the client may retry it
+				key.StatusCode = http.StatusInternalServerError
+				if err := th.usecase.FinishIdempotent(context.Background(), key); err != nil {
+					log.Error().Err(err).Str("key", key.Key).Int64("owner", key.OwnerID).Msg("FinishIdempotent")
+				}
+				panic(p)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w}
+		next(rec, r)
+
+		key.StatusCode = rec.status
+		key.Header = rec.header
+		if key.StatusCode == 0 {
+			key.StatusCode = http.StatusOK
+		}
+		key.Response = rec.body.Bytes()
+
+		// the client may be gone already, but the outcome must be stored anyway
+		if err := th.usecase.FinishIdempotent(context.Background(), key); err != nil {
+			log.Error().Err(err).Str("key", key.Key).Int64("owner", key.OwnerID).Msg("FinishIdempotent")
+		}
+	}
+}
+
+// fingerprint identifies the payload of a request.
+func fingerprint(r *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Form.Encode()))
+	return hex.EncodeToString(h.Sum(nil))
+}
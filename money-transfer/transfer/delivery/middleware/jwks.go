@@ -0,0 +1,150 @@
+package delivery
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// minRefreshInterval protects auth service from being flooded
+// by tokens with unknown key IDs.
+const minRefreshInterval = 10 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet caches public keys published by auth service.
+type keySet struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+	// fetched is when the keys were fetched, attempted when they were
+	// last tried to be, failed or not.
+	fetched   time.Time
+	attempted time.Time
+	// refreshing is closed when the fetch in progress is over.
+	refreshing chan struct{}
+}
+
+func newKeySet(url string, ttl time.Duration) *keySet {
+	return &keySet{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// key returns the verification key with given ID. Keys are fetched again
+// when the cache is stale or the key is unknown, at most once in
+// minRefreshInterval, stale keys are used while auth service is unavailable.
+// One request fetches at a time, without the lock, the ones that need a key
+// it may bring wait for it.
+func (ks *keySet) key(kid string) (*rsa.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	for {
+		key, ok := ks.keys[kid]
+
+		stale := time.Since(ks.fetched) > ks.ttl
+		canRefresh := time.Since(ks.attempted) > minRefreshInterval
+
+		switch {
+		case !stale && ok:
+		case ks.refreshing != nil && !ok:
+			refreshing := ks.refreshing
+			ks.mu.Unlock()
+			<-refreshing
+			ks.mu.Lock()
+			continue
+		case ks.refreshing == nil && canRefresh:
+			ks.refresh()
+			continue
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		return key, nil
+	}
+}
+
+// refresh fetches the keys with ks.mu unlocked, it is called and
+// returns with ks.mu locked.
+func (ks *keySet) refresh() {
+	refreshing := make(chan struct{})
+	ks.refreshing, ks.attempted = refreshing, time.Now()
+	ks.mu.Unlock()
+
+	keys, err := ks.fetch()
+
+	ks.mu.Lock()
+	if err != nil {
+		log.Warn().Err(err).Str("url", ks.url).Msg("cannot fetch JWKS")
+	} else {
+		ks.keys, ks.fetched = keys, time.Now()
+	}
+	ks.refreshing = nil
+	close(refreshing)
+}
+
+func (ks *keySet) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
@@ -8,14 +8,14 @@ import (
 	"time"
 
 	"money-transfer/domain"
+	"shared/rbac"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/rs/zerolog/log"
 )
 
 type MiddleWare struct {
-	accessSecret    string
-	refreshSecret   string
+	keys            *keySet
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 }
@@ -27,8 +27,7 @@ const CtxKeyUser ctxKey = iota
 func New(c *domain.Config) *MiddleWare {
 
 	return &MiddleWare{
-		accessSecret:    c.AccessTokenSecret,
-		refreshSecret:   c.RefreshTokenSecret,
+		keys:            newKeySet(c.JWKSURL, c.JWKSCacheTTL.Duration),
 		accessTokenTTL:  c.AccessTokenTTL.Duration,
 		refreshTokenTTL: c.RefreshTokenTTL.Duration,
 	}
@@ -72,6 +71,31 @@ func (m *MiddleWare) CheckAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// RequirePermission lets through only users whose role grants the permission.
+// It must be used after CheckAuthMiddleware.
+func (m *MiddleWare) RequirePermission(p rbac.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			u, ok := r.Context().Value(CtxKeyUser).(*domain.User)
+			if !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if !u.Can(p) {
+				log.Warn().Int64("user", u.ID).Str("permission", string(p)).Msg("permission denied")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("permission denied"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
 func (m *MiddleWare) ExtractToken(AuthorizationHeader string) (string, error) {
 	// header := r.Header.Get("Authorization")
 	if AuthorizationHeader == "" {
@@ -89,13 +113,12 @@ func (m *MiddleWare) ExtractToken(AuthorizationHeader string) (string, error) {
 func (m *MiddleWare) ParseToken(token string, isAccess bool) (*domain.User, error) {
 
 	JWTToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("failed to extract token metadata, unexpected signing method: %v", token.Header["alg"])
 		}
-		if isAccess {
-			return []byte(m.accessSecret), nil
-		}
-		return []byte(m.refreshSecret), nil
+
+		kid, _ := token.Header["kid"].(string)
+		return m.keys.key(kid)
 	})
 
 	if err != nil {
@@ -106,6 +129,11 @@ func (m *MiddleWare) ParseToken(token string, isAccess bool) (*domain.User, erro
 
 	if ok && JWTToken.Valid {
 
+		typ, _ := claims["typ"].(string)
+		if (isAccess && typ != "access") || (!isAccess && typ != "refresh") {
+			return nil, domain.ErrInvalidToken
+		}
+
 		var userID float64
 
 		userID, ok = claims["id"].(float64)
@@ -119,7 +147,7 @@ func (m *MiddleWare) ParseToken(token string, isAccess bool) (*domain.User, erro
 		}
 
 		role, ok := claims["role"].(string)
-		if !ok || (role != "user" && role != "admin") {
+		if !ok || !rbac.ValidRole(role) {
 			return nil, domain.ErrInvalidToken
 		}
 
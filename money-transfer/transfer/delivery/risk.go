@@ -0,0 +1,109 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// AdminRiskQueue lists transfers held for review or blocked, status=open by default.
+func (th *TransferHanlder) AdminRiskQueue(w http.ResponseWriter, r *http.Request) {
+	status := r.FormValue("status")
+	if status == "" {
+		status = domain.DecisionOpen
+	}
+
+	decisions, err := th.usecase.RiskQueue(r.Context(), status)
+	if !riskError(w, err, "AdminRiskQueue") {
+		return
+	}
+
+	writeJSON(w, decisions)
+}
+
+// AdminApproveRiskDecision makes the transfer held by the decision.
+func (th *TransferHanlder) AdminApproveRiskDecision(w http.ResponseWriter, r *http.Request) {
+	th.resolveRiskDecision(w, r, th.usecase.ApproveRiskDecision, "AdminApproveRiskDecision")
+}
+
+func (th *TransferHanlder) AdminRejectRiskDecision(w http.ResponseWriter, r *http.Request) {
+	th.resolveRiskDecision(w, r, th.usecase.RejectRiskDecision, "AdminRejectRiskDecision")
+}
+
+func (th *TransferHanlder) resolveRiskDecision(w http.ResponseWriter, r *http.Request,
+	resolve func(context.Context, *domain.User, int64) (*domain.RiskDecision, error), op string) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d, err := resolve(r.Context(), u, ID)
+	if !riskError(w, err, op) {
+		return
+	}
+
+	writeJSON(w, d)
+}
+
+// riskError answers err of the review queue, a transfer refused
+// on approval leaves the decision open and is a conflict.
+func riskError(w http.ResponseWriter, err error, op string) bool {
+	var limitErr *domain.LimitError
+	if errors.As(err, &limitErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(limitErr)
+		return false
+	}
+
+	switch err {
+	case nil:
+		return true
+	case domain.ErrDecisionNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	case domain.ErrDecisionResolved, domain.ErrInvalidSum, domain.ErrTransSum, domain.ErrTransSender,
+		domain.ErrTransReceiver, domain.ErrInvalidCurrency, domain.ErrNoRate:
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg(op)
+	}
+
+	return false
+}
+
+// writeRiskError answers a transfer refused by the risk check: 202 when it waits
+// for review, 403 when it is blocked. It reports whether err was a RiskError.
+func writeRiskError(w http.ResponseWriter, err error) bool {
+	var riskErr *domain.RiskError
+	if !errors.As(err, &riskErr) {
+		return false
+	}
+
+	code := http.StatusForbidden
+	if riskErr.Outcome == domain.RiskReview {
+		code = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(riskErr)
+	return true
+}
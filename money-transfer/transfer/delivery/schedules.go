@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateSchedule stores a standing order.
+// Takes the fields of SendMoney and start (default now), cron for recurring
+// transfers, until (the day is included) and count of occurrences.
+func (th *TransferHanlder) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sender, err := th.accountRef(r, r.FormValue("senderID"))
+	receiver, err2 := th.receiverRef(r, r.FormValue("recieverID"))
+	amount, err3 := strconv.ParseInt(r.FormValue("amount"), 10, 64)
+
+	if err != nil || err2 != nil || err3 != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s := &domain.Schedule{
+		SenderID:   sender,
+		ReceiverID: receiver,
+		Amount:     amount,
+		Cron:       r.FormValue("cron"),
+	}
+
+	if s.Start, err = parseDate(r.FormValue("start"), false); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(domain.ErrInvalidSchedule.Error()))
+		return
+	}
+
+	if v := r.FormValue("until"); v != "" {
+		until, err := parseDate(v, true)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(domain.ErrInvalidSchedule.Error()))
+			return
+		}
+		s.Until = &until
+	}
+
+	if v := r.FormValue("count"); v != "" {
+		if s.Count, err = strconv.Atoi(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(domain.ErrInvalidSchedule.Error()))
+			return
+		}
+	}
+
+	if err := th.usecase.CreateSchedule(r.Context(), u, s); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	writeJSON(w, s)
+}
+
+func (th *TransferHanlder) Schedules(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	schedules, err := th.usecase.Schedules(r.Context(), u.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("Schedules")
+		return
+	}
+
+	writeJSON(w, schedules)
+}
+
+func (th *TransferHanlder) ScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	runs, err := th.usecase.ScheduleRuns(r.Context(), u.ID, ID)
+	if err == domain.ErrScheduleNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("ScheduleRuns")
+		return
+	}
+
+	writeJSON(w, runs)
+}
+
+func (th *TransferHanlder) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = th.usecase.CancelSchedule(r.Context(), u.ID, ID)
+	if err == domain.ErrScheduleNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("CancelSchedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+	"money-transfer/transfer/statement"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// AccountStatement exports account statement for a period.
+// Query parameters: from, to (current month by default)
+// and format: csv, pdf or ofx, taken from Accept header when omitted.
+func (th *TransferHanlder) AccountStatement(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := th.accountRef(r, chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+
+	from, err := parseDate(r.FormValue("from"), false)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if from.IsZero() {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+
+	to, err := parseDate(r.FormValue("to"), true)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if to.IsZero() {
+		to = now
+	}
+
+	format := statementFormat(r)
+
+	contentType, ok := statement.ContentType(format)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(domain.ErrInvalidFormat.Error()))
+		return
+	}
+
+	st, err := th.usecase.Statement(r.Context(), u.ID, accountID, from, to)
+	switch err {
+	case nil:
+	case domain.ErrNotFound, domain.ErrInvalidFilter:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("AccountStatement")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+statement.FileName(st, format)+`"`)
+
+	if err := statement.Render(w, st, format); err != nil {
+		log.Warn().Err(err).Msg("cannot render statement")
+	}
+}
+
+func statementFormat(r *http.Request) string {
+	if format := r.FormValue("format"); format != "" {
+		return strings.ToLower(format)
+	}
+
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case strings.Contains(accept, "application/pdf"):
+		return statement.FormatPDF
+	case strings.Contains(accept, "ofx"):
+		return statement.FormatOFX
+	default:
+		return statement.FormatCSV
+	}
+}
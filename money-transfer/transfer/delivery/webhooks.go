@@ -0,0 +1,205 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"money-transfer/domain"
+	middleware "money-transfer/transfer/delivery/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateWebhook registers url for events of accountID.
+// Takes events as a comma separated list, without it every event is sent.
+func (th *TransferHanlder) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	hook, err := th.webhookForm(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = th.usecase.CreateWebhook(r.Context(), u.ID, hook)
+	if !webhookError(w, err, "CreateWebhook") {
+		return
+	}
+
+	writeJSON(w, hook)
+}
+
+// AdminCreateWebhook registers a webhook on any account,
+// it belongs to the owner of the account.
+func (th *TransferHanlder) AdminCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := th.webhookForm(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = th.usecase.AnyCreateWebhook(r.Context(), hook)
+	if !webhookError(w, err, "AdminCreateWebhook") {
+		return
+	}
+
+	log.Info().Int64("webhook", hook.ID).Int64("account", hook.AccountID).Msg("webhook registered by admin")
+
+	writeJSON(w, hook)
+}
+
+func (th *TransferHanlder) webhookForm(r *http.Request) (*domain.Webhook, error) {
+	accountID, err := th.accountRef(r, r.FormValue("accountID"))
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &domain.Webhook{
+		AccountID: accountID,
+		URL:       strings.TrimSpace(r.FormValue("url")),
+		Events:    []string{},
+	}
+
+	for _, e := range strings.Split(r.FormValue("events"), ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			hook.Events = append(hook.Events, e)
+		}
+	}
+
+	return hook, nil
+}
+
+func (th *TransferHanlder) Webhooks(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	webhooks, err := th.usecase.Webhooks(r.Context(), u.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("Webhooks")
+		return
+	}
+
+	writeJSON(w, webhooks)
+}
+
+func (th *TransferHanlder) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !webhookError(w, th.usecase.DeleteWebhook(r.Context(), u.ID, ID), "DeleteWebhook") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebhookDeliveries lists deliveries of a webhook, status=dead is its dead-letter list.
+func (th *TransferHanlder) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := th.usecase.WebhookDeliveries(r.Context(), u.ID, ID, r.FormValue("status"))
+	if !webhookError(w, err, "WebhookDeliveries") {
+		return
+	}
+
+	writeJSON(w, deliveries)
+}
+
+// AdminDeliveries lists deliveries of every webhook, status=dead by default.
+func (th *TransferHanlder) AdminDeliveries(w http.ResponseWriter, r *http.Request) {
+	status := r.FormValue("status")
+	if status == "" {
+		status = domain.DeliveryDead
+	}
+
+	deliveries, err := th.usecase.AnyDeliveries(r.Context(), status)
+	if !webhookError(w, err, "AdminDeliveries") {
+		return
+	}
+
+	writeJSON(w, deliveries)
+}
+
+func (th *TransferHanlder) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	u, ok := r.Context().Value(middleware.CtxKeyUser).(*domain.User)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d, err := th.usecase.RedeliverDelivery(r.Context(), u.ID, ID)
+	if !webhookError(w, err, "RedeliverDelivery") {
+		return
+	}
+
+	writeJSON(w, d)
+}
+
+func (th *TransferHanlder) AdminRedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d, err := th.usecase.AnyRedeliverDelivery(r.Context(), ID)
+	if !webhookError(w, err, "AdminRedeliverDelivery") {
+		return
+	}
+
+	writeJSON(w, d)
+}
+
+// webhookError answers errors of webhook operations, it returns false when there was one.
+func webhookError(w http.ResponseWriter, err error, op string) bool {
+	switch err {
+	case nil:
+		return true
+	case domain.ErrNotFound, domain.ErrAccountNotFound, domain.ErrWebhookNotFound, domain.ErrDeliveryNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	case domain.ErrInvalidWebhook, domain.ErrWebhookAddress, domain.ErrInvalidFilter:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg(op)
+	}
+
+	return false
+}
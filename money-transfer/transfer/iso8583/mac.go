@@ -0,0 +1,53 @@
+package iso8583
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// macLength is the number of bytes of the HMAC field 64 carries, in hex.
+const macLength = 8
+
+// MAC authenticates the message under the key of its terminal: the first
+// 8 bytes of an HMAC-SHA256 of the message packed without field 64.
+func (m *Message) MAC(key []byte) (string, error) {
+	unsigned := &Message{MTI: m.MTI, Fields: make(map[int]string, len(m.Fields))}
+	for f, value := range m.Fields {
+		if f != FieldMAC {
+			unsigned.Fields[f] = value
+		}
+	}
+
+	b, err := unsigned.Pack()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+
+	return strings.ToUpper(hex.EncodeToString(mac.Sum(nil)[:macLength])), nil
+}
+
+// Sign sets field 64 to the MAC of the message.
+func (m *Message) Sign(key []byte) error {
+	mac, err := m.MAC(key)
+	if err != nil {
+		return err
+	}
+
+	m.Set(FieldMAC, mac)
+	return nil
+}
+
+// Verify reports whether field 64 holds the MAC of the message.
+func (m *Message) Verify(key []byte) bool {
+	if !m.Has(FieldMAC) {
+		return false
+	}
+
+	mac, err := m.MAC(key)
+	return err == nil && hmac.Equal([]byte(mac), []byte(strings.ToUpper(m.Get(FieldMAC))))
+}
@@ -0,0 +1,261 @@
+// Package iso8583 speaks the subset of ISO 8583:1987 the card simulator needs.
+// Messages are ASCII: the 4 digit MTI, the bitmaps in hex and the fields in
+// order. On the wire every message follows its length in 2 bytes, big-endian.
+package iso8583
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fields the simulator knows.
+const (
+	FieldPAN             = 2
+	FieldProcessingCode  = 3
+	FieldAmount          = 4
+	FieldTransmittedAt   = 7
+	FieldSTAN            = 11
+	FieldLocalTime       = 12
+	FieldLocalDate       = 13
+	FieldExpiry          = 14
+	FieldRRN             = 37
+	FieldAuthCode        = 38
+	FieldResponseCode    = 39
+	FieldTerminalID      = 41
+	FieldMerchantID      = 42
+	FieldAdditionalData  = 48
+	FieldCurrency        = 49
+	FieldMAC             = 64
+	FieldNetworkCode     = 70
+	FieldOriginalElement = 90
+)
+
+type fieldSpec struct {
+	// prefix is the number of length digits of a variable field, 0 for a fixed one
+	prefix  int
+	length  int
+	numeric bool
+}
+
+var specs = map[int]fieldSpec{
+	FieldPAN:             {prefix: 2, length: 19, numeric: true},
+	FieldProcessingCode:  {length: 6, numeric: true},
+	FieldAmount:          {length: 12, numeric: true},
+	FieldTransmittedAt:   {length: 10, numeric: true},
+	FieldSTAN:            {length: 6, numeric: true},
+	FieldLocalTime:       {length: 6, numeric: true},
+	FieldLocalDate:       {length: 4, numeric: true},
+	FieldExpiry:          {length: 4, numeric: true},
+	FieldRRN:             {length: 12},
+	FieldAuthCode:        {length: 6},
+	FieldResponseCode:    {length: 2},
+	FieldTerminalID:      {length: 8},
+	FieldMerchantID:      {length: 15},
+	FieldAdditionalData:  {prefix: 3, length: 999},
+	FieldCurrency:        {length: 3, numeric: true},
+	FieldMAC:             {length: 16},
+	FieldNetworkCode:     {length: 3, numeric: true},
+	FieldOriginalElement: {length: 42, numeric: true},
+}
+
+// maxMessage is the longest message a 2 byte length frames.
+const maxMessage = 1<<16 - 1
+
+var ErrMalformed = errors.New("iso8583: malformed message")
+
+// Message is an ISO 8583 message. Fixed fields are padded when packed,
+// numeric ones with zeros on the left and the others with spaces on the right.
+type Message struct {
+	MTI    string
+	Fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, Fields: map[int]string{}}
+}
+
+func (m *Message) Set(field int, value string) {
+	m.Fields[field] = value
+}
+
+// Get returns the field without the padding of fixed text fields.
+func (m *Message) Get(field int) string {
+	return strings.TrimRight(m.Fields[field], " ")
+}
+
+func (m *Message) Has(field int) bool {
+	_, ok := m.Fields[field]
+	return ok
+}
+
+// ResponseMTI is the MTI of the answer to m, e.g. 0110 to 0100 and 0410 to 0401.
+func (m *Message) ResponseMTI() string {
+	if len(m.MTI) != 4 {
+		return m.MTI
+	}
+	return m.MTI[:2] + string(m.MTI[2]+1) + "0"
+}
+
+func (m *Message) Pack() ([]byte, error) {
+	if len(m.MTI) != 4 || !numeric(m.MTI) {
+		return nil, fmt.Errorf("iso8583: invalid MTI %q", m.MTI)
+	}
+
+	fields := make([]int, 0, len(m.Fields))
+	for f := range m.Fields {
+		if _, ok := specs[f]; !ok {
+			return nil, fmt.Errorf("iso8583: unknown field %d", f)
+		}
+		fields = append(fields, f)
+	}
+	sort.Ints(fields)
+
+	bitmap := make([]byte, 8)
+	if len(fields) > 0 && fields[len(fields)-1] > 64 {
+		bitmap = make([]byte, 16)
+		bitmap[0] |= 0x80
+	}
+
+	var body strings.Builder
+
+	for _, f := range fields {
+		bitmap[(f-1)/8] |= 0x80 >> ((f - 1) % 8)
+
+		value, err := packField(f, m.Fields[f])
+		if err != nil {
+			return nil, err
+		}
+		body.WriteString(value)
+	}
+
+	return []byte(m.MTI + strings.ToUpper(hex.EncodeToString(bitmap)) + body.String()), nil
+}
+
+func packField(f int, value string) (string, error) {
+	spec := specs[f]
+
+	if len(value) > spec.length || spec.numeric && !numeric(value) {
+		return "", fmt.Errorf("iso8583: invalid field %d", f)
+	}
+
+	switch {
+	case spec.prefix > 0:
+		return fmt.Sprintf("%0*d%s", spec.prefix, len(value), value), nil
+	case spec.numeric:
+		return strings.Repeat("0", spec.length-len(value)) + value, nil
+	default:
+		return value + strings.Repeat(" ", spec.length-len(value)), nil
+	}
+}
+
+func Unpack(b []byte) (*Message, error) {
+	s := string(b)
+	if len(s) < 4+16 || !numeric(s[:4]) {
+		return nil, ErrMalformed
+	}
+
+	m := NewMessage(s[:4])
+
+	bitmap, err := hex.DecodeString(s[4:20])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	s = s[20:]
+
+	if bitmap[0]&0x80 != 0 {
+		if len(s) < 16 {
+			return nil, ErrMalformed
+		}
+
+		secondary, err := hex.DecodeString(s[:16])
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		bitmap = append(bitmap, secondary...)
+		s = s[16:]
+	}
+
+	// field 1 is the secondary bitmap
+	for f := 2; f <= len(bitmap)*8; f++ {
+		if bitmap[(f-1)/8]&(0x80>>((f-1)%8)) == 0 {
+			continue
+		}
+
+		spec, ok := specs[f]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %d", ErrMalformed, f)
+		}
+
+		length := spec.length
+		if spec.prefix > 0 {
+			if len(s) < spec.prefix {
+				return nil, ErrMalformed
+			}
+			if length, err = strconv.Atoi(s[:spec.prefix]); err != nil || length > spec.length {
+				return nil, ErrMalformed
+			}
+			s = s[spec.prefix:]
+		}
+
+		if len(s) < length || spec.numeric && !numeric(s[:length]) {
+			return nil, ErrMalformed
+		}
+
+		m.Fields[f] = s[:length]
+		s = s[length:]
+	}
+
+	if s != "" {
+		return nil, ErrMalformed
+	}
+
+	return m, nil
+}
+
+// ReadMessage reads a message framed by its length.
+func ReadMessage(r io.Reader) (*Message, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, binary.BigEndian.Uint16(header[:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return Unpack(b)
+}
+
+// WriteMessage writes the message after its length.
+func WriteMessage(w io.Writer, m *Message) error {
+	b, err := m.Pack()
+	if err != nil {
+		return err
+	}
+
+	if len(b) > maxMessage {
+		return fmt.Errorf("iso8583: message of %d bytes is too long", len(b))
+	}
+
+	frame := make([]byte, 2, 2+len(b))
+	binary.BigEndian.PutUint16(frame, uint16(len(b)))
+
+	_, err = w.Write(append(frame, b...))
+	return err
+}
+
+func numeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     *Message
+		fields map[int]string
+	}{
+		{
+			name: "authorization",
+			in: &Message{MTI: "0100", Fields: map[int]string{
+				FieldPAN:            "4400431234567894",
+				FieldProcessingCode: "000000",
+				FieldAmount:         "150000",
+				FieldSTAN:           "000042",
+				FieldExpiry:         "2910",
+				FieldRRN:            "410112000042",
+				FieldTerminalID:     "TERM1",
+				FieldMerchantID:     "MERCHANT0000001",
+				FieldAdditionalData: "123",
+				FieldCurrency:       "398",
+			}},
+			// fixed fields come back padded
+			fields: map[int]string{
+				FieldPAN:            "4400431234567894",
+				FieldProcessingCode: "000000",
+				FieldAmount:         "000000150000",
+				FieldSTAN:           "000042",
+				FieldExpiry:         "2910",
+				FieldRRN:            "410112000042",
+				FieldTerminalID:     "TERM1   ",
+				FieldMerchantID:     "MERCHANT0000001",
+				FieldAdditionalData: "123",
+				FieldCurrency:       "398",
+			},
+		},
+		{
+			name: "reversal with the secondary bitmap",
+			in: &Message{MTI: "0400", Fields: map[int]string{
+				FieldPAN:             "4400431234567894",
+				FieldRRN:             "410112000042",
+				FieldOriginalElement: "0100000042",
+			}},
+			fields: map[int]string{
+				FieldPAN:             "4400431234567894",
+				FieldRRN:             "410112000042",
+				FieldOriginalElement: "000000000000000000000000000000000100000042",
+			},
+		},
+		{
+			name:   "no fields",
+			in:     &Message{MTI: "0800", Fields: map[int]string{}},
+			fields: map[int]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		b, err := tt.in.Pack()
+		if err != nil {
+			t.Errorf("%s: Pack error = %v", tt.name, err)
+			continue
+		}
+
+		out, err := Unpack(b)
+		if err != nil {
+			t.Errorf("%s: Unpack(%q) error = %v", tt.name, b, err)
+			continue
+		}
+
+		if out.MTI != tt.in.MTI || !reflect.DeepEqual(out.Fields, tt.fields) {
+			t.Errorf("%s: got %s %v, want %s %v", tt.name, out.MTI, out.Fields, tt.in.MTI, tt.fields)
+		}
+
+		if got := out.Get(FieldTerminalID); tt.in.Has(FieldTerminalID) && got != tt.in.Fields[FieldTerminalID] {
+			t.Errorf("%s: Get(FieldTerminalID) = %q, want %q", tt.name, got, tt.in.Fields[FieldTerminalID])
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	m := NewMessage("0800")
+	m.Set(FieldTransmittedAt, "1017120000")
+	m.Set(FieldSTAN, "1")
+	m.Set(FieldNetworkCode, "301")
+
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fields 1 (the secondary bitmap), 7 and 11, then 70
+	want := "0800" + "8220000000000000" + "0400000000000000" + "1017120000" + "000001" + "301"
+	if string(b) != want {
+		t.Errorf("Pack() = %q, want %q", b, want)
+	}
+
+	invalid := []*Message{
+		{MTI: "080", Fields: map[int]string{}},
+		{MTI: "08a0", Fields: map[int]string{}},
+		{MTI: "0800", Fields: map[int]string{5: "1"}},
+		{MTI: "0800", Fields: map[int]string{FieldAmount: "12a"}},
+		{MTI: "0800", Fields: map[int]string{FieldAmount: "1234567890123"}},
+		{MTI: "0800", Fields: map[int]string{FieldPAN: "12345678901234567890"}},
+	}
+
+	for _, m := range invalid {
+		if _, err := m.Pack(); err == nil {
+			t.Errorf("Pack(%v) error = nil, want an error", m)
+		}
+	}
+}
+
+func TestUnpackMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"0800",
+		"08x00000000000000000",
+		"0800Z000000000000000",
+		// secondary bitmap announced but missing
+		"08008000000000000000",
+		// field 5 is unknown
+		"08000800000000000000" + "000000000001",
+		// numeric field 11 with letters
+		"08000020000000000000" + "00000A",
+		// field 11 cut short
+		"08000020000000000000" + "0001",
+		// PAN longer than 19 digits
+		"08004000000000000000" + "20" + "12345678901234567890",
+		// bytes after the last field
+		"08000020000000000000" + "000001" + "X",
+	}
+
+	for _, s := range tests {
+		if _, err := Unpack([]byte(s)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Unpack(%q) error = %v, want %v", s, err, ErrMalformed)
+		}
+	}
+}
+
+func TestResponseMTI(t *testing.T) {
+	tests := map[string]string{
+		"0100": "0110",
+		"0200": "0210",
+		"0400": "0410",
+		"0401": "0410",
+		"0800": "0810",
+	}
+
+	for mti, want := range tests {
+		if got := NewMessage(mti).ResponseMTI(); got != want {
+			t.Errorf("ResponseMTI of %s = %s, want %s", mti, got, want)
+		}
+	}
+}
+
+func TestReadWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+
+	m := NewMessage("0200")
+	m.Set(FieldAmount, "120000")
+	m.Set(FieldRRN, "410112000042")
+
+	if err := WriteMessage(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := int(buf.Bytes()[0])<<8 | int(buf.Bytes()[1]); n != buf.Len()-2 {
+		t.Errorf("length header = %d, want %d", n, buf.Len()-2)
+	}
+
+	out, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.MTI != "0200" || out.Get(FieldAmount) != "000000120000" || out.Get(FieldRRN) != "410112000042" {
+		t.Errorf("ReadMessage = %s %v", out.MTI, out.Fields)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("terminal key")
+
+	req := NewMessage("0100")
+	req.Set(FieldPAN, "4400431234567894")
+	req.Set(FieldAmount, "150000")
+	req.Set(FieldRRN, "410112000042")
+	req.Set(FieldTerminalID, "TERM1")
+	req.Set(FieldMerchantID, "MERCHANT0000001")
+
+	if req.Verify(key) {
+		t.Fatal("unsigned message verified")
+	}
+
+	if err := req.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// the padding of fixed fields doesn't change the MAC
+	b, err := req.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Unpack(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Verify(key) {
+		t.Error("signed message doesn't verify after a round trip")
+	}
+
+	if got.Verify([]byte("another key")) {
+		t.Error("message verified under another key")
+	}
+
+	got.Set(FieldAmount, "150001")
+	if got.Verify(key) {
+		t.Error("tampered message verified")
+	}
+}
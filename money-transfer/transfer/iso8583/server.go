@@ -0,0 +1,285 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+// idleTimeout closes connections of terminals that went quiet.
+const idleTimeout = 5 * time.Minute
+
+// Response codes of field 39.
+const (
+	Approved           = "00"
+	ReferToIssuer      = "01"
+	InvalidMerchant    = "03"
+	DoNotHonor         = "05"
+	InProgress         = "09"
+	InvalidTransaction = "12"
+	InvalidAmount      = "13"
+	InvalidCard        = "14"
+	OriginalNotFound   = "25"
+	FormatError        = "30"
+	InsufficientFunds  = "51"
+	ExpiredCard        = "54"
+	TerminalNotAllowed = "58"
+	ExceedsLimit       = "61"
+	RestrictedCard     = "62"
+	SecurityViolation  = "63"
+	CVVMismatch        = "N7"
+	SystemError        = "96"
+)
+
+// kinds of card messages by MTI, 0401 is a repeated reversal
+var kinds = map[string]string{
+	"0100": domain.CardAuthorization,
+	"0200": domain.CardCompletion,
+	"0400": domain.CardReversal,
+	"0401": domain.CardReversal,
+}
+
+// currencies by their ISO 4217 numeric code in field 49
+var currencies = map[string]string{
+	"398": domain.KZT,
+	"840": domain.USD,
+	"978": domain.EUR,
+	"643": domain.RUB,
+}
+
+// fields of the request copied to the response
+var echoed = []int{
+	FieldProcessingCode, FieldAmount, FieldTransmittedAt, FieldSTAN, FieldLocalTime, FieldLocalDate,
+	FieldRRN, FieldTerminalID, FieldMerchantID, FieldCurrency, FieldNetworkCode, FieldOriginalElement,
+}
+
+var errUnsupported = errors.New("iso8583: unsupported message")
+
+// Server answers card messages of terminals, one at a time on each connection.
+// 0800 network management messages are answered to test the link.
+// Card messages come from the terminals of card_merchants and carry
+// their MAC in field 64, the answers are signed with the same key.
+type Server struct {
+	addr      string
+	merchants map[string]domain.CardMerchant
+	usecase   domain.Transfer
+}
+
+func NewServer(c *domain.Config, tu domain.Transfer) *Server {
+	return &Server{addr: c.ISO8583Addr, merchants: c.CardMerchants, usecase: tu}
+}
+
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(idleTimeout))
+
+		req, err := ReadMessage(conn)
+		if errors.Is(err, ErrMalformed) {
+			// the frame was read, the next one can still be answered
+			log.Warn().Err(err).Str("terminal", conn.RemoteAddr().String()).Msg("iso8583")
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Warn().Err(err).Str("terminal", conn.RemoteAddr().String()).Msg("iso8583")
+			}
+			return
+		}
+
+		if err := WriteMessage(conn, s.handle(context.Background(), req)); err != nil {
+			log.Warn().Err(err).Str("terminal", conn.RemoteAddr().String()).Msg("iso8583")
+			return
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req *Message) *Message {
+	resp := NewMessage(req.ResponseMTI())
+
+	for _, f := range echoed {
+		if req.Has(f) {
+			resp.Set(f, req.Fields[f])
+		}
+	}
+
+	if req.MTI == "0800" {
+		resp.Set(FieldResponseCode, Approved)
+		return resp
+	}
+
+	var m *domain.CardMessage
+
+	terminal, err := s.authenticate(req)
+	if err == nil {
+		m, err = cardMessage(req)
+	}
+	if err == nil {
+		m.CardPresent = terminal.CardPresent
+		err = s.usecase.ProcessCardMessage(ctx, m)
+	}
+
+	code := responseCode(err)
+	resp.Set(FieldResponseCode, code)
+
+	if code == Approved {
+		resp.Set(FieldAuthCode, fmt.Sprintf("%06d", m.ID%1000000))
+	}
+
+	if terminal != nil {
+		if err := resp.Sign([]byte(terminal.Key)); err != nil {
+			log.Warn().Err(err).Str("terminal", req.Get(FieldTerminalID)).Msg("cannot sign the answer")
+		}
+	}
+
+	event := log.Info()
+	if code == SystemError {
+		event = log.Warn().Err(err)
+	}
+	event.Str("mti", req.MTI).Str("terminal", req.Get(FieldTerminalID)).Str("rrn", req.Get(FieldRRN)).
+		Str("code", code).Msg("card message")
+
+	return resp
+}
+
+// authenticate finds the terminal of the request among the terminals
+// of its merchant and checks the MAC under the key of the terminal.
+func (s *Server) authenticate(req *Message) (*domain.CardTerminal, error) {
+	merchant, ok := s.merchants[req.Get(FieldMerchantID)]
+	if !ok {
+		return nil, domain.ErrUnknownMerchant
+	}
+
+	terminal, ok := merchant.Terminals[req.Get(FieldTerminalID)]
+	if !ok {
+		return nil, domain.ErrUnknownTerminal
+	}
+
+	if !req.Verify([]byte(terminal.Key)) {
+		return nil, domain.ErrTerminalAuth
+	}
+
+	return &terminal, nil
+}
+
+// cardMessage reads a card message out of the request.
+// Field 48 carries the CVV2 when the terminal has it.
+func cardMessage(req *Message) (*domain.CardMessage, error) {
+	kind, ok := kinds[req.MTI]
+	if !ok {
+		return nil, errUnsupported
+	}
+
+	// only purchases of goods and services
+	if code := req.Get(FieldProcessingCode); code != "" && code[:2] != "00" {
+		return nil, errUnsupported
+	}
+
+	m := &domain.CardMessage{
+		Kind:       kind,
+		TerminalID: req.Get(FieldTerminalID),
+		MerchantID: req.Get(FieldMerchantID),
+		RRN:        req.Get(FieldRRN),
+		PAN:        req.Get(FieldPAN),
+		CVV:        req.Get(FieldAdditionalData),
+	}
+
+	if kind != domain.CardReversal {
+		amount, err := strconv.ParseInt(req.Get(FieldAmount), 10, 64)
+		if err != nil {
+			return nil, domain.ErrInvalidCardMessage
+		}
+		m.Amount = amount
+
+		// YYMM on the wire, MM/YY on the card
+		expiry := req.Get(FieldExpiry)
+		if len(expiry) != 4 {
+			return nil, domain.ErrInvalidCardMessage
+		}
+		m.Expiry = expiry[2:] + "/" + expiry[:2]
+	}
+
+	if code := req.Get(FieldCurrency); code != "" {
+		if m.Currency, ok = currencies[code]; !ok {
+			return nil, domain.ErrInvalidCurrency
+		}
+	}
+
+	return m, nil
+}
+
+func responseCode(err error) string {
+	var limitErr *domain.LimitError
+	if errors.As(err, &limitErr) {
+		return ExceedsLimit
+	}
+
+	var riskErr *domain.RiskError
+	if errors.As(err, &riskErr) {
+		if riskErr.Outcome == domain.RiskReview {
+			return ReferToIssuer
+		}
+		return DoNotHonor
+	}
+
+	switch err {
+	case nil:
+		return Approved
+	case domain.ErrUnknownMerchant:
+		return InvalidMerchant
+	case domain.ErrUnknownTerminal:
+		return TerminalNotAllowed
+	case domain.ErrTerminalAuth:
+		return SecurityViolation
+	case domain.ErrScreeningBlocked:
+		return DoNotHonor
+	case errUnsupported, domain.ErrTransReceiver, domain.ErrHoldNotActive, domain.ErrHoldNotFound:
+		return InvalidTransaction
+	case domain.ErrTransSum, domain.ErrInvalidCurrency, domain.ErrNoRate, domain.ErrHoldExceeded:
+		return InvalidAmount
+	case domain.ErrInvalidPAN, domain.ErrCardNotFound:
+		return InvalidCard
+	case domain.ErrOriginalNotFound, domain.ErrRefundExceeded:
+		return OriginalNotFound
+	case domain.ErrInvalidCardMessage:
+		return FormatError
+	case domain.ErrCardMessageInProgress:
+		return InProgress
+	case domain.ErrInvalidSum:
+		return InsufficientFunds
+	case domain.ErrCardExpired:
+		return ExpiredCard
+	case domain.ErrCardNotActive:
+		return RestrictedCard
+	case domain.ErrCardDetails:
+		return CVVMismatch
+	default:
+		return SystemError
+	}
+}
@@ -0,0 +1,162 @@
+package fees
+
+import (
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog/log"
+)
+
+// fileFeeStore reads the fee schedule from a TOML file and rereads it
+// when the file changes, so fees are updated without a restart.
+type fileFeeStore struct {
+	path           string
+	reloadInterval time.Duration
+
+	mu       sync.Mutex
+	schedule *domain.FeeSchedule
+	percents map[string][]*big.Rat
+	modified time.Time
+	checked  time.Time
+}
+
+// NewFileFeeStore loads the fee schedule from the file.
+func NewFileFeeStore(c *domain.Config) (domain.FeeStore, error) {
+	s := &fileFeeStore{
+		path:           c.FeesFile,
+		reloadInterval: c.FeesReloadInterval.Duration,
+	}
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileFeeStore) Schedule() *domain.FeeSchedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadIfChanged()
+
+	return s.schedule
+}
+
+// Fee finds the tier of the amount. Percent part is rounded half up to a minor unit.
+func (s *fileFeeStore) Fee(currency string, amount int64, own bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadIfChanged()
+
+	if own && s.schedule.OwnAccountsFree {
+		return 0, nil
+	}
+
+	tiers := s.schedule.Currencies[currency]
+
+	for i, tier := range tiers {
+		if tier.UpTo != 0 && amount > tier.UpTo {
+			continue
+		}
+
+		percent := new(big.Rat).Mul(s.percents[currency][i], big.NewRat(amount, 100))
+		half := new(big.Rat).Add(percent, big.NewRat(1, 2))
+
+		fee := tier.Flat + new(big.Int).Quo(half.Num(), half.Denom()).Int64()
+
+		if tier.Min != 0 && fee < tier.Min {
+			fee = tier.Min
+		}
+		if tier.Max != 0 && fee > tier.Max {
+			fee = tier.Max
+		}
+
+		return fee, nil
+	}
+
+	return 0, nil
+}
+
+func (s *fileFeeStore) reloadIfChanged() {
+	if time.Since(s.checked) < s.reloadInterval {
+		return
+	}
+	s.checked = time.Now()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		log.Warn().Err(err).Str("file", s.path).Msg("cannot check fee schedule, keeping loaded one")
+		return
+	}
+
+	if info.ModTime().Equal(s.modified) {
+		return
+	}
+
+	if err := s.reload(); err != nil {
+		log.Warn().Err(err).Str("file", s.path).Msg("cannot reload fee schedule, keeping loaded one")
+		return
+	}
+
+	log.Info().Str("file", s.path).Msg("fee schedule reloaded")
+}
+
+// reload reads and validates the schedule. Caller must hold the lock.
+func (s *fileFeeStore) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+
+	schedule := &domain.FeeSchedule{}
+	if _, err := toml.DecodeFile(s.path, schedule); err != nil {
+		return err
+	}
+
+	percents := make(map[string][]*big.Rat, len(schedule.Currencies))
+
+	for currency, tiers := range schedule.Currencies {
+		if !domain.ValidCurrency(currency) {
+			return domain.ErrInvalidFees
+		}
+
+		sort.SliceStable(tiers, func(i, j int) bool {
+			// the unbounded tier goes last
+			if tiers[i].UpTo == 0 {
+				return false
+			}
+			return tiers[j].UpTo == 0 || tiers[i].UpTo < tiers[j].UpTo
+		})
+
+		for _, tier := range tiers {
+			p := new(big.Rat)
+			if tier.Percent != "" {
+				var ok bool
+				if p, ok = p.SetString(tier.Percent); !ok {
+					return domain.ErrInvalidFees
+				}
+			}
+
+			if tier.Flat < 0 || tier.Min < 0 || tier.Max < 0 || tier.UpTo < 0 || p.Sign() < 0 {
+				return domain.ErrInvalidFees
+			}
+
+			percents[currency] = append(percents[currency], p)
+		}
+	}
+
+	s.schedule = schedule
+	s.percents = percents
+	s.modified = info.ModTime()
+	s.checked = time.Now()
+
+	return nil
+}
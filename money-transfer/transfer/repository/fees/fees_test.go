@@ -0,0 +1,100 @@
+package fees
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"money-transfer/domain"
+)
+
+const testSchedule = `
+own_accounts_free = true
+
+# the unbounded tier first, tiers are sorted when loaded
+[[currencies.KZT]]
+percent = "0.5"
+min = 5000
+max = 200000
+
+[[currencies.KZT]]
+up_to = 1000000
+
+[[currencies.USD]]
+flat = 100
+percent = "0.3"
+max = 2500
+
+[[currencies.EUR]]
+up_to = 10000
+percent = "1.25"
+`
+
+func testStore(t *testing.T, schedule string) (domain.FeeStore, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fees.toml")
+	if err := os.WriteFile(path, []byte(schedule), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewFileFeeStore(&domain.Config{FeesFile: path})
+}
+
+func TestFee(t *testing.T) {
+	s, err := testStore(t, testSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		currency string
+		amount   int64
+		own      bool
+		want     int64
+	}{
+		{"KZT", 1000000, false, 0}, // up_to is included
+		{"KZT", 1000001, false, 5000},
+		{"KZT", 1500000, false, 7500},
+		{"KZT", 1500099, false, 7500}, // 7500.495
+		{"KZT", 1500100, false, 7501}, // 7500.5 rounds up
+		{"KZT", 50000000, false, 200000},
+		{"KZT", 1500000, true, 0},
+		{"USD", 10000, false, 130},
+		{"USD", 10166, false, 130}, // 30.498
+		{"USD", 10167, false, 131}, // 30.501
+		{"USD", 1000000, false, 2500},
+		{"EUR", 10000, false, 125},
+		{"EUR", 40, false, 1}, // 0.5
+		{"EUR", 39, false, 0}, // 0.4875
+		{"EUR", 10001, false, 0},
+		{"RUB", 1000000, false, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := s.Fee(tt.currency, tt.amount, tt.own)
+		if err != nil {
+			t.Errorf("Fee(%s, %d, %v) error = %v", tt.currency, tt.amount, tt.own, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Fee(%s, %d, %v) = %d, want %d", tt.currency, tt.amount, tt.own, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	tests := []string{
+		"[[currencies.XYZ]]\nflat = 100\n",
+		"[[currencies.KZT]]\npercent = \"-1\"\n",
+		"[[currencies.KZT]]\npercent = \"abc\"\n",
+		"[[currencies.KZT]]\nflat = -100\n",
+		"[[currencies.KZT]]\nup_to = -1\n",
+	}
+
+	for _, schedule := range tests {
+		if _, err := testStore(t, schedule); err != domain.ErrInvalidFees {
+			t.Errorf("%q: error = %v, want %v", schedule, err, domain.ErrInvalidFees)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// cardColumns are scanned by cardFields.
+var cardColumns = `ID, AccountID, OwnerID, PANHash, BIN, Last4, CVVHash, Status, Created, Expires, ` +
+	numberColumn("AccountID", "AccountNumber")
+
+func cardFields(c *domain.Card) []interface{} {
+	return []interface{}{
+		&c.ID, &c.AccountID, &c.OwnerID, &c.PANHash, &c.BIN, &c.Last4, &c.CVVHash, &c.Status, &c.Created, &c.Expires,
+		&c.AccountNumber,
+	}
+}
+
+// expireCard shows an active card past its expiry as expired.
+func expireCard(c *domain.Card, now time.Time) {
+	if c.Status == domain.CardActive && !now.Before(c.Expires) {
+		c.Status = domain.CardExpired
+	}
+}
+
+func (db *sqlRepository) CreateCard(ctx context.Context, c *domain.Card) error {
+	err := db.QueryRow(ctx, `
+	INSERT INTO cards(AccountID, OwnerID, PANHash, BIN, Last4, CVVHash, Status, Created, Expires)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING ID, `+numberColumn("AccountID", "AccountNumber"),
+		c.AccountID, c.OwnerID, c.PANHash, c.BIN, c.Last4, c.CVVHash, c.Status, c.Created, c.Expires,
+	).Scan(&c.ID, &c.AccountNumber)
+
+	return translate(err)
+}
+
+func (db *sqlRepository) FindCard(ctx context.Context, ID int64) (*domain.Card, error) {
+	return db.findCard(ctx, `SELECT `+cardColumns+` FROM cards WHERE ID = $1`, ID)
+}
+
+func (db *sqlRepository) CardByPAN(ctx context.Context, panHash string) (*domain.Card, error) {
+	return db.findCard(ctx, `SELECT `+cardColumns+` FROM cards WHERE PANHash = $1`, panHash)
+}
+
+func (db *sqlRepository) findCard(ctx context.Context, query string, arg interface{}) (*domain.Card, error) {
+	c := &domain.Card{}
+
+	err := db.QueryRow(ctx, query, arg).Scan(cardFields(c)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrCardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expireCard(c, time.Now())
+
+	return c, nil
+}
+
+func (db *sqlRepository) Cards(ctx context.Context, ownerID int64) ([]*domain.Card, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+cardColumns+`
+	FROM cards
+	WHERE OwnerID = $1
+	ORDER BY ID DESC`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	now := time.Now()
+	cards := make([]*domain.Card, 0)
+
+	for rows.Next() {
+		c := &domain.Card{}
+
+		if err := rows.Scan(cardFields(c)...); err != nil {
+			return nil, err
+		}
+
+		expireCard(c, now)
+		cards = append(cards, c)
+	}
+
+	return cards, rows.Err()
+}
+
+// SetCardStatus blocks or unblocks a card that hasn't expired.
+func (db *sqlRepository) SetCardStatus(ctx context.Context, ID int64, status string) error {
+	tag, err := db.Exec(ctx, `
+	UPDATE cards SET Status = $2
+	WHERE ID = $1 AND Expires > $3`,
+		ID, status, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrCardNotActive
+	}
+	return nil
+}
+
+// cardMessageColumns are scanned by cardMessageFields.
+const cardMessageColumns = `ID, Kind, Status, TerminalID, MerchantID, RRN, Amount, Currency, COALESCE(CardID, 0), 
+	COALESCE(HoldID, 0), COALESCE(TransactionID, 0), Created`
+
+func cardMessageFields(m *domain.CardMessage) []interface{} {
+	return []interface{}{
+		&m.ID, &m.Kind, &m.Status, &m.TerminalID, &m.MerchantID, &m.RRN, &m.Amount, &m.Currency, &m.CardID,
+		&m.HoldID, &m.TransactionID, &m.Created,
+	}
+}
+
+// ReserveCardMessage relies on card_messages_terminal_rrn_key, of two copies
+// of a message sent at once only one is inserted.
+func (db *sqlRepository) ReserveCardMessage(ctx context.Context, m *domain.CardMessage) error {
+	m.Status = domain.CardMessagePending
+
+	err := db.QueryRow(ctx, `
+	INSERT INTO card_messages(Kind, Status, TerminalID, MerchantID, RRN, Amount, Currency, Created)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING ID`,
+		m.Kind, m.Status, m.TerminalID, m.MerchantID, m.RRN, m.Amount, m.Currency, m.Created,
+	).Scan(&m.ID)
+
+	return translate(err)
+}
+
+func (db *sqlRepository) ApproveCardMessage(ctx context.Context, m *domain.CardMessage) error {
+	_, err := db.Exec(ctx, `
+	UPDATE card_messages 
+	SET Status = $2, Amount = $3, Currency = $4, CardID = $5, HoldID = NULLIF($6::bigint, 0), TransactionID = NULLIF($7::bigint, 0)
+	WHERE ID = $1`,
+		m.ID, domain.CardMessageApproved, m.Amount, m.Currency, m.CardID, m.HoldID, m.TransactionID,
+	)
+	if err != nil {
+		return err
+	}
+
+	m.Status = domain.CardMessageApproved
+
+	return nil
+}
+
+func (db *sqlRepository) ReleaseCardMessage(ctx context.Context, ID int64) error {
+	_, err := db.Exec(ctx, `DELETE FROM card_messages WHERE ID = $1 AND Status = $2`, ID, domain.CardMessagePending)
+	return err
+}
+
+func (db *sqlRepository) FindCardMessage(ctx context.Context, terminalID, RRN, kind string) (*domain.CardMessage, error) {
+	m := &domain.CardMessage{}
+
+	err := db.QueryRow(ctx, `
+	SELECT `+cardMessageColumns+` 
+	FROM card_messages 
+	WHERE TerminalID = $1 AND RRN = $2 AND Kind = $3 AND Status = $4`,
+		terminalID, RRN, kind, domain.CardMessageApproved,
+	).Scan(cardMessageFields(m)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrOriginalNotFound
+	}
+
+	return m, err
+}
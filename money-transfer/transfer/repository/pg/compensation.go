@@ -0,0 +1,177 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (db *sqlRepository) FindTransaction(ctx context.Context, ID int64) (*domain.Transaction, error) {
+	t := &domain.Transaction{}
+
+	err := db.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE ID = $1`, ID).Scan(transactionFields(t)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrTransactionNotFound
+	}
+
+	return t, err
+}
+
+// CreateCompensation locks the original transfer, so concurrent refunds
+// can't return more than it brought. Money goes back at the rate of the transfer
+// and the last compensation returns exactly what is left of both legs.
+func (db *sqlRepository) CreateCompensation(ctx context.Context, originalID int64, kind string, amount int64) (*domain.Transaction, error) {
+	var c *domain.Transaction
+
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		o := &domain.Transaction{}
+
+		err := tx.QueryRow(ctx, `
+		SELECT `+transactionColumns+` 
+		FROM transactions 
+		WHERE ID = $1 AND Kind = $2 AND Status = $3
+		FOR UPDATE`,
+			originalID, domain.TransactionTransfer, domain.StatusSettled,
+		).Scan(transactionFields(o)...)
+		if err == pgx.ErrNoRows {
+			return domain.ErrTransactionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// what receiver already sent back and sender already got back
+		var taken, returned int64
+		err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(Amount), 0), COALESCE(SUM(ReceivedAmount), 0)
+		FROM transactions 
+		WHERE OriginalID = $1`,
+			originalID,
+		).Scan(&taken, &returned)
+		if err != nil {
+			return err
+		}
+
+		left := o.ReceivedAmount - taken
+		if amount == 0 {
+			amount = left
+		}
+
+		if amount <= 0 || amount > left {
+			return domain.ErrRefundExceeded
+		}
+
+		back := o.Amount - returned
+		if amount < left {
+			share := new(big.Int).Mul(big.NewInt(o.Amount), big.NewInt(amount))
+			back = share.Div(share, big.NewInt(o.ReceivedAmount)).Int64()
+		}
+
+		var fee int64
+		if kind == domain.TransactionReversal {
+			fee = o.Fee
+		}
+
+		balances, err := lockAccounts(ctx, tx, o.SenderID, o.ReceiverID)
+		if err != nil {
+			return err
+		}
+
+		held, err := heldAmount(ctx, tx, o.ReceiverID, 0, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if balances[o.ReceiverID]-held < amount {
+			return domain.ErrInvalidSum
+		}
+
+		postings := []domain.Posting{
+			{AccountID: o.ReceiverID, Amount: -amount, Currency: o.ReceivedCurrency},
+			{AccountID: o.SenderID, Amount: back + fee, Currency: o.Currency},
+		}
+
+		if fee > 0 {
+			fees, err := systemAccount(ctx, tx, domain.AccountFees, o.Currency)
+			if err != nil {
+				return err
+			}
+
+			postings = append(postings, domain.Posting{AccountID: fees, Amount: -fee, Currency: o.Currency})
+		}
+
+		rate := "1"
+
+		if o.Currency != o.ReceivedCurrency {
+			sold, err := systemAccount(ctx, tx, domain.AccountFX, o.ReceivedCurrency)
+			if err != nil {
+				return err
+			}
+
+			bought, err := systemAccount(ctx, tx, domain.AccountFX, o.Currency)
+			if err != nil {
+				return err
+			}
+
+			postings = append(postings,
+				domain.Posting{AccountID: sold, Amount: amount, Currency: o.ReceivedCurrency},
+				domain.Posting{AccountID: bought, Amount: -back, Currency: o.Currency},
+			)
+
+			rate = new(big.Rat).SetFrac64(back, amount).FloatString(6)
+		}
+
+		entryKind := domain.EntryRefund
+		if kind == domain.TransactionReversal {
+			entryKind = domain.EntryReversal
+		}
+
+		entryID, err := postEntry(ctx, tx, entryKind, fmt.Sprintf("%s of transaction %d", kind, o.ID), postings...)
+		if err != nil {
+			return err
+		}
+
+		c = &domain.Transaction{
+			SenderID:         o.ReceiverID,
+			ReceiverID:       o.SenderID,
+			Amount:           amount,
+			Date:             time.Now(),
+			Kind:             kind,
+			Status:           domain.StatusSettled,
+			Currency:         o.ReceivedCurrency,
+			ReceivedAmount:   back + fee,
+			ReceivedCurrency: o.Currency,
+			Rate:             rate,
+			OriginalID:       o.ID,
+		}
+
+		err = tx.QueryRow(ctx, `
+		INSERT INTO transactions(SenderID, ReceiverID, Amount, Date, EntryID, Kind, Status, 
+			Currency, ReceivedAmount, ReceivedCurrency, Rate, OriginalID) 
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ID, `+transferNumbers,
+			c.SenderID, c.ReceiverID, c.Amount, c.Date, entryID, c.Kind, c.Status,
+			c.Currency, c.ReceivedAmount, c.ReceivedCurrency, c.Rate, c.OriginalID,
+		).Scan(&c.ID, &c.SenderNumber, &c.ReceiverNumber)
+		if err != nil {
+			return err
+		}
+
+		event := domain.EventTransactionRefunded
+		if kind == domain.TransactionReversal {
+			event = domain.EventTransactionReversed
+		}
+
+		return addEvent(ctx, tx, event, c, c.SenderID, c.ReceiverID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,177 @@
+package pg
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+)
+
+func TestRefundsNeverExceedTransfer(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 100000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	original := testTransfer(sender, receiver, 10000)
+	if err := db.CreateTransaction(ctx, original, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := db.CreateCompensation(ctx, original.ID, domain.TransactionRefund, 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refund.OriginalID != original.ID || refund.SenderID != receiver.ID || refund.ReceiverID != sender.ID {
+		t.Errorf("refund %+v doesn't send back transaction %d", refund, original.ID)
+	}
+
+	if _, err := db.CreateCompensation(ctx, original.ID, domain.TransactionRefund, 7000); err != domain.ErrRefundExceeded {
+		t.Errorf("refund of 7000 out of 6000 left = %v, want %v", err, domain.ErrRefundExceeded)
+	}
+
+	// a reversal returns what is left after the refund
+	reversal, err := db.CreateCompensation(ctx, original.ID, domain.TransactionReversal, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reversal.Amount != 6000 {
+		t.Errorf("reversal amount %d, want 6000", reversal.Amount)
+	}
+
+	if _, err := db.CreateCompensation(ctx, original.ID, domain.TransactionReversal, 0); err != domain.ErrRefundExceeded {
+		t.Errorf("second reversal = %v, want %v", err, domain.ErrRefundExceeded)
+	}
+
+	if got := balance(t, db, sender); got != 100000 {
+		t.Errorf("sender balance %d, want 100000", got)
+	}
+
+	if got := balance(t, db, receiver); got != 0 {
+		t.Errorf("receiver balance %d, want 0", got)
+	}
+
+	if err := db.CheckLedger(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReversalReturnsFee(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 100000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	original := testTransfer(sender, receiver, 10000)
+	original.Fee = 150
+	if err := db.CreateTransaction(ctx, original, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	// a refund is the receiver's, the fee stays with the bank
+	if _, err := db.CreateCompensation(ctx, original.ID, domain.TransactionRefund, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := balance(t, db, sender), int64(100000-10000-150+1000); got != want {
+		t.Errorf("sender balance after refund %d, want %d", got, want)
+	}
+
+	if _, err := db.CreateCompensation(ctx, original.ID, domain.TransactionReversal, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := balance(t, db, sender); got != 100000 {
+		t.Errorf("sender balance after reversal %d, want 100000", got)
+	}
+
+	if err := db.CheckLedger(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentRefundsNeverExceedTransfer(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	const (
+		workers = 30
+		amount  = 1000
+		total   = 10000
+	)
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, total)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	original := testTransfer(sender, receiver, total)
+	if err := db.CreateTransaction(ctx, original, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	var refunded int64
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			switch _, err := db.CreateCompensation(ctx, original.ID, domain.TransactionRefund, amount); err {
+			case nil:
+				atomic.AddInt64(&refunded, amount)
+			case domain.ErrRefundExceeded:
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if refunded != total {
+		t.Errorf("refunded %d, want %d", refunded, total)
+	}
+
+	if got := balance(t, db, sender); got != total {
+		t.Errorf("sender balance %d, want %d", got, total)
+	}
+
+	if err := db.CheckLedger(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOnlySettledTransfersAreCompensated(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 10000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	original := testTransfer(sender, receiver, 5000)
+	if err := db.CreateTransaction(ctx, original, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := db.CreateCompensation(ctx, original.ID, domain.TransactionRefund, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a refund is not a transfer, it can't be refunded in turn
+	if _, err := db.CreateCompensation(ctx, refund.ID, domain.TransactionRefund, 0); err != domain.ErrTransactionNotFound {
+		t.Errorf("refund of a refund = %v, want %v", err, domain.ErrTransactionNotFound)
+	}
+}
@@ -0,0 +1,85 @@
+package pg
+
+import (
+	"context"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// caseColumns are scanned by caseFields.
+var caseColumns = `ID, OwnerID, SenderID, ReceiverID, Amount, Currency, BaseAmount, SenderIIN, ReceiverIIN, 
+	SenderName, ReceiverName, Action, Matches, Status, Note, Created, Resolved, ResolvedBy, ` + transferNumbers
+
+func caseFields(c *domain.ComplianceCase) []interface{} {
+	return []interface{}{
+		&c.ID, &c.OwnerID, &c.SenderID, &c.ReceiverID, &c.Amount, &c.Currency, &c.BaseAmount, &c.SenderIIN, &c.ReceiverIIN,
+		&c.SenderName, &c.ReceiverName, &c.Action, &c.Matches, &c.Status, &c.Note, &c.Created, &c.Resolved, &c.ResolvedBy,
+		&c.SenderNumber, &c.ReceiverNumber,
+	}
+}
+
+func (db *sqlRepository) CreateCase(ctx context.Context, c *domain.ComplianceCase) error {
+	return db.QueryRow(ctx, `
+	INSERT INTO compliance_cases(OwnerID, SenderID, ReceiverID, Amount, Currency, BaseAmount, SenderIIN, ReceiverIIN, 
+		SenderName, ReceiverName, Action, Matches, Status, Created) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING ID, `+transferNumbers,
+		c.OwnerID, c.SenderID, c.ReceiverID, c.Amount, c.Currency, c.BaseAmount, c.SenderIIN, c.ReceiverIIN,
+		c.SenderName, c.ReceiverName, c.Action, c.Matches, c.Status, c.Created,
+	).Scan(&c.ID, &c.SenderNumber, &c.ReceiverNumber)
+}
+
+func (db *sqlRepository) Cases(ctx context.Context, status string, limit int) ([]*domain.ComplianceCase, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+caseColumns+` 
+	FROM compliance_cases 
+	WHERE $1::varchar = '' OR Status = $1 
+	ORDER BY ID DESC 
+	LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	cases := make([]*domain.ComplianceCase, 0)
+
+	for rows.Next() {
+		c := &domain.ComplianceCase{}
+
+		if err := rows.Scan(caseFields(c)...); err != nil {
+			return nil, err
+		}
+
+		cases = append(cases, c)
+	}
+
+	return cases, rows.Err()
+}
+
+func (db *sqlRepository) ResolveCase(ctx context.Context, c *domain.ComplianceCase) error {
+	err := db.QueryRow(ctx, `
+	UPDATE compliance_cases SET Status = $1, Note = $2, Resolved = $3, ResolvedBy = $4 
+	WHERE ID = $5 AND Status = $6
+	RETURNING `+caseColumns,
+		c.Status, c.Note, c.Resolved, c.ResolvedBy, c.ID, domain.CaseOpen,
+	).Scan(caseFields(c)...)
+	if err != pgx.ErrNoRows {
+		return err
+	}
+
+	// either there is no such case or it isn't open
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM compliance_cases WHERE ID = $1)`, c.ID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return domain.ErrCaseNotFound
+	}
+	return domain.ErrCaseResolved
+}
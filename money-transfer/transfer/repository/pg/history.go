@@ -0,0 +1,198 @@
+package pg
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"money-transfer/domain"
+)
+
+// AccountTransactions returns a page of account history, newest first.
+// A transaction is dated by the journal entry it was booked with, the page,
+// its order and the running balance all go by that time. Running balance is
+// summed from the postings of the account up to the entry of every transaction,
+// so it shows the balance right after the transaction was booked. Entries
+// without a transaction, like the opening balance, count too, and a cash-in
+// changes the balance from its settlement on.
+func (db *sqlRepository) AccountTransactions(ctx context.Context, f *domain.HistoryFilter) (*domain.HistoryPage, error) {
+	args := []interface{}{f.AccountID}
+	where := make([]string, 0)
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !f.From.IsZero() {
+		where = append(where, "Posted >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "Posted < "+arg(f.To))
+	}
+	if f.Direction != "" {
+		where = append(where, "Direction = "+arg(f.Direction))
+	}
+	if f.Counterparty != 0 {
+		where = append(where, "Counterparty = "+arg(f.Counterparty))
+	}
+	if f.MinAmount != 0 {
+		where = append(where, "Value >= "+arg(f.MinAmount))
+	}
+	if f.MaxAmount != 0 {
+		where = append(where, "Value <= "+arg(f.MaxAmount))
+	}
+	if f.Cursor != "" {
+		date, ID, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("(Posted, ID) < (%s, %s)", arg(date), arg(ID)))
+	}
+
+	filter := ""
+	if len(where) > 0 {
+		filter = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	// one extra row tells whether there is a next page
+	limit := arg(f.Limit + 1)
+
+	rows, err := db.Query(ctx, `
+	WITH balances AS (
+		SELECT p.EntryID, e.Created, SUM(SUM(p.Amount)) OVER (ORDER BY e.Created, p.EntryID) AS Balance
+		FROM postings p
+		JOIN journal_entries e ON e.ID = p.EntryID
+		WHERE p.AccountID = $1
+		GROUP BY p.EntryID, e.Created
+	), booked AS (
+		SELECT transactions.*, e.Created AS Posted
+		FROM transactions
+		JOIN journal_entries e ON e.ID = transactions.EntryID
+		WHERE SenderID = $1 OR ReceiverID = $1
+	), moves AS (
+		SELECT `+transactionColumns+`, Posted,
+			CASE WHEN SenderID = $1 THEN 'out' ELSE 'in' END AS Direction,
+			CASE WHEN SenderID = $1 THEN ReceiverID ELSE SenderID END AS Counterparty,
+			CASE WHEN SenderID = $1 THEN Amount ELSE ReceivedAmount END AS Value,
+			COALESCE((
+				SELECT b.Balance FROM balances b
+				WHERE (b.Created, b.EntryID) <= (booked.Posted, booked.EntryID)
+				ORDER BY b.Created DESC, b.EntryID DESC
+				LIMIT 1
+			), 0)::bigint AS Balance,
+			ARRAY(SELECT c.ID FROM transactions c WHERE c.OriginalID = booked.ID ORDER BY c.ID) AS Compensations
+		FROM booked
+	)
+	SELECT `+transactionColumns+`, Posted, Direction, Balance, Compensations
+	FROM moves
+	`+filter+`
+	ORDER BY Posted DESC, ID DESC
+	LIMIT `+limit, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	page := &domain.HistoryPage{
+		Transactions: make([]*domain.Transaction, 0, f.Limit),
+	}
+
+	for rows.Next() {
+		t := &domain.Transaction{}
+		var posted time.Time
+		var balance int64
+
+		err := rows.Scan(append(transactionFields(t), &posted, &t.Direction, &balance, &t.Compensations)...)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Date = posted
+		t.Balance = &balance
+		page.Transactions = append(page.Transactions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Transactions) > f.Limit {
+		page.Transactions = page.Transactions[:f.Limit]
+
+		last := page.Transactions[f.Limit-1]
+		page.NextCursor = encodeCursor(last.Date, last.ID)
+	}
+
+	return page, nil
+}
+
+// BalanceAt returns balance of the account right before the moment,
+// the sum of its postings in journal entries made before it.
+// It windows by the same time history is dated by.
+func (db *sqlRepository) BalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error) {
+	var balance int64
+
+	err := db.QueryRow(ctx, `
+	SELECT COALESCE(SUM(p.Amount), 0)::bigint
+	FROM postings p
+	JOIN journal_entries e ON e.ID = p.EntryID
+	WHERE p.AccountID = $1 AND e.Created < $2`,
+		accountID, at,
+	).Scan(&balance)
+
+	return balance, err
+}
+
+// AccountTurnover sums what came into and went out of the account
+// in journal entries made within [from, to), so the balance at to is
+// the balance at from plus in less out.
+func (db *sqlRepository) AccountTurnover(ctx context.Context, accountID int64, from, to time.Time) (int64, int64, error) {
+	var in, out int64
+
+	err := db.QueryRow(ctx, `
+	SELECT COALESCE(SUM(p.Amount) FILTER (WHERE p.Amount > 0), 0)::bigint,
+		COALESCE(-SUM(p.Amount) FILTER (WHERE p.Amount < 0), 0)::bigint
+	FROM postings p
+	JOIN journal_entries e ON e.ID = p.EntryID
+	WHERE p.AccountID = $1 AND e.Created >= $2 AND e.Created < $3`,
+		accountID, from, to,
+	).Scan(&in, &out)
+
+	return in, out, err
+}
+
+// Cursor points right after the last transaction of a page.
+// It is opaque for clients.
+func encodeCursor(date time.Time, ID int64) string {
+	raw := strconv.FormatInt(date.UnixNano(), 10) + ":" + strconv.FormatInt(ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, domain.ErrInvalidFilter
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return time.Time{}, 0, domain.ErrInvalidFilter
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, domain.ErrInvalidFilter
+	}
+
+	ID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, domain.ErrInvalidFilter
+	}
+
+	return time.Unix(0, nanos).UTC(), ID, nil
+}
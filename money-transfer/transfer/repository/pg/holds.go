@@ -0,0 +1,244 @@
+package pg
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// availableColumn is the balance of accounts less their active holds at $2.
+const availableColumn = `Amount - (
+		SELECT COALESCE(SUM(h.Amount - h.Captured + h.Fee - h.FeeCaptured), 0)
+		FROM holds h
+		WHERE h.AccountID = accounts.ID AND h.Status = 'active' AND h.Expires > $2
+	) AS Available`
+
+// holdColumns are scanned by holdFields.
+var holdColumns = `ID, AccountID, ReceiverID, Role, Amount, Captured, Fee, FeeCaptured, Currency, Status, Created, Expires, ` +
+	numberColumn("AccountID", "AccountNumber") + `, ` + numberColumn("ReceiverID", "ReceiverNumber")
+
+func holdFields(h *domain.Hold) []interface{} {
+	return []interface{}{
+		&h.ID, &h.AccountID, &h.ReceiverID, &h.Role, &h.Amount, &h.Captured, &h.Fee, &h.FeeCaptured,
+		&h.Currency, &h.Status, &h.Created, &h.Expires,
+		&h.AccountNumber, &h.ReceiverNumber,
+	}
+}
+
+// expire shows an active hold past its expiry as expired.
+// Expired holds are never written back, they just stop counting.
+func expire(h *domain.Hold, now time.Time) {
+	if h.Status == domain.HoldActive && !now.Before(h.Expires) {
+		h.Status = domain.HoldExpired
+	}
+}
+
+// heldAmount sums what is left of active holds on the account at now
+// with their fees, except the hold with ID except.
+func heldAmount(ctx context.Context, tx pgx.Tx, accountID, except int64, now time.Time) (int64, error) {
+	var held int64
+
+	err := tx.QueryRow(ctx, `
+	SELECT COALESCE(SUM(Amount - Captured + Fee - FeeCaptured), 0)
+	FROM holds
+	WHERE AccountID = $1 AND Status = $2 AND Expires > $3 AND ID <> $4`,
+		accountID, domain.HoldActive, now, except,
+	).Scan(&held)
+
+	return held, err
+}
+
+// CreateHold reserves money on the account under the same locks as transfers,
+// so a hold and a transfer can't spend the same money twice.
+func (db *sqlRepository) CreateHold(ctx context.Context, h *domain.Hold, check func(u *domain.TransferUsage) error) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		if check != nil {
+			_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(OwnerID) FROM accounts WHERE ID = $1`, h.AccountID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if h.RiskDecisionID != 0 {
+			// a decision rejected meanwhile doesn't let the hold through
+			if err := lockRiskDecision(ctx, tx, h.RiskDecisionID, domain.DecisionRejected); err != nil {
+				return err
+			}
+		}
+
+		balances, err := lockAccounts(ctx, tx, h.AccountID)
+		if err != nil {
+			return err
+		}
+
+		balance, ok := balances[h.AccountID]
+		if !ok {
+			return domain.ErrTransSender
+		}
+
+		held, err := heldAmount(ctx, tx, h.AccountID, 0, h.Created)
+		if err != nil {
+			return err
+		}
+
+		if balance-held < h.Amount+h.Fee {
+			return domain.ErrInvalidSum
+		}
+
+		if check != nil {
+			usage, err := transferUsage(ctx, tx, h.AccountID, h.Created)
+			if err != nil {
+				return err
+			}
+
+			if err := check(usage); err != nil {
+				return err
+			}
+		}
+
+		err = tx.QueryRow(ctx, `
+		INSERT INTO holds(AccountID, ReceiverID, Role, Amount, Captured, Fee, Currency, Status, Created, Expires, RiskDecisionID) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, 0))
+		RETURNING `+holdColumns,
+			h.AccountID, h.ReceiverID, h.Role, h.Amount, h.Captured, h.Fee, h.Currency, h.Status, h.Created, h.Expires, h.RiskDecisionID,
+		).Scan(holdFields(h)...)
+		if err != nil {
+			return err
+		}
+
+		return addEvent(ctx, tx, domain.EventHoldCreated, h, h.AccountID, h.ReceiverID)
+	})
+}
+
+func (db *sqlRepository) FindHold(ctx context.Context, ID int64) (*domain.Hold, error) {
+	h := &domain.Hold{}
+
+	err := db.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE ID = $1`, ID).Scan(holdFields(h)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expire(h, time.Now())
+	return h, nil
+}
+
+// Holds returns holds placed on the account or in its favour, newest first.
+func (db *sqlRepository) Holds(ctx context.Context, accountID int64) ([]*domain.Hold, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+holdColumns+` 
+	FROM holds 
+	WHERE AccountID = $1 OR ReceiverID = $1 
+	ORDER BY ID DESC`,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	now := time.Now()
+	holds := make([]*domain.Hold, 0)
+
+	for rows.Next() {
+		h := &domain.Hold{}
+
+		if err := rows.Scan(holdFields(h)...); err != nil {
+			return nil, err
+		}
+
+		expire(h, now)
+		holds = append(holds, h)
+	}
+
+	return holds, rows.Err()
+}
+
+// CaptureHold makes transfer t out of the hold t.HoldID. The hold is locked
+// first, so concurrent captures of one hold can't take more than it reserved.
+// The capture pays the part of the hold's fee it takes, the last capture
+// what is left of the fee. The hold is captured once nothing is left of it.
+// The last capture voids the rest of the hold in the same transaction.
+func (db *sqlRepository) CaptureHold(ctx context.Context, t *domain.Transaction, last bool, check func(u *domain.TransferUsage) error) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		h := &domain.Hold{}
+
+		err := tx.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE ID = $1 FOR UPDATE`, t.HoldID).Scan(holdFields(h)...)
+		if err == pgx.ErrNoRows {
+			return domain.ErrHoldNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		expire(h, t.Date)
+		if h.Status != domain.HoldActive {
+			return domain.ErrHoldNotActive
+		}
+
+		if t.SenderID != h.AccountID || t.ReceiverID != h.ReceiverID || t.Amount > h.Amount-h.Captured {
+			return domain.ErrHoldExceeded
+		}
+
+		t.Fee = h.Fee - h.FeeCaptured
+		if t.Amount < h.Amount-h.Captured {
+			share := new(big.Int).Mul(big.NewInt(h.Fee), big.NewInt(t.Amount))
+			t.Fee = share.Div(share, big.NewInt(h.Amount)).Int64()
+		}
+
+		if err := transfer(ctx, tx, t, check); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `
+		UPDATE holds 
+		SET Captured = Captured + $1, FeeCaptured = FeeCaptured + $2, 
+			Status = CASE WHEN Captured + $1 = Amount THEN $3 WHEN $4 THEN $5 ELSE Status END 
+		WHERE ID = $6
+		RETURNING Captured, FeeCaptured, Status`,
+			t.Amount, t.Fee, domain.HoldCaptured, last, domain.HoldVoided, h.ID,
+		).Scan(&h.Captured, &h.FeeCaptured, &h.Status)
+		if err != nil {
+			return err
+		}
+
+		if err := addEvent(ctx, tx, domain.EventHoldCaptured, h, h.AccountID, h.ReceiverID); err != nil {
+			return err
+		}
+
+		if h.Status != domain.HoldVoided {
+			return nil
+		}
+
+		return addEvent(ctx, tx, domain.EventHoldVoided, h, h.AccountID, h.ReceiverID)
+	})
+}
+
+// VoidHold releases what is left of an active hold.
+func (db *sqlRepository) VoidHold(ctx context.Context, ID int64) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		h := &domain.Hold{}
+
+		err := tx.QueryRow(ctx, `
+		UPDATE holds SET Status = $1 
+		WHERE ID = $2 AND Status = $3 AND Expires > $4
+		RETURNING `+holdColumns,
+			domain.HoldVoided, ID, domain.HoldActive, time.Now(),
+		).Scan(holdFields(h)...)
+		if err == pgx.ErrNoRows {
+			return domain.ErrHoldNotActive
+		}
+		if err != nil {
+			return err
+		}
+
+		return addEvent(ctx, tx, domain.EventHoldVoided, h, h.AccountID, h.ReceiverID)
+	})
+}
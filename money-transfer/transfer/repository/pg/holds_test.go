@@ -0,0 +1,222 @@
+package pg
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+	"shared/rbac"
+)
+
+// testHold reserves amount and fee on from for a payment to to.
+func testHold(t *testing.T, db *sqlRepository, from, to *domain.Account, amount, fee int64, expires time.Time) *domain.Hold {
+	t.Helper()
+
+	h := &domain.Hold{
+		AccountID:  from.ID,
+		ReceiverID: to.ID,
+		Role:       rbac.RoleUser,
+		Amount:     amount,
+		Fee:        fee,
+		Currency:   domain.KZT,
+		Status:     domain.HoldActive,
+		Created:    time.Now(),
+		Expires:    expires,
+	}
+
+	if err := db.CreateHold(context.Background(), h, allow); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func testCapture(h *domain.Hold, from, to *domain.Account, amount int64) *domain.Transaction {
+	t := testTransfer(from, to, amount)
+	t.HoldID = h.ID
+	return t
+}
+
+func TestHoldReservesBalance(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 10000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	testHold(t, db, sender, receiver, 6000, 100, time.Now().Add(time.Hour))
+
+	if err := db.CreateTransaction(ctx, testTransfer(sender, receiver, 4000), allow); err != domain.ErrInvalidSum {
+		t.Errorf("transfer of held money = %v, want %v", err, domain.ErrInvalidSum)
+	}
+
+	h := &domain.Hold{AccountID: sender.ID, ReceiverID: receiver.ID, Role: rbac.RoleUser, Amount: 4000,
+		Currency: domain.KZT, Status: domain.HoldActive, Created: time.Now(), Expires: time.Now().Add(time.Hour)}
+	if err := db.CreateHold(ctx, h, allow); err != domain.ErrInvalidSum {
+		t.Errorf("hold of held money = %v, want %v", err, domain.ErrInvalidSum)
+	}
+
+	if err := db.CreateTransaction(ctx, testTransfer(sender, receiver, 3900), allow); err != nil {
+		t.Errorf("transfer of the available balance = %v", err)
+	}
+}
+
+func TestCapturesPayTheirShareOfFee(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 10000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	h := testHold(t, db, sender, receiver, 9000, 90, time.Now().Add(time.Hour))
+
+	first := testCapture(h, sender, receiver, 3000)
+	if err := db.CaptureHold(ctx, first, false, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Fee != 30 {
+		t.Errorf("fee of the first capture %d, want 30", first.Fee)
+	}
+
+	last := testCapture(h, sender, receiver, 6000)
+	if err := db.CaptureHold(ctx, last, false, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	if last.Fee != 60 {
+		t.Errorf("fee of the last capture %d, want 60", last.Fee)
+	}
+
+	captured, err := db.FindHold(ctx, h.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if captured.Status != domain.HoldCaptured || captured.Captured != 9000 || captured.FeeCaptured != 90 {
+		t.Errorf("hold after the last capture %+v, want captured 9000 with fee 90", captured)
+	}
+
+	if err := db.CaptureHold(ctx, testCapture(h, sender, receiver, 1), false, allow); err != domain.ErrHoldNotActive {
+		t.Errorf("capture of a captured hold = %v, want %v", err, domain.ErrHoldNotActive)
+	}
+
+	if got := balance(t, db, sender); got != 10000-9090 {
+		t.Errorf("sender balance %d, want %d", got, 10000-9090)
+	}
+
+	if got := balance(t, db, receiver); got != 9000 {
+		t.Errorf("receiver balance %d, want 9000", got)
+	}
+
+	if err := db.CheckLedger(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLastCaptureReleasesRest(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 10000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	h := testHold(t, db, sender, receiver, 5000, 50, time.Now().Add(time.Hour))
+
+	c := testCapture(h, sender, receiver, 2000)
+	if err := db.CaptureHold(ctx, c, true, allow); err != nil {
+		t.Fatal(err)
+	}
+
+	voided, err := db.FindHold(ctx, h.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if voided.Status != domain.HoldVoided || voided.Captured != 2000 {
+		t.Errorf("hold after the last capture %+v, want voided with 2000 captured", voided)
+	}
+
+	// nothing of the hold is reserved any more
+	if err := db.CreateTransaction(ctx, testTransfer(sender, receiver, 10000-2000-c.Fee), allow); err != nil {
+		t.Errorf("transfer of the released rest = %v", err)
+	}
+}
+
+func TestCapturesNeverExceedHold(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	const (
+		workers = 20
+		amount  = 1000
+		held    = 5000
+	)
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 10000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	h := testHold(t, db, sender, receiver, held, 0, time.Now().Add(time.Hour))
+
+	var captured int64
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			switch err := db.CaptureHold(ctx, testCapture(h, sender, receiver, amount), false, allow); err {
+			case nil:
+				atomic.AddInt64(&captured, amount)
+			case domain.ErrHoldExceeded, domain.ErrHoldNotActive:
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if captured != held {
+		t.Errorf("captured %d, want %d", captured, held)
+	}
+
+	if got := balance(t, db, receiver); got != held {
+		t.Errorf("receiver balance %d, want %d", got, held)
+	}
+
+	if err := db.CheckLedger(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExpiredHoldReleasesBalance(t *testing.T) {
+	db := testRepository(t)
+	ctx := context.Background()
+
+	owner := time.Now().UnixNano()
+	sender := testAccount(t, db, owner, 10000)
+	receiver := testAccount(t, db, owner+1, 0)
+
+	h := testHold(t, db, sender, receiver, 8000, 0, time.Now().Add(-time.Second))
+
+	if err := db.CaptureHold(ctx, testCapture(h, sender, receiver, 1000), false, allow); err != domain.ErrHoldNotActive {
+		t.Errorf("capture of an expired hold = %v, want %v", err, domain.ErrHoldNotActive)
+	}
+
+	if err := db.VoidHold(ctx, h.ID); err != domain.ErrHoldNotActive {
+		t.Errorf("void of an expired hold = %v, want %v", err, domain.ErrHoldNotActive)
+	}
+
+	if err := db.CreateTransaction(ctx, testTransfer(sender, receiver, 10000), allow); err != nil {
+		t.Errorf("transfer of the whole balance = %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// postEntry writes a journal entry with its postings inside tx
+// and applies them to account balances. Accounts.Amount is only a projection
+// of postings, so it must never be changed outside of this function.
+func postEntry(ctx context.Context, tx pgx.Tx, kind, description string, postings ...domain.Posting) (int64, error) {
+	if len(postings) < 2 {
+		return 0, domain.ErrUnbalancedEntry
+	}
+
+	sums := make(map[string]int64)
+	for _, p := range postings {
+		sums[p.Currency] += p.Amount
+	}
+
+	for currency, sum := range sums {
+		if sum != 0 {
+			log.Error().Str("kind", kind).Str("currency", currency).Int64("sum", sum).Msg("refusing unbalanced journal entry")
+			return 0, domain.ErrUnbalancedEntry
+		}
+	}
+
+	var entryID int64
+	err := tx.QueryRow(ctx, `
+	INSERT INTO journal_entries(Kind, Description, Created)
+	VALUES ($1, $2, $3)
+	RETURNING ID`,
+		kind, description, time.Now(),
+	).Scan(&entryID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range postings {
+		_, err = tx.Exec(ctx, `
+		INSERT INTO postings(EntryID, AccountID, Amount)
+		VALUES ($1, $2, $3)`,
+			entryID, p.AccountID, p.Amount,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE accounts SET Amount = Amount + $1 WHERE ID = $2`, p.Amount, p.AccountID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// double check what actually landed in the database,
+	// currencies are taken from the accounts this time
+	var (
+		currency string
+		stored   int64
+	)
+
+	err = tx.QueryRow(ctx, `
+	SELECT a.Currency, SUM(p.Amount)
+	FROM postings p
+	JOIN accounts a ON a.ID = p.AccountID
+	WHERE p.EntryID = $1
+	GROUP BY a.Currency
+	HAVING SUM(p.Amount) <> 0
+	LIMIT 1`,
+		entryID,
+	).Scan(&currency, &stored)
+
+	switch err {
+	case pgx.ErrNoRows:
+		return entryID, nil
+	case nil:
+		log.Error().Int64("entry", entryID).Str("currency", currency).Int64("sum", stored).Msg("journal entry is unbalanced")
+		return 0, domain.ErrUnbalancedEntry
+	default:
+		return 0, err
+	}
+}
+
+// systemAccount returns ID of the system account of the kind in the currency.
+func systemAccount(ctx context.Context, tx pgx.Tx, kind, currency string) (int64, error) {
+	var ID int64
+
+	err := tx.QueryRow(ctx, `
+	SELECT ID FROM accounts 
+	WHERE OwnerID = 0 AND Kind = $1 AND Currency = $2`,
+		kind, currency,
+	).Scan(&ID)
+	if err == pgx.ErrNoRows {
+		return 0, domain.ErrInvalidCurrency
+	}
+
+	return ID, err
+}
+
+// CheckLedger verifies that every journal entry is balanced in every currency
+// and every account balance matches the sum of its postings.
+func (db *sqlRepository) CheckLedger(ctx context.Context) error {
+	var (
+		entryID, sum int64
+		currency     string
+	)
+
+	err := db.QueryRow(ctx, `
+	SELECT p.EntryID, a.Currency, SUM(p.Amount)
+	FROM postings p
+	JOIN accounts a ON a.ID = p.AccountID
+	GROUP BY p.EntryID, a.Currency
+	HAVING SUM(p.Amount) <> 0
+	LIMIT 1`,
+	).Scan(&entryID, &currency, &sum)
+
+	switch err {
+	case nil:
+		return fmt.Errorf("%w: entry %d sums to %d %s", domain.ErrLedgerUnbalanced, entryID, sum, currency)
+	case pgx.ErrNoRows:
+	default:
+		return err
+	}
+
+	var accountID, balance, posted int64
+
+	err = db.QueryRow(ctx, `
+	SELECT a.ID, a.Amount, COALESCE(SUM(p.Amount), 0)
+	FROM accounts a
+	LEFT JOIN postings p ON p.AccountID = a.ID
+	GROUP BY a.ID, a.Amount
+	HAVING a.Amount <> COALESCE(SUM(p.Amount), 0)
+	LIMIT 1`,
+	).Scan(&accountID, &balance, &posted)
+
+	switch err {
+	case nil:
+		return fmt.Errorf("%w: account %d has balance %d, postings sum to %d", domain.ErrLedgerUnbalanced, accountID, balance, posted)
+	case pgx.ErrNoRows:
+		return nil
+	default:
+		return err
+	}
+}
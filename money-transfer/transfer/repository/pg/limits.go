@@ -0,0 +1,44 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// usageQuery sums transfers sent from accounts matching the condition
+// within the rolling windows ending at $2.
+const usageQuery = `
+SELECT
+	COALESCE(SUM(BaseAmount) FILTER (WHERE Date > $2::timestamp - $3::interval), 0),
+	COALESCE(SUM(BaseAmount), 0),
+	COUNT(*) FILTER (WHERE Date > $2::timestamp - $3::interval)
+FROM transactions
+WHERE Kind = 'transfer' AND Date > $2::timestamp - $4::interval AND `
+
+// transferUsage counts what the account and all accounts
+// of its owner have sent before now.
+func transferUsage(ctx context.Context, tx pgx.Tx, accountID int64, now time.Time) (*domain.TransferUsage, error) {
+	u := &domain.TransferUsage{}
+
+	err := tx.QueryRow(ctx, usageQuery+`SenderID = $1`,
+		accountID, now, domain.LimitDay, domain.LimitMonth,
+	).Scan(&u.Account.Day, &u.Account.Month, &u.Account.DayCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, usageQuery+`SenderID IN (
+		SELECT ID FROM accounts WHERE OwnerID = (SELECT OwnerID FROM accounts WHERE ID = $1)
+	)`,
+		accountID, now, domain.LimitDay, domain.LimitMonth,
+	).Scan(&u.User.Day, &u.User.Month, &u.User.DayCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
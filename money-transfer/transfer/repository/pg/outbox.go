@@ -0,0 +1,91 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// addEvent writes an event about the change made by tx to the outbox,
+// it is published only if tx commits.
+func addEvent(ctx context.Context, tx pgx.Tx, kind string, payload interface{}, accounts ...int64) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO outbox(Type, Accounts, Payload, Created) 
+	VALUES ($1, $2, $3, $4)`,
+		kind, accounts, raw, time.Now(),
+	)
+	return err
+}
+
+// RelayEvents hands unpublished events to publish in the order they were
+// written and marks the ones it accepted. It stops at the first failure,
+// the rest is picked up next time. Events taken by another instance are skipped.
+func (db *sqlRepository) RelayEvents(ctx context.Context, limit int, publish func(e *domain.Event) error) (int, error) {
+	var published int
+	var publishErr error
+
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		published, publishErr = 0, nil
+
+		rows, err := tx.Query(ctx, `
+		SELECT ID, Type, Accounts, Payload, Created
+		FROM outbox
+		WHERE Published IS NULL
+		ORDER BY ID
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+		if err != nil {
+			return err
+		}
+
+		events := make([]*domain.Event, 0, limit)
+
+		for rows.Next() {
+			e := &domain.Event{}
+
+			if err := rows.Scan(&e.ID, &e.Type, &e.Accounts, &e.Payload, &e.Created); err != nil {
+				rows.Close()
+				return err
+			}
+
+			events = append(events, e)
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		IDs := make([]int64, 0, len(events))
+
+		for _, e := range events {
+			if publishErr = publish(e); publishErr != nil {
+				break
+			}
+			IDs = append(IDs, e.ID)
+		}
+
+		if len(IDs) == 0 {
+			return nil
+		}
+
+		published = len(IDs)
+
+		_, err = tx.Exec(ctx, `UPDATE outbox SET Published = $1 WHERE ID = ANY($2)`, time.Now(), IDs)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, publishErr
+}
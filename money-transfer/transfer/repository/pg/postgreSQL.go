@@ -19,6 +19,10 @@ func NewSQLRepository(c *domain.Config) (domain.Repository, error) {
 
 	DSN := fmt.Sprintf("postgres://%s:%s@%s%s/%s", c.UserDB, c.PasswordDB, c.HostDB, c.PortDB, c.NameDB)
 
+	return connect(DSN)
+}
+
+func connect(DSN string) (*sqlRepository, error) {
 	config, err := pgxpool.ParseConfig(DSN)
 	if err != nil {
 		return nil, err
@@ -43,25 +47,37 @@ func (db *sqlRepository) CloseConnection() {
 }
 
 func (db *sqlRepository) CreateAccount(ctx context.Context, account *domain.Account) error {
-	_, err := db.Exec(ctx, `
-	INSERT INTO accounts(ID, OwnerID, IIN, Amount, Registered) 
-	VALUES ($1,$2,$3,$4,$5)`,
-		account.ID, account.OwnerID, account.IIN, 0, account.Registered,
-	)
-	return err
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+		INSERT INTO accounts(Number, OwnerID, IIN, Amount, Currency, Registered) 
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING ID`,
+			account.Number, account.OwnerID, account.IIN, 0, account.Currency, account.Registered,
+		).Scan(&account.ID)
+		if err != nil {
+			return err
+		}
+
+		return addEvent(ctx, tx, domain.EventAccountCreated, account, account.ID)
+	})
 }
 
 func (db *sqlRepository) FindAccount(ctx context.Context, ID int64) (*domain.Account, error) {
 	acc := &domain.Account{}
 	err := db.QueryRow(ctx,
-		`SELECT ID, OwnerID, IIN, Amount, Registered FROM accounts WHERE ID=$1`,
-		ID).Scan(&acc.ID, &acc.OwnerID, &acc.IIN, &acc.Amount, &acc.Registered)
+		`SELECT ID, COALESCE(Number, ''), OwnerID, IIN, Amount, `+availableColumn+`, Currency, Kind, Registered FROM accounts WHERE ID=$1`,
+		ID, time.Now()).Scan(&acc.ID, &acc.Number, &acc.OwnerID, &acc.IIN, &acc.Amount, &acc.Available, &acc.Currency, &acc.Kind, &acc.Registered)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrAccountNotFound
+	}
 	return acc, err
 }
 
 func (db *sqlRepository) GetAccounts(ctx context.Context, OwnerID int64) ([]*domain.Account, error) {
 	accounts := make([]*domain.Account, 0)
-	rows, err := db.Query(ctx, `SELECT ID, Amount, Registered FROM accounts WHERE OwnerID = $1`, OwnerID)
+	rows, err := db.Query(ctx,
+		`SELECT ID, COALESCE(Number, ''), Amount, `+availableColumn+`, Currency, Kind, Registered FROM accounts WHERE OwnerID = $1`,
+		OwnerID, time.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +87,7 @@ func (db *sqlRepository) GetAccounts(ctx context.Context, OwnerID int64) ([]*dom
 	for rows.Next() {
 		a := &domain.Account{}
 
-		err = rows.Scan(&a.ID, &a.Amount, &a.Registered)
+		err = rows.Scan(&a.ID, &a.Number, &a.Amount, &a.Available, &a.Currency, &a.Kind, &a.Registered)
 		if err != nil {
 			return nil, err
 		}
@@ -91,17 +107,40 @@ func (db *sqlRepository) GetAccounts(ctx context.Context, OwnerID int64) ([]*dom
 	return accounts, rows.Err()
 }
 
+// numberColumn selects the IBAN of the account whose ID is in column as name.
+// System accounts have no number, theirs is empty.
+func numberColumn(column, name string) string {
+	return `COALESCE((SELECT n.Number FROM accounts n WHERE n.ID = ` + column + `), '') AS ` + name
+}
+
+// transferNumbers are the IBANs of SenderID and ReceiverID,
+// scanned into SenderNumber and ReceiverNumber.
+var transferNumbers = numberColumn("SenderID", "SenderNumber") + `, ` + numberColumn("ReceiverID", "ReceiverNumber")
+
+// transactionColumns are scanned by transactionFields.
+var transactionColumns = `ID, SenderID, ReceiverID, Amount, Date, Kind, Status, Source, 
+	Currency, ReceivedAmount, ReceivedCurrency, Rate, Fee, COALESCE(OriginalID, 0) AS OriginalID, 
+	COALESCE(HoldID, 0) AS HoldID, ` + transferNumbers
+
+func transactionFields(t *domain.Transaction) []interface{} {
+	return []interface{}{
+		&t.ID, &t.SenderID, &t.ReceiverID, &t.Amount, &t.Date, &t.Kind, &t.Status, &t.Source,
+		&t.Currency, &t.ReceivedAmount, &t.ReceivedCurrency, &t.Rate, &t.Fee, &t.OriginalID,
+		&t.HoldID, &t.SenderNumber, &t.ReceiverNumber,
+	}
+}
+
 func (db *sqlRepository) GetLastTransaction(ctx context.Context, accountID int64) (*domain.Transaction, error) {
 	t := &domain.Transaction{}
 
 	err := db.QueryRow(ctx,
-		`SELECT ID, SenderID, ReceiverID, Amount, Date 
+		`SELECT `+transactionColumns+` 
 		FROM transactions 
 		WHERE SenderID = $1 
 		OR ReceiverID = $2 
 		ORDER BY Date DESC`,
 		accountID, accountID,
-	).Scan(&t.ID, &t.SenderID, &t.ReceiverID, &t.Amount, &t.Date)
+	).Scan(transactionFields(t)...)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -113,44 +152,220 @@ func (db *sqlRepository) GetLastTransaction(ctx context.Context, accountID int64
 	return t, nil
 }
 
-func (db *sqlRepository) ChangeAccountSum(ctx context.Context, accountID, newValue int64) error {
-	_, err := db.Exec(ctx, `UPDATE accounts SET Amount = Amount + $1 WHERE ID = $2`, newValue, accountID)
-	return err
+// CreateCashIn records a pending cash-in in the currency of the transaction.
+// Money is parked in the suspense account until the funding source confirms it.
+func (db *sqlRepository) CreateCashIn(ctx context.Context, t *domain.Transaction) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		cashIn, err := systemAccount(ctx, tx, domain.AccountCashIn, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		suspense, err := systemAccount(ctx, tx, domain.AccountSuspense, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		entryID, err := postEntry(ctx, tx, domain.EntryCashIn, fmt.Sprintf("cash-in to %d from %s", t.ReceiverID, t.Source),
+			domain.Posting{AccountID: cashIn, Amount: -t.Amount, Currency: t.Currency},
+			domain.Posting{AccountID: suspense, Amount: t.Amount, Currency: t.Currency},
+		)
+		if err != nil {
+			return err
+		}
+
+		t.SenderID = cashIn
+		t.Kind, t.Status = domain.TransactionCashIn, domain.StatusPending
+
+		err = tx.QueryRow(ctx, `
+		INSERT INTO transactions(SenderID, ReceiverID, Amount, Date, EntryID, Kind, Status, Source, 
+			Currency, ReceivedAmount, ReceivedCurrency, Rate) 
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ID, `+transferNumbers,
+			t.SenderID, t.ReceiverID, t.Amount, t.Date, entryID, t.Kind, t.Status, t.Source,
+			t.Currency, t.ReceivedAmount, t.ReceivedCurrency, t.Rate,
+		).Scan(&t.ID, &t.SenderNumber, &t.ReceiverNumber)
+		if err != nil {
+			return err
+		}
+
+		return addEvent(ctx, tx, domain.EventCashInPending, t, t.ReceiverID)
+	})
 }
 
-func (db *sqlRepository) CreateTransaction(ctx context.Context, SenderID, ReceiverID, Value int64) error {
-	tx, err := db.Begin(ctx)
+// CompleteCashIn moves a pending cash-in out of the suspense account:
+// to the receiver when settled, back to the cash-in account when cancelled.
+func (db *sqlRepository) CompleteCashIn(ctx context.Context, ID int64, status string) (*domain.Transaction, error) {
+	t := &domain.Transaction{}
+
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+		SELECT `+transactionColumns+` 
+		FROM transactions 
+		WHERE ID = $1 AND Kind = $2 AND Status = $3
+		FOR UPDATE`,
+			ID, domain.TransactionCashIn, domain.StatusPending,
+		).Scan(transactionFields(t)...)
+		if err == pgx.ErrNoRows {
+			return domain.ErrCashInNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		suspense, err := systemAccount(ctx, tx, domain.AccountSuspense, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		// SenderID of a cash-in is the cash-in account of its currency
+		kind, to := domain.EntryCashInSettle, t.ReceiverID
+		if status == domain.StatusCancelled {
+			kind, to = domain.EntryCashInCancel, t.SenderID
+		}
+
+		_, err = postEntry(ctx, tx, kind, fmt.Sprintf("cash-in %d %s", t.ID, status),
+			domain.Posting{AccountID: suspense, Amount: -t.Amount, Currency: t.Currency},
+			domain.Posting{AccountID: to, Amount: t.Amount, Currency: t.Currency},
+		)
+		if err != nil {
+			return err
+		}
+
+		t.Status = status
+		_, err = tx.Exec(ctx, `UPDATE transactions SET Status = $1 WHERE ID = $2`, status, t.ID)
+		if err != nil {
+			return err
+		}
+
+		event := domain.EventCashInSettled
+		if status == domain.StatusCancelled {
+			event = domain.EventCashInCancelled
+		}
+
+		return addEvent(ctx, tx, event, t, t.ReceiverID)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer func() {
+	return t, nil
+}
+
+// CreateTransaction moves money between accounts. The available balance of the sender
+// is checked under a row lock, so concurrent transfers can't overdraw it.
+// When currencies differ, the money goes through FX accounts of both currencies.
+// The fee goes to the fees account of the sender's currency in the same entry.
+func (db *sqlRepository) CreateTransaction(ctx context.Context, t *domain.Transaction, check func(u *domain.TransferUsage) error) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		return transfer(ctx, tx, t, check)
+	})
+}
+
+// transfer is the body of CreateTransaction. The sender can spend its balance
+// less active holds, except the hold the transfer is captured from.
+func transfer(ctx context.Context, tx pgx.Tx, t *domain.Transaction, check func(u *domain.TransferUsage) error) error {
+	if check != nil {
+		// transfers of one user are checked one at a time,
+		// whichever of the user's accounts they are sent from
+		_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(OwnerID) FROM accounts WHERE ID = $1`, t.SenderID)
 		if err != nil {
-			tx.Rollback(ctx)
-		} else {
-			tx.Commit(ctx)
+			return err
 		}
-	}()
+	}
 
-	_, err = tx.Exec(ctx, `UPDATE accounts SET Amount = Amount - $1 WHERE ID = $2`, Value, SenderID)
+	if t.RiskDecisionID != 0 {
+		// a decision rejected meanwhile doesn't let the transfer through
+		if err := lockRiskDecision(ctx, tx, t.RiskDecisionID, domain.DecisionRejected); err != nil {
+			return err
+		}
+	}
+
+	balances, err := lockAccounts(ctx, tx, t.SenderID, t.ReceiverID)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, `UPDATE accounts SET Amount = Amount + $1 WHERE ID = $2`, Value, ReceiverID)
+	balance, ok := balances[t.SenderID]
+	if !ok {
+		return domain.ErrTransSender
+	}
+
+	if _, ok := balances[t.ReceiverID]; !ok {
+		return domain.ErrTransReceiver
+	}
+
+	held, err := heldAmount(ctx, tx, t.SenderID, t.HoldID, t.Date)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, `
-	INSERT INTO transactions(SenderID, ReceiverID, Amount, Date) 
-	VALUES($1, $2, $3, $4)`, SenderID, ReceiverID, Value, time.Now())
+	if balance-held < t.Amount+t.Fee {
+		return domain.ErrInvalidSum
+	}
+
+	if check != nil {
+		usage, err := transferUsage(ctx, tx, t.SenderID, t.Date)
+		if err != nil {
+			return err
+		}
+
+		if err := check(usage); err != nil {
+			return err
+		}
+	}
+
+	postings := []domain.Posting{
+		{AccountID: t.SenderID, Amount: -(t.Amount + t.Fee), Currency: t.Currency},
+		{AccountID: t.ReceiverID, Amount: t.ReceivedAmount, Currency: t.ReceivedCurrency},
+	}
+
+	if t.Fee > 0 {
+		fees, err := systemAccount(ctx, tx, domain.AccountFees, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		postings = append(postings, domain.Posting{AccountID: fees, Amount: t.Fee, Currency: t.Currency})
+	}
 
+	if t.Currency != t.ReceivedCurrency {
+		sold, err := systemAccount(ctx, tx, domain.AccountFX, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		bought, err := systemAccount(ctx, tx, domain.AccountFX, t.ReceivedCurrency)
+		if err != nil {
+			return err
+		}
+
+		postings = append(postings,
+			domain.Posting{AccountID: sold, Amount: t.Amount, Currency: t.Currency},
+			domain.Posting{AccountID: bought, Amount: -t.ReceivedAmount, Currency: t.ReceivedCurrency},
+		)
+	}
+
+	entryID, err := postEntry(ctx, tx, domain.EntryTransfer, fmt.Sprintf("transfer from %d to %d", t.SenderID, t.ReceiverID), postings...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	t.Kind, t.Status = domain.TransactionTransfer, domain.StatusSettled
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO transactions(SenderID, ReceiverID, Amount, Date, EntryID, Kind, Status, 
+			Currency, ReceivedAmount, ReceivedCurrency, Rate, BaseAmount, Fee, ScheduleRunID, HoldID, RiskDecisionID) 
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, 0), NULLIF($15, 0), NULLIF($16, 0))
+		RETURNING ID, `+transferNumbers,
+		t.SenderID, t.ReceiverID, t.Amount, t.Date, entryID, t.Kind, t.Status,
+		t.Currency, t.ReceivedAmount, t.ReceivedCurrency, t.Rate, t.BaseAmount, t.Fee, t.ScheduleRunID, t.HoldID, t.RiskDecisionID,
+	).Scan(&t.ID, &t.SenderNumber, &t.ReceiverNumber)
+	if err != nil {
+		return err
+	}
+
+	return addEvent(ctx, tx, domain.EventTransferCompleted, t, t.SenderID, t.ReceiverID)
 }
 
 func (db *sqlRepository) AccountExists(ctx context.Context, accountID int64) bool {
@@ -162,37 +377,71 @@ func (db *sqlRepository) AccountExists(ctx context.Context, accountID int64) boo
 	return true
 }
 
-func (db *sqlRepository) AccountTransactions(ctx context.Context, accountID int64) ([]*domain.Transaction, error) {
+func (db *sqlRepository) NextAccountNumber(ctx context.Context) (int64, error) {
+	var seq int64
+	err := db.QueryRow(ctx, `SELECT nextval('account_number_seq')`).Scan(&seq)
+	return seq, err
+}
+
+func (db *sqlRepository) AccountIDByNumber(ctx context.Context, number string) (int64, error) {
+	var ID int64
+	err := db.QueryRow(ctx, `SELECT ID FROM accounts WHERE Number = $1`, number).Scan(&ID)
+	if err == pgx.ErrNoRows {
+		return 0, domain.ErrAccountNotFound
+	}
+	return ID, err
+}
 
-	rows, err := db.Query(ctx, `
-	SELECT ID, SenderID, ReceiverID, Amount, Date 
-	FROM transactions 
-	WHERE SenderID = $1 OR ReceiverID = $2 
-	ORDER BY Date DESC
-	`, accountID, accountID)
+// ReserveIdempotencyKey stores a fresh key or returns the one
+// already stored under the same name. Keys older than ttl are forgotten,
+// so are unanswered ones past their lease: their request is gone.
+func (db *sqlRepository) ReserveIdempotencyKey(ctx context.Context, key *domain.IdempotencyKey, ttl time.Duration) (*domain.IdempotencyKey, error) {
+	now := time.Now()
 
+	_, err := db.Exec(ctx, `
+	DELETE FROM idempotency_keys 
+	WHERE OwnerID = $1 AND Key = $2 AND (Created < $3 OR StatusCode = 0 AND Leased < $4)`,
+		key.OwnerID, key.Key, now.Add(-ttl), now,
+	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			err = nil
-		}
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	trans := make([]*domain.Transaction, 0)
+	tag, err := db.Exec(ctx, `
+	INSERT INTO idempotency_keys(Key, OwnerID, Fingerprint, Created, Leased) 
+	VALUES ($1, $2, $3, $4, $5) 
+	ON CONFLICT DO NOTHING`,
+		key.Key, key.OwnerID, key.Fingerprint, key.Created, key.Leased,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
 
-		t := &domain.Transaction{}
+	stored := &domain.IdempotencyKey{}
+	err = db.QueryRow(ctx, `
+	SELECT Key, OwnerID, Fingerprint, StatusCode, COALESCE(Header, '{}'), Response, Created, Leased 
+	FROM idempotency_keys 
+	WHERE OwnerID = $1 AND Key = $2`,
+		key.OwnerID, key.Key,
+	).Scan(&stored.Key, &stored.OwnerID, &stored.Fingerprint, &stored.StatusCode, &stored.Header, &stored.Response, &stored.Created, &stored.Leased)
 
-		err := rows.Scan(&t.ID, &t.SenderID, &t.ReceiverID, &t.Amount, &t.Date)
-		if err != nil {
-			return nil, err
-		}
+	return stored, err
+}
 
-		trans = append(trans, t)
-	}
+func (db *sqlRepository) SaveIdempotencyKey(ctx context.Context, key *domain.IdempotencyKey) error {
+	_, err := db.Exec(ctx, `
+	UPDATE idempotency_keys SET StatusCode = $1, Header = $2, Response = $3 
+	WHERE OwnerID = $4 AND Key = $5`,
+		key.StatusCode, key.Header, key.Response, key.OwnerID, key.Key,
+	)
+	return err
+}
 
-	return trans, rows.Err()
+func (db *sqlRepository) DeleteIdempotencyKey(ctx context.Context, ownerID int64, key string) error {
+	_, err := db.Exec(ctx, `DELETE FROM idempotency_keys WHERE OwnerID = $1 AND Key = $2`, ownerID, key)
+	return err
 }
@@ -0,0 +1,198 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// riskDecisionColumns are scanned by riskDecisionFields.
+// TransactionID and HoldID are the transfer or the hold made on the decision.
+var riskDecisionColumns = `d.ID, d.Kind, d.OwnerID, d.Role, d.SenderID, d.ReceiverID, d.Amount, d.Currency, d.BaseAmount, 
+	d.Outcome, d.Rule, d.Fired, d.Reason, d.Status, COALESCE(t.ID, 0), COALESCE(h.ID, 0), COALESCE(d.ScheduleRunID, 0), 
+	d.Created, d.Resolved, d.ResolvedBy, ` +
+	numberColumn("d.SenderID", "SenderNumber") + `, ` + numberColumn("d.ReceiverID", "ReceiverNumber")
+
+const riskDecisionTables = `risk_decisions d 
+	LEFT JOIN transactions t ON t.RiskDecisionID = d.ID 
+	LEFT JOIN holds h ON h.RiskDecisionID = d.ID`
+
+func riskDecisionFields(d *domain.RiskDecision) []interface{} {
+	return []interface{}{
+		&d.ID, &d.Kind, &d.OwnerID, &d.Role, &d.SenderID, &d.ReceiverID, &d.Amount, &d.Currency, &d.BaseAmount,
+		&d.Outcome, &d.Rule, &d.Fired, &d.Reason, &d.Status, &d.TransactionID, &d.HoldID, &d.ScheduleRunID,
+		&d.Created, &d.Resolved, &d.ResolvedBy, &d.SenderNumber, &d.ReceiverNumber,
+	}
+}
+
+func (db *sqlRepository) TransfersSince(ctx context.Context, ownerID int64, since time.Time) (int, error) {
+	var n int
+
+	err := db.QueryRow(ctx, `
+	SELECT COUNT(*)
+	FROM transactions t
+	JOIN accounts a ON a.ID = t.SenderID
+	WHERE a.OwnerID = $1 AND t.Kind = $2 AND t.Date > $3`,
+		ownerID, domain.TransactionTransfer, since,
+	).Scan(&n)
+
+	return n, err
+}
+
+func (db *sqlRepository) KnownReceiver(ctx context.Context, ownerID, receiverID int64) (bool, error) {
+	var known bool
+
+	err := db.QueryRow(ctx, `
+	SELECT EXISTS (
+		SELECT 1
+		FROM transactions t
+		JOIN accounts a ON a.ID = t.SenderID
+		WHERE a.OwnerID = $1 AND t.ReceiverID = $2 AND t.Kind = $3
+	) OR EXISTS (
+		SELECT 1 FROM accounts WHERE ID = $2 AND OwnerID = $1
+	)`,
+		ownerID, receiverID, domain.TransactionTransfer,
+	).Scan(&known)
+
+	return known, err
+}
+
+func (db *sqlRepository) AverageTransfer(ctx context.Context, ownerID int64, since time.Time) (int64, int, error) {
+	var avg int64
+	var n int
+
+	err := db.QueryRow(ctx, `
+	SELECT COALESCE(AVG(t.BaseAmount), 0)::bigint, COUNT(*)
+	FROM transactions t
+	JOIN accounts a ON a.ID = t.SenderID
+	WHERE a.OwnerID = $1 AND t.Kind = $2 AND t.Date > $3`,
+		ownerID, domain.TransactionTransfer, since,
+	).Scan(&avg, &n)
+
+	return avg, n, err
+}
+
+func (db *sqlRepository) CreateRiskDecision(ctx context.Context, d *domain.RiskDecision) error {
+	return db.QueryRow(ctx, `
+	INSERT INTO risk_decisions(Kind, OwnerID, Role, SenderID, ReceiverID, Amount, Currency, BaseAmount, 
+		Outcome, Rule, Fired, Reason, Status, ScheduleRunID, Created) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14::bigint, 0), $15)
+	RETURNING ID, `+transferNumbers,
+		d.Kind, d.OwnerID, d.Role, d.SenderID, d.ReceiverID, d.Amount, d.Currency, d.BaseAmount,
+		d.Outcome, d.Rule, d.Fired, d.Reason, d.Status, d.ScheduleRunID, d.Created,
+	).Scan(&d.ID, &d.SenderNumber, &d.ReceiverNumber)
+}
+
+func (db *sqlRepository) FindRiskDecision(ctx context.Context, ID int64) (*domain.RiskDecision, error) {
+	d := &domain.RiskDecision{}
+
+	err := db.QueryRow(ctx, `SELECT `+riskDecisionColumns+` FROM `+riskDecisionTables+` WHERE d.ID = $1`, ID).Scan(riskDecisionFields(d)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrDecisionNotFound
+	}
+
+	return d, err
+}
+
+func (db *sqlRepository) RiskDecisions(ctx context.Context, status string, limit int) ([]*domain.RiskDecision, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+riskDecisionColumns+` 
+	FROM `+riskDecisionTables+` 
+	WHERE d.Outcome <> $1 AND ($2::varchar = '' OR d.Status = $2) 
+	ORDER BY d.ID DESC 
+	LIMIT $3`,
+		domain.RiskAllow, status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	decisions := make([]*domain.RiskDecision, 0)
+
+	for rows.Next() {
+		d := &domain.RiskDecision{}
+
+		if err := rows.Scan(riskDecisionFields(d)...); err != nil {
+			return nil, err
+		}
+
+		decisions = append(decisions, d)
+	}
+
+	return decisions, rows.Err()
+}
+
+// ResolveRiskDecision waits for a transfer or a hold being made on the decision,
+// a decision with one can't be rejected. The schedule run held
+// for the decision succeeds or fails with it.
+func (db *sqlRepository) ResolveRiskDecision(ctx context.Context, d *domain.RiskDecision) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		if err := lockRiskDecision(ctx, tx, d.ID, domain.DecisionApproved, domain.DecisionRejected); err != nil {
+			return err
+		}
+
+		if d.Status == domain.DecisionRejected {
+			var made bool
+
+			err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM transactions WHERE RiskDecisionID = $1) 
+				OR EXISTS (SELECT 1 FROM holds WHERE RiskDecisionID = $1)`,
+				d.ID,
+			).Scan(&made)
+			if err != nil {
+				return err
+			}
+
+			if made {
+				return domain.ErrDecisionResolved
+			}
+		}
+
+		_, err := tx.Exec(ctx, `
+		UPDATE risk_decisions SET Status = $1, Resolved = $2, ResolvedBy = $3 
+		WHERE ID = $4`,
+			d.Status, d.Resolved, d.ResolvedBy, d.ID,
+		)
+		if err != nil || d.ScheduleRunID == 0 {
+			return err
+		}
+
+		status, reason := domain.RunSucceeded, ""
+		if d.Status == domain.DecisionRejected {
+			status, reason = domain.RunFailed, "rejected by risk review"
+		}
+
+		_, err = tx.Exec(ctx, `
+		UPDATE schedule_runs SET Status = $1, Error = $2, Finished = $3 
+		WHERE ID = $4 AND Status = $5`,
+			status, reason, d.Resolved, d.ScheduleRunID, domain.RunPending,
+		)
+		return err
+	})
+}
+
+// lockRiskDecision locks the decision, it is resolved already when it has one of the statuses.
+func lockRiskDecision(ctx context.Context, tx pgx.Tx, ID int64, resolved ...string) error {
+	var status string
+
+	err := tx.QueryRow(ctx, `SELECT Status FROM risk_decisions WHERE ID = $1 FOR UPDATE`, ID).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return domain.ErrDecisionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, s := range resolved {
+		if status == s {
+			return domain.ErrDecisionResolved
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// scheduleColumns are scanned by scheduleFields.
+var scheduleColumns = `ID, OwnerID, Role, SenderID, ReceiverID, Amount, Cron, Start, Until, 
+	Count, Remaining, Status, Occurrence, Attempt, NextRun, Created, ` + transferNumbers
+
+func scheduleFields(s *domain.Schedule) []interface{} {
+	return []interface{}{
+		&s.ID, &s.OwnerID, &s.Role, &s.SenderID, &s.ReceiverID, &s.Amount, &s.Cron, &s.Start, &s.Until,
+		&s.Count, &s.Remaining, &s.Status, &s.Occurrence, &s.Attempt, &s.NextRun, &s.Created,
+		&s.SenderNumber, &s.ReceiverNumber,
+	}
+}
+
+func (db *sqlRepository) CreateSchedule(ctx context.Context, s *domain.Schedule) error {
+	return db.QueryRow(ctx, `
+	INSERT INTO schedules(OwnerID, Role, SenderID, ReceiverID, Amount, Cron, Start, Until, 
+		Count, Remaining, Status, Occurrence, Attempt, NextRun, Created) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	RETURNING ID, `+transferNumbers,
+		s.OwnerID, s.Role, s.SenderID, s.ReceiverID, s.Amount, s.Cron, s.Start, s.Until,
+		s.Count, s.Remaining, s.Status, s.Occurrence, s.Attempt, s.NextRun, s.Created,
+	).Scan(&s.ID, &s.SenderNumber, &s.ReceiverNumber)
+}
+
+func (db *sqlRepository) FindSchedule(ctx context.Context, ID int64) (*domain.Schedule, error) {
+	s := &domain.Schedule{}
+
+	err := db.QueryRow(ctx, `SELECT `+scheduleColumns+` FROM schedules WHERE ID = $1`, ID).Scan(scheduleFields(s)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrScheduleNotFound
+	}
+
+	return s, err
+}
+
+func (db *sqlRepository) Schedules(ctx context.Context, ownerID int64) ([]*domain.Schedule, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+scheduleColumns+` 
+	FROM schedules 
+	WHERE OwnerID = $1 
+	ORDER BY ID DESC`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSchedules(rows)
+}
+
+func (db *sqlRepository) ScheduleRuns(ctx context.Context, scheduleID int64) ([]*domain.ScheduleRun, error) {
+	rows, err := db.Query(ctx, `
+	SELECT r.ID, r.ScheduleID, r.Occurrence, r.Attempt, r.Status, COALESCE(t.ID, 0), r.Error, r.Started, r.Finished
+	FROM schedule_runs r
+	LEFT JOIN transactions t ON t.ScheduleRunID = r.ID
+	WHERE r.ScheduleID = $1
+	ORDER BY r.ID DESC`,
+		scheduleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	runs := make([]*domain.ScheduleRun, 0)
+
+	for rows.Next() {
+		r := &domain.ScheduleRun{}
+
+		err := rows.Scan(&r.ID, &r.ScheduleID, &r.Occurrence, &r.Attempt, &r.Status, &r.TransactionID, &r.Error, &r.Started, &r.Finished)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, r)
+	}
+
+	return runs, rows.Err()
+}
+
+func (db *sqlRepository) CancelSchedule(ctx context.Context, ownerID, ID int64) error {
+	tag, err := db.Exec(ctx, `
+	UPDATE schedules SET Status = $1 
+	WHERE ID = $2 AND OwnerID = $3 AND Status = $4`,
+		domain.ScheduleCancelled, ID, ownerID, domain.ScheduleActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// ClaimSchedules skips schedules locked by other instances,
+// so every due schedule is taken by one of them.
+func (db *sqlRepository) ClaimSchedules(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.Schedule, error) {
+	var schedules []*domain.Schedule
+
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+		SELECT `+scheduleColumns+` 
+		FROM schedules 
+		WHERE Status = $1 AND NextRun <= $2 
+		ORDER BY NextRun 
+		LIMIT $3 
+		FOR UPDATE SKIP LOCKED`,
+			domain.ScheduleActive, now, limit,
+		)
+		if err != nil {
+			return err
+		}
+
+		if schedules, err = scanSchedules(rows); err != nil {
+			return err
+		}
+
+		IDs := make([]int64, 0, len(schedules))
+		for _, s := range schedules {
+			IDs = append(IDs, s.ID)
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE schedules SET NextRun = $1 WHERE ID = ANY($2)`, now.Add(lease), IDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (db *sqlRepository) StartScheduleRun(ctx context.Context, s *domain.Schedule) (*domain.ScheduleRun, error) {
+	r := &domain.ScheduleRun{
+		ScheduleID: s.ID,
+		Occurrence: s.Occurrence,
+		Attempt:    s.Attempt,
+	}
+
+	// a run left by a crashed instance is picked up again
+	err := db.QueryRow(ctx, `
+	INSERT INTO schedule_runs(ScheduleID, Occurrence, Attempt, Status, Started) 
+	VALUES ($1, $2, $3, $4, $5) 
+	ON CONFLICT (ScheduleID, Occurrence, Attempt) DO UPDATE SET Status = schedule_runs.Status
+	RETURNING ID, Status, Started`,
+		s.ID, s.Occurrence, s.Attempt, domain.RunRunning, time.Now(),
+	).Scan(&r.ID, &r.Status, &r.Started)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRow(ctx, `SELECT ID FROM transactions WHERE ScheduleRunID = $1`, r.ID).Scan(&r.TransactionID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// FinishScheduleRun leaves schedules cancelled while they were running cancelled.
+func (db *sqlRepository) FinishScheduleRun(ctx context.Context, r *domain.ScheduleRun, s *domain.Schedule) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+		UPDATE schedule_runs SET Status = $1, Error = $2, Finished = $3 
+		WHERE ID = $4`,
+			r.Status, r.Error, r.Finished, r.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+		UPDATE schedules 
+		SET Occurrence = $1, Attempt = $2, NextRun = $3, Remaining = $4, 
+			Status = CASE WHEN Status = $5 THEN $6 ELSE Status END
+		WHERE ID = $7`,
+			s.Occurrence, s.Attempt, s.NextRun, s.Remaining, domain.ScheduleActive, s.Status, s.ID,
+		)
+		return err
+	})
+}
+
+func scanSchedules(rows pgx.Rows) ([]*domain.Schedule, error) {
+	defer rows.Close()
+
+	schedules := make([]*domain.Schedule, 0)
+
+	for rows.Next() {
+		s := &domain.Schedule{}
+
+		if err := rows.Scan(scheduleFields(s)...); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, rows.Err()
+}
@@ -0,0 +1,124 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	maxTxAttempts = 5
+	txRetryDelay  = 10 * time.Millisecond
+
+	nonNegativeBalance = "accounts_amount_non_negative"
+	uniqueScheduleRun  = "transactions_schedulerunid_key"
+	uniqueRiskDecision = "transactions_riskdecisionid_key"
+	uniqueCardPAN      = "cards_panhash_key"
+	uniqueCardMessage  = "card_messages_terminal_rrn_key"
+)
+
+// inTx runs fn inside a transaction. Serialization failures and deadlocks
+// are retried with a growing delay, fn must be safe to run again.
+func (db *sqlRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := db.runTx(ctx, fn)
+		if err == nil || !retryable(err) || attempt == maxTxAttempts {
+			return translate(err)
+		}
+
+		delay := time.Duration(attempt)*txRetryDelay + time.Duration(rand.Int63n(int64(txRetryDelay)))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (db *sqlRepository) runTx(ctx context.Context, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	return fn(tx)
+}
+
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == pgerrcode.SerializationFailure || pgErr.Code == pgerrcode.DeadlockDetected
+}
+
+// translate turns database constraint violations into domain errors.
+func translate(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch {
+	case pgErr.Code == pgerrcode.CheckViolation && pgErr.ConstraintName == nonNegativeBalance:
+		return domain.ErrInvalidSum
+	case pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == uniqueScheduleRun:
+		return domain.ErrScheduleRunDone
+	case pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == uniqueRiskDecision:
+		return domain.ErrDecisionResolved
+	case pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == uniqueCardPAN:
+		return domain.ErrCardExists
+	case pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == uniqueCardMessage:
+		return domain.ErrCardMessageReplayed
+	default:
+		return err
+	}
+}
+
+// lockAccounts takes row locks on the accounts in ID order,
+// so two transfers between the same accounts can't deadlock,
+// and returns their current balances.
+func lockAccounts(ctx context.Context, tx pgx.Tx, IDs ...int64) (map[int64]int64, error) {
+	rows, err := tx.Query(ctx, `
+	SELECT ID, Amount
+	FROM accounts
+	WHERE ID = ANY($1)
+	ORDER BY ID
+	FOR UPDATE`, IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	balances := make(map[int64]int64, len(IDs))
+
+	for rows.Next() {
+		var id, amount int64
+
+		if err := rows.Scan(&id, &amount); err != nil {
+			return nil, err
+		}
+
+		balances[id] = amount
+	}
+
+	return balances, rows.Err()
+}
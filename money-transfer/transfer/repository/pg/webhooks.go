@@ -0,0 +1,244 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// webhookColumns are scanned by webhookFields.
+var webhookColumns = `ID, OwnerID, AccountID, URL, Secret, Events, Created, ` + numberColumn("AccountID", "AccountNumber")
+
+func webhookFields(w *domain.Webhook) []interface{} {
+	return []interface{}{&w.ID, &w.OwnerID, &w.AccountID, &w.URL, &w.Secret, &w.Events, &w.Created, &w.AccountNumber}
+}
+
+// deliveryColumns are scanned by deliveryFields.
+const deliveryColumns = `d.ID, d.WebhookID, d.EventID, d.EventType, d.Status, d.Attempts, d.NextAttempt, 
+	d.ResponseCode, d.LastError, d.Created, d.Delivered`
+
+func deliveryFields(d *domain.WebhookDelivery) []interface{} {
+	return []interface{}{
+		&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttempt,
+		&d.ResponseCode, &d.LastError, &d.Created, &d.Delivered,
+	}
+}
+
+func (db *sqlRepository) CreateWebhook(ctx context.Context, w *domain.Webhook) error {
+	return db.QueryRow(ctx, `
+	INSERT INTO webhooks(OwnerID, AccountID, URL, Secret, Events, Created) 
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ID, `+numberColumn("AccountID", "AccountNumber"),
+		w.OwnerID, w.AccountID, w.URL, w.Secret, w.Events, w.Created,
+	).Scan(&w.ID, &w.AccountNumber)
+}
+
+func (db *sqlRepository) FindWebhook(ctx context.Context, ID int64) (*domain.Webhook, error) {
+	w := &domain.Webhook{}
+
+	err := db.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE ID = $1`, ID).Scan(webhookFields(w)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	return w, err
+}
+
+func (db *sqlRepository) Webhooks(ctx context.Context, ownerID int64) ([]*domain.Webhook, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+webhookColumns+` 
+	FROM webhooks 
+	WHERE OwnerID = $1 
+	ORDER BY ID DESC`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	webhooks := make([]*domain.Webhook, 0)
+
+	for rows.Next() {
+		w := &domain.Webhook{}
+
+		if err := rows.Scan(webhookFields(w)...); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook drops the webhook with its deliveries.
+func (db *sqlRepository) DeleteWebhook(ctx context.Context, ID int64) error {
+	tag, err := db.Exec(ctx, `DELETE FROM webhooks WHERE ID = $1`, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries adds a delivery of the event for every webhook of its accounts
+// that wants it. An event heard again doesn't add deliveries twice.
+func (db *sqlRepository) EnqueueDeliveries(ctx context.Context, e *domain.Event) (int, error) {
+	now := time.Now()
+
+	tag, err := db.Exec(ctx, `
+	INSERT INTO webhook_deliveries(WebhookID, EventID, EventType, Status, NextAttempt, Created)
+	SELECT ID, $1, $2, $3, $4, $4
+	FROM webhooks
+	WHERE AccountID = ANY($5) AND (cardinality(Events) = 0 OR $2 = ANY(Events))
+	ON CONFLICT (WebhookID, EventID) DO NOTHING`,
+		e.ID, e.Type, domain.DeliveryPending, now, e.Accounts,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// ClaimDeliveries takes due deliveries with their webhooks and events
+// and postpones them by lease, so other instances skip them while they are sent.
+func (db *sqlRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+		SELECT `+deliveryColumns+`, 
+			w.URL, w.Secret, 
+			e.ID, e.Type, e.Accounts, e.Payload, e.Created
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.ID = d.WebhookID
+		JOIN outbox e ON e.ID = d.EventID
+		WHERE d.Status = $1 AND d.NextAttempt <= $2
+		ORDER BY d.NextAttempt
+		LIMIT $3
+		FOR UPDATE OF d SKIP LOCKED`,
+			domain.DeliveryPending, now, limit,
+		)
+		if err != nil {
+			return err
+		}
+
+		deliveries = make([]*domain.WebhookDelivery, 0, limit)
+		IDs := make([]int64, 0, limit)
+
+		for rows.Next() {
+			d := &domain.WebhookDelivery{
+				Webhook: &domain.Webhook{},
+				Event:   &domain.Event{},
+			}
+
+			err := rows.Scan(append(deliveryFields(d),
+				&d.Webhook.URL, &d.Webhook.Secret,
+				&d.Event.ID, &d.Event.Type, &d.Event.Accounts, &d.Event.Payload, &d.Event.Created,
+			)...)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			d.Webhook.ID = d.WebhookID
+			deliveries = append(deliveries, d)
+			IDs = append(IDs, d.ID)
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE webhook_deliveries SET NextAttempt = $1 WHERE ID = ANY($2)`, now.Add(lease), IDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// FinishDelivery stores the outcome of an attempt.
+func (db *sqlRepository) FinishDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	_, err := db.Exec(ctx, `
+	UPDATE webhook_deliveries 
+	SET Status = $1, Attempts = $2, NextAttempt = $3, ResponseCode = $4, LastError = $5, Delivered = $6 
+	WHERE ID = $7`,
+		d.Status, d.Attempts, d.NextAttempt, d.ResponseCode, d.LastError, d.Delivered, d.ID,
+	)
+	return err
+}
+
+func (db *sqlRepository) FindDelivery(ctx context.Context, ID int64) (*domain.WebhookDelivery, error) {
+	d := &domain.WebhookDelivery{}
+
+	err := db.QueryRow(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.ID = $1`, ID).Scan(deliveryFields(d)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrDeliveryNotFound
+	}
+
+	return d, err
+}
+
+// Deliveries lists deliveries newest first. Zero webhookID is every webhook,
+// empty status is every status.
+func (db *sqlRepository) Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	rows, err := db.Query(ctx, `
+	SELECT `+deliveryColumns+` 
+	FROM webhook_deliveries d 
+	WHERE ($1::bigint = 0 OR d.WebhookID = $1) AND ($2::varchar = '' OR d.Status = $2) 
+	ORDER BY d.ID DESC 
+	LIMIT $3`,
+		webhookID, status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]*domain.WebhookDelivery, 0)
+
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+
+		if err := rows.Scan(deliveryFields(d)...); err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RedeliverDelivery sends the delivery again right away with a fresh set of attempts.
+func (db *sqlRepository) RedeliverDelivery(ctx context.Context, ID int64) (*domain.WebhookDelivery, error) {
+	d := &domain.WebhookDelivery{}
+
+	err := db.QueryRow(ctx, `
+	UPDATE webhook_deliveries d 
+	SET Status = $1, Attempts = 0, NextAttempt = $2, LastError = '', Delivered = NULL 
+	WHERE d.ID = $3
+	RETURNING `+deliveryColumns,
+		domain.DeliveryPending, time.Now(), ID,
+	).Scan(deliveryFields(d)...)
+	if err == pgx.ErrNoRows {
+		return nil, domain.ErrDeliveryNotFound
+	}
+
+	return d, err
+}
@@ -0,0 +1,205 @@
+package rates
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/BurntSushi/toml"
+)
+
+// fileRateStore keeps rates in memory and in a TOML file,
+// so rates set by an admin survive a restart.
+type fileRateStore struct {
+	path string
+
+	// write serializes SetRates, so the file and memory end up with the same rates
+	write sync.Mutex
+
+	mu     sync.RWMutex
+	rates  *domain.Rates
+	spread *big.Rat
+	prices map[string]*big.Rat
+}
+
+// NewFileRateStore loads rates from the file.
+func NewFileRateStore(c *domain.Config) (domain.RateStore, error) {
+	s := &fileRateStore{path: c.RatesFile}
+
+	r := &domain.Rates{}
+	if _, err := toml.DecodeFile(s.path, r); err != nil {
+		return nil, err
+	}
+
+	if err := s.set(r); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileRateStore) Rates() *domain.Rates {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.rates
+}
+
+// SetRates writes the rates to the file and then makes them current,
+// so rates that didn't reach the file are never used.
+func (s *fileRateStore) SetRates(r *domain.Rates) error {
+	s.write.Lock()
+	defer s.write.Unlock()
+
+	r.Updated = time.Now().UTC()
+
+	spread, prices, err := parse(r)
+	if err != nil {
+		return err
+	}
+
+	if err := s.save(r); err != nil {
+		return err
+	}
+
+	s.swap(r, spread, prices)
+	return nil
+}
+
+// save replaces the file with the rates in one rename,
+// readers see either the old file or the new one.
+func (s *fileRateStore) save(r *domain.Rates) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(r); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Convert exchanges amount through the base currency.
+// The client gets the mid rate less the spread, rounded down to a minor unit.
+func (s *fileRateStore) Convert(from, to string, amount int64) (*domain.Conversion, error) {
+	if !domain.ValidCurrency(from) || !domain.ValidCurrency(to) {
+		return nil, domain.ErrInvalidCurrency
+	}
+
+	if from == to {
+		return &domain.Conversion{From: from, To: to, Amount: amount, Converted: amount, Rate: "1"}, nil
+	}
+
+	s.mu.RLock()
+	fromPrice, ok := s.prices[from]
+	toPrice, ok2 := s.prices[to]
+	spread := s.spread
+	s.mu.RUnlock()
+
+	if !ok || !ok2 {
+		return nil, domain.ErrNoRate
+	}
+
+	rate := new(big.Rat).Quo(fromPrice, toPrice)
+	rate.Mul(rate, new(big.Rat).Sub(big.NewRat(1, 1), spread))
+
+	converted := new(big.Rat).Mul(rate, new(big.Rat).SetInt64(amount))
+	units := new(big.Int).Quo(converted.Num(), converted.Denom())
+
+	return &domain.Conversion{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Converted: units.Int64(),
+		Rate:      rate.FloatString(6),
+	}, nil
+}
+
+func (s *fileRateStore) ToBase(currency string, amount int64) (int64, error) {
+	s.mu.RLock()
+	price, ok := s.prices[currency]
+	s.mu.RUnlock()
+
+	if !ok {
+		return 0, domain.ErrNoRate
+	}
+
+	value := new(big.Rat).Mul(price, new(big.Rat).SetInt64(amount))
+	return new(big.Int).Quo(value.Num(), value.Denom()).Int64(), nil
+}
+
+// set validates the rates and makes them current.
+func (s *fileRateStore) set(r *domain.Rates) error {
+	spread, prices, err := parse(r)
+	if err != nil {
+		return err
+	}
+
+	s.swap(r, spread, prices)
+	return nil
+}
+
+func (s *fileRateStore) swap(r *domain.Rates, spread *big.Rat, prices map[string]*big.Rat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates = r
+	s.spread = spread
+	s.prices = prices
+}
+
+// parse validates the rates and returns the spread and the prices in the base currency.
+func parse(r *domain.Rates) (*big.Rat, map[string]*big.Rat, error) {
+	if !domain.ValidCurrency(r.Base) {
+		return nil, nil, domain.ErrInvalidRates
+	}
+
+	spread, ok := new(big.Rat).SetString(r.Spread)
+	if !ok || spread.Sign() < 0 || spread.Cmp(big.NewRat(1, 2)) >= 0 {
+		return nil, nil, domain.ErrInvalidRates
+	}
+
+	prices := map[string]*big.Rat{r.Base: big.NewRat(1, 1)}
+
+	for currency, value := range r.Rates {
+		if !domain.ValidCurrency(currency) || currency == r.Base {
+			return nil, nil, domain.ErrInvalidRates
+		}
+
+		price, ok := new(big.Rat).SetString(value)
+		if !ok || price.Sign() <= 0 {
+			return nil, nil, domain.ErrInvalidRates
+		}
+
+		prices[currency] = price
+	}
+
+	return spread, prices, nil
+}
@@ -0,0 +1,73 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"money-transfer/domain"
+	"shared/rbac"
+)
+
+// httpDirectory asks auth-service for users on its internal API,
+// requests carry the token both services share.
+type httpDirectory struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewUserDirectory(c *domain.Config) domain.UserDirectory {
+	return &httpDirectory{
+		url:    strings.TrimSuffix(c.UsersURL, "/"),
+		token:  c.InternalToken,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (d *httpDirectory) Role(ctx context.Context, ID int64) (string, error) {
+	u, err := d.User(ctx, ID)
+	if err != nil {
+		return "", err
+	}
+
+	return u.Role, nil
+}
+
+func (d *httpDirectory) User(ctx context.Context, ID int64) (*domain.User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url+"/"+strconv.FormatInt(ID, 10), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.token)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, domain.ErrUserNotFound
+	default:
+		return nil, fmt.Errorf("users: unexpected status: %s", resp.Status)
+	}
+
+	u := &domain.User{}
+	if err := json.NewDecoder(resp.Body).Decode(u); err != nil {
+		return nil, err
+	}
+
+	if !rbac.ValidRole(u.Role) {
+		return nil, fmt.Errorf("users: unknown role %q of user %d", u.Role, ID)
+	}
+
+	return u, nil
+}
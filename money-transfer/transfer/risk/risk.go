@@ -0,0 +1,173 @@
+// Package risk checks transfers against rules before they are made.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+	// rules name time zones, the image may have no zoneinfo
+	_ "time/tzdata"
+
+	"money-transfer/domain"
+
+	"github.com/BurntSushi/toml"
+)
+
+// rulesEngine fires rules from a TOML file against the history of the user.
+type rulesEngine struct {
+	rules   []domain.RiskRule
+	zones   []*time.Location
+	history domain.RiskHistory
+}
+
+// NewRulesEngine loads rules from the file, without a file every transfer is allowed.
+func NewRulesEngine(c *domain.Config, history domain.RiskHistory) (domain.RiskChecker, error) {
+	rules := &domain.RiskRules{}
+	if c.RiskFile != "" {
+		if _, err := toml.DecodeFile(c.RiskFile, rules); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &rulesEngine{
+		rules:   rules.Rules,
+		zones:   make([]*time.Location, len(rules.Rules)),
+		history: history,
+	}
+
+	for i, r := range rules.Rules {
+		if err := validRule(r); err != nil {
+			return nil, err
+		}
+
+		if r.Kind == domain.RuleNight {
+			zone, err := time.LoadLocation(r.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("%w: rule %s: %v", domain.ErrInvalidRiskRules, r.Name, err)
+			}
+			e.zones[i] = zone
+		}
+	}
+
+	return e, nil
+}
+
+func validRule(r domain.RiskRule) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: rule %q: %s", domain.ErrInvalidRiskRules, r.Name, reason)
+	}
+
+	if r.Name == "" {
+		return invalid("no name")
+	}
+
+	if r.Action != domain.RiskReview && r.Action != domain.RiskBlock {
+		return invalid("action should be review or block")
+	}
+
+	switch r.Kind {
+	case domain.RuleVelocity:
+		if r.Window.Duration <= 0 || r.Count <= 0 {
+			return invalid("velocity needs window and count")
+		}
+	case domain.RuleNewReceiver:
+		if r.MinAmount < 0 {
+			return invalid("negative min_amount")
+		}
+	case domain.RuleUnusualAmount:
+		if r.Window.Duration <= 0 || r.Factor <= 0 || r.MinHistory <= 0 {
+			return invalid("unusual_amount needs window, factor and min_history")
+		}
+	case domain.RuleNight:
+		if r.FromHour < 0 || r.FromHour > 23 || r.ToHour < 0 || r.ToHour > 23 || r.FromHour == r.ToHour {
+			return invalid("night needs different from_hour and to_hour within 0-23")
+		}
+	default:
+		return invalid("unknown kind " + r.Kind)
+	}
+
+	return nil
+}
+
+// Assess fires every rule, the strongest action wins
+// and the first rule with it decides.
+func (e *rulesEngine) Assess(ctx context.Context, d *domain.RiskDecision) error {
+	d.Outcome, d.Rule, d.Reason = domain.RiskAllow, "", ""
+	d.Fired = make([]string, 0)
+
+	for i, r := range e.rules {
+		reason, err := e.fire(ctx, r, e.zones[i], d)
+		if err != nil {
+			return err
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		d.Fired = append(d.Fired, r.Name)
+
+		if strength(r.Action) > strength(d.Outcome) {
+			d.Outcome, d.Rule, d.Reason = r.Action, r.Name, reason
+		}
+	}
+
+	return nil
+}
+
+// fire tells why the rule fires on the transfer, empty reason when it doesn't.
+func (e *rulesEngine) fire(ctx context.Context, r domain.RiskRule, zone *time.Location, d *domain.RiskDecision) (string, error) {
+	switch r.Kind {
+	case domain.RuleVelocity:
+		n, err := e.history.TransfersSince(ctx, d.OwnerID, d.Created.Add(-r.Window.Duration))
+		if err != nil || n < r.Count {
+			return "", err
+		}
+		return fmt.Sprintf("%d transfers within %s", n, r.Window.Duration), nil
+
+	case domain.RuleNewReceiver:
+		if d.BaseAmount < r.MinAmount {
+			return "", nil
+		}
+
+		known, err := e.history.KnownReceiver(ctx, d.OwnerID, d.ReceiverID)
+		if err != nil || known {
+			return "", err
+		}
+		return "first transfer to the receiver", nil
+
+	case domain.RuleUnusualAmount:
+		avg, n, err := e.history.AverageTransfer(ctx, d.OwnerID, d.Created.Add(-r.Window.Duration))
+		if err != nil || n < r.MinHistory || float64(d.BaseAmount) <= r.Factor*float64(avg) {
+			return "", err
+		}
+		return fmt.Sprintf("%d is over %g times the average of %d", d.BaseAmount, r.Factor, avg), nil
+
+	case domain.RuleNight:
+		if d.BaseAmount < r.MinAmount || !withinHours(d.Created.In(zone).Hour(), r.FromHour, r.ToHour) {
+			return "", nil
+		}
+		return fmt.Sprintf("made at %s", d.Created.In(zone).Format("15:04 MST")), nil
+	}
+
+	return "", nil
+}
+
+// withinHours reports whether hour is in [from, to), the range may wrap midnight.
+func withinHours(hour, from, to int) bool {
+	if from < to {
+		return hour >= from && hour < to
+	}
+	return hour >= from || hour < to
+}
+
+func strength(outcome string) int {
+	switch outcome {
+	case domain.RiskBlock:
+		return 2
+	case domain.RiskReview:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,134 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+)
+
+// history is the past of one user as the risk check sees it.
+type history struct {
+	transfers int
+	known     bool
+	average   int64
+	count     int
+}
+
+func (h *history) TransfersSince(ctx context.Context, ownerID int64, since time.Time) (int, error) {
+	return h.transfers, nil
+}
+
+func (h *history) KnownReceiver(ctx context.Context, ownerID, receiverID int64) (bool, error) {
+	return h.known, nil
+}
+
+func (h *history) AverageTransfer(ctx context.Context, ownerID int64, since time.Time) (int64, int, error) {
+	return h.average, h.count, nil
+}
+
+func TestAssessRulesOfConfig(t *testing.T) {
+	almaty, err := time.LoadLocation("Asia/Almaty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noon := time.Date(2023, 3, 15, 12, 0, 0, 0, almaty)
+	night := time.Date(2023, 3, 15, 3, 0, 0, 0, almaty)
+	morning := time.Date(2023, 3, 15, 6, 0, 0, 0, almaty)
+
+	tests := []struct {
+		name    string
+		history history
+		amount  int64
+		created time.Time
+		outcome string
+		rule    string
+		fired   []string
+	}{
+		{"quiet", history{known: true}, 10000, noon, domain.RiskAllow, "", []string{}},
+		{"burst", history{transfers: 10, known: true}, 10000, noon, domain.RiskReview, "burst", []string{"burst"}},
+		{"flood", history{transfers: 60, known: true}, 10000, noon, domain.RiskBlock, "flood", []string{"burst", "flood"}},
+		{"large to new receiver", history{}, 50000000, noon, domain.RiskReview, "large_to_new_receiver", []string{"large_to_new_receiver"}},
+		{"small to new receiver", history{}, 49999999, noon, domain.RiskAllow, "", []string{}},
+		{"unusual amount", history{known: true, average: 100000, count: 5}, 1000001, noon, domain.RiskReview, "unusual_amount", []string{"unusual_amount"}},
+		{"unusual amount, short history", history{known: true, average: 100000, count: 4}, 1000001, noon, domain.RiskAllow, "", []string{}},
+		{"night", history{known: true}, 20000000, night, domain.RiskReview, "night", []string{"night"}},
+		{"small at night", history{known: true}, 19999999, night, domain.RiskAllow, "", []string{}},
+		{"after night", history{known: true}, 20000000, morning, domain.RiskAllow, "", []string{}},
+		{"block beats review", history{transfers: 60, known: true}, 20000000, night, domain.RiskBlock, "flood", []string{"burst", "flood", "night"}},
+	}
+
+	for _, tt := range tests {
+		h := tt.history
+
+		checker, err := NewRulesEngine(&domain.Config{RiskFile: "../../configs/risk.toml"}, &h)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := &domain.RiskDecision{OwnerID: 1, ReceiverID: 2, BaseAmount: tt.amount, Created: tt.created}
+		if err := checker.Assess(context.Background(), d); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if d.Outcome != tt.outcome || d.Rule != tt.rule || !reflect.DeepEqual(d.Fired, tt.fired) {
+			t.Errorf("%s: outcome %s by %q, fired %v, want %s by %q, fired %v",
+				tt.name, d.Outcome, d.Rule, d.Fired, tt.outcome, tt.rule, tt.fired)
+		}
+
+		if (d.Reason == "") != (tt.rule == "") {
+			t.Errorf("%s: reason %q with rule %q", tt.name, d.Reason, d.Rule)
+		}
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	rules := []string{
+		"[[rule]]\nkind = \"velocity\"\naction = \"review\"\nwindow = \"1m\"\ncount = 1",
+		"[[rule]]\nname = \"a\"\nkind = \"velocity\"\naction = \"allow\"\nwindow = \"1m\"\ncount = 1",
+		"[[rule]]\nname = \"a\"\nkind = \"velocity\"\naction = \"review\"\ncount = 1",
+		"[[rule]]\nname = \"a\"\nkind = \"unusual_amount\"\naction = \"review\"\nwindow = \"1h\"\nfactor = 2.0",
+		"[[rule]]\nname = \"a\"\nkind = \"night\"\naction = \"review\"\nfrom_hour = 1\nto_hour = 1\ntimezone = \"UTC\"",
+		"[[rule]]\nname = \"a\"\nkind = \"night\"\naction = \"review\"\nfrom_hour = 1\nto_hour = 6\ntimezone = \"Nowhere/Land\"",
+		"[[rule]]\nname = \"a\"\nkind = \"weather\"\naction = \"review\"",
+	}
+
+	for _, r := range rules {
+		file := filepath.Join(t.TempDir(), "risk.toml")
+		if err := os.WriteFile(file, []byte(r), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewRulesEngine(&domain.Config{RiskFile: file}, &history{}); !errors.Is(err, domain.ErrInvalidRiskRules) {
+			t.Errorf("rules %q: %v, want %v", r, err, domain.ErrInvalidRiskRules)
+		}
+	}
+}
+
+func TestWithinHours(t *testing.T) {
+	tests := []struct {
+		hour, from, to int
+		want           bool
+	}{
+		{1, 1, 6, true},
+		{5, 1, 6, true},
+		{6, 1, 6, false},
+		{0, 1, 6, false},
+		{23, 22, 4, true},
+		{3, 22, 4, true},
+		{4, 22, 4, false},
+		{12, 22, 4, false},
+	}
+
+	for _, tt := range tests {
+		if got := withinHours(tt.hour, tt.from, tt.to); got != tt.want {
+			t.Errorf("withinHours(%d, %d, %d) = %v, want %v", tt.hour, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
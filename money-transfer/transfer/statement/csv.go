@@ -0,0 +1,50 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"money-transfer/domain"
+)
+
+func renderCSV(w io.Writer, st *domain.Statement) error {
+	cw := csv.NewWriter(w)
+
+	i := strconv.FormatInt
+
+	rows := [][]string{
+		{"Account", st.Account.Number, st.Account.Currency},
+		{"Period", date(st.From), date(st.To)},
+		{"Opening balance", money(st.OpeningBalance)},
+		{},
+		{"Date", "ID", "Kind", "Status", "Direction", "Counterparty", "Amount", "Fee", "Balance"},
+	}
+
+	for _, t := range st.Transactions {
+		rows = append(rows, []string{
+			date(t.Date),
+			i(t.ID, 10),
+			t.Kind,
+			t.Status,
+			t.Direction,
+			counterparty(t),
+			money(signed(t)),
+			money(t.Fee),
+			money(balance(t)),
+		})
+	}
+
+	rows = append(rows,
+		[]string{},
+		[]string{"Total in", money(st.TotalIn)},
+		[]string{"Total out", money(st.TotalOut)},
+		[]string{"Closing balance", money(st.ClosingBalance)},
+	)
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
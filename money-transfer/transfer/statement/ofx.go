@@ -0,0 +1,108 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"money-transfer/domain"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	ID     string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO"`
+}
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	Signon  struct {
+		Status   ofxStatus `xml:"STATUS"`
+		Server   string    `xml:"DTSERVER"`
+		Language string    `xml:"LANGUAGE"`
+	} `xml:"SIGNONMSGSRSV1>SONRS"`
+	Statement struct {
+		TrnUID    string    `xml:"TRNUID"`
+		Status    ofxStatus `xml:"STATUS"`
+		Currency  string    `xml:"STMTRS>CURDEF"`
+		BankID    string    `xml:"STMTRS>BANKACCTFROM>BANKID"`
+		AccountID string    `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+		Type      string    `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+		Start     string    `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+		End       string    `xml:"STMTRS>BANKTRANLIST>DTEND"`
+
+		Transactions []ofxTransaction `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+
+		Balance string `xml:"STMTRS>LEDGERBAL>BALAMT"`
+		AsOf    string `xml:"STMTRS>LEDGERBAL>DTASOF"`
+	} `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+func renderOFX(w io.Writer, st *domain.Statement) error {
+	doc := &ofxDocument{}
+
+	doc.Signon.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.Signon.Server = ofxDate(st.Generated)
+	doc.Signon.Language = "ENG"
+
+	s := &doc.Statement
+	s.TrnUID = "1"
+	s.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	s.Currency = st.Account.Currency
+	s.BankID = "HALYK"
+	s.AccountID = st.Account.Number
+	s.Type = "CHECKING"
+	s.Start = ofxDate(st.From)
+	s.End = ofxDate(st.To)
+	s.Balance = money(st.ClosingBalance)
+	s.AsOf = ofxDate(st.To)
+
+	s.Transactions = make([]ofxTransaction, 0, len(st.Transactions))
+
+	for _, t := range st.Transactions {
+		trnType := "CREDIT"
+		if t.Direction == domain.DirectionOut {
+			trnType = "DEBIT"
+		}
+
+		s.Transactions = append(s.Transactions, ofxTransaction{
+			Type:   trnType,
+			Posted: ofxDate(t.Date),
+			Amount: money(signed(t)),
+			ID:     strconv.FormatInt(t.ID, 10),
+			Name:   counterparty(t),
+			Memo:   t.Kind + " " + t.Status,
+		})
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102150405")
+}
@@ -0,0 +1,132 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"money-transfer/domain"
+)
+
+// A4 page in points, text is set in the Courier standard font
+// so the document needs no embedded fonts and columns line up.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 40
+	fontSize     = 8
+	lineHeight   = 11
+	linesPerPage = (pageHeight - 2*pageMargin) / lineHeight
+)
+
+func renderPDF(w io.Writer, st *domain.Statement) error {
+	lines := pdfLines(st)
+
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// objects: 1 catalog, 2 pages, 3 font, then page and content per page
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	}
+
+	kids := make([]string, 0, len(pages))
+
+	for _, page := range pages {
+		pageID := len(objects) + 1
+		kids = append(kids, strconv.Itoa(pageID)+" 0 R")
+
+		content := pdfContent(page)
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, pageID+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func pdfLines(st *domain.Statement) []string {
+	lines := []string{
+		"Statement of account " + st.Account.Number + " in " + st.Account.Currency,
+		"Period: " + date(st.From) + " - " + date(st.To),
+		"Generated: " + date(st.Generated),
+		"",
+		"Opening balance: " + money(st.OpeningBalance),
+		"",
+		fmt.Sprintf("%-19s  %-8s  %-8s  %-9s  %-22s  %14s  %14s",
+			"Date", "ID", "Kind", "Status", "Counterparty", "Amount", "Balance"),
+	}
+
+	for _, t := range st.Transactions {
+		lines = append(lines, fmt.Sprintf("%-19s  %-8d  %-8s  %-9s  %-22s  %14s  %14s",
+			date(t.Date), t.ID, t.Kind, t.Status, counterparty(t), money(signed(t)), money(balance(t))))
+	}
+
+	return append(lines,
+		"",
+		"Total in: "+money(st.TotalIn),
+		"Total out: "+money(st.TotalOut),
+		"Closing balance: "+money(st.ClosingBalance),
+	)
+}
+
+func pdfContent(lines []string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, pageMargin, pageHeight-pageMargin)
+	for _, line := range lines {
+		b.WriteString("(" + pdfEscape(line) + ") '\n")
+	}
+	b.WriteString("ET")
+
+	return b.String()
+}
+
+// pdfEscape makes the text safe for a PDF string literal.
+// Only ASCII is kept, the standard fonts don't cover the rest.
+func pdfEscape(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+// Package statement renders account statements for download.
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"money-transfer/domain"
+)
+
+// Supported formats.
+const (
+	FormatCSV = "csv"
+	FormatPDF = "pdf"
+	FormatOFX = "ofx"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV: "text/csv; charset=utf-8",
+	FormatPDF: "application/pdf",
+	FormatOFX: "application/x-ofx",
+}
+
+// ContentType returns MIME type of the format.
+func ContentType(format string) (string, bool) {
+	ct, ok := contentTypes[format]
+	return ct, ok
+}
+
+// Render writes the statement in the format.
+func Render(w io.Writer, st *domain.Statement, format string) error {
+	switch format {
+	case FormatCSV:
+		return renderCSV(w, st)
+	case FormatPDF:
+		return renderPDF(w, st)
+	case FormatOFX:
+		return renderOFX(w, st)
+	default:
+		return domain.ErrInvalidFormat
+	}
+}
+
+// FileName suggests a name for the downloaded statement.
+func FileName(st *domain.Statement, format string) string {
+	return "statement-" + st.Account.Number + "-" +
+		st.From.Format("20060102") + "-" + st.To.Format("20060102") + "." + format
+}
+
+// counterparty describes the other side of a transaction by its IBAN.
+func counterparty(t *domain.Transaction) string {
+	if t.Source != "" {
+		return t.Source
+	}
+
+	if t.Direction == domain.DirectionOut {
+		return t.ReceiverNumber
+	}
+	return t.SenderNumber
+}
+
+// money formats minor units with two decimals, e.g. -1234.50,
+// all supported currencies have two decimal places.
+func money(v int64) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// signed returns the amount as it changes the account balance.
+func signed(t *domain.Transaction) int64 {
+	switch {
+	case t.Direction == domain.DirectionOut:
+		return -(t.Amount + t.Fee)
+	case t.Status == domain.StatusSettled:
+		return t.ReceivedAmount
+	default:
+		return 0
+	}
+}
+
+func balance(t *domain.Transaction) int64 {
+	if t.Balance == nil {
+		return 0
+	}
+	return *t.Balance
+}
+
+func date(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
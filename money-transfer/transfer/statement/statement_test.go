@@ -0,0 +1,77 @@
+package statement
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+)
+
+func testStatement() *domain.Statement {
+	day := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	out, in := int64(98700), int64(223450)
+
+	return &domain.Statement{
+		Account:        &domain.Account{ID: 4405211239547816, Number: "KZ699990000000000001", Currency: domain.KZT},
+		From:           day,
+		To:             day.AddDate(0, 1, 0),
+		OpeningBalance: 100000,
+		ClosingBalance: 223450,
+		TotalIn:        123450,
+		TotalOut:       1300,
+		Transactions: []*domain.Transaction{
+			{
+				ID: 7, SenderID: 4405211239547816, ReceiverID: 101, ReceiverNumber: "KZ289990000000000002",
+				Amount: 1000, Fee: 300, Date: day.Add(time.Hour), Kind: domain.TransactionTransfer,
+				Status: domain.StatusSettled, Direction: domain.DirectionOut, Balance: &out,
+			},
+			{
+				ID: 8, SenderID: 102, SenderNumber: "KZ019990000000000003", ReceiverID: 4405211239547816,
+				Amount: 123450, ReceivedAmount: 123450, Date: day.Add(2 * time.Hour), Kind: domain.TransactionTransfer,
+				Status: domain.StatusSettled, Direction: domain.DirectionIn, Balance: &in,
+			},
+		},
+		Generated: day,
+	}
+}
+
+func TestStatementShowsNumbersAndDecimals(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatPDF, FormatOFX} {
+		var b bytes.Buffer
+		if err := Render(&b, testStatement(), format); err != nil {
+			t.Fatalf("Render(%s): %v", format, err)
+		}
+		out := b.String()
+
+		for _, want := range []string{"KZ699990000000000001", "KZ289990000000000002", "KZ019990000000000003", "-13.00", "1234.50", "2234.50"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s statement has no %q", format, want)
+			}
+		}
+
+		for _, internal := range []string{"4405211239547816", ",101,", ",102,"} {
+			if strings.Contains(out, internal) {
+				t.Errorf("%s statement shows internal ID %q", format, internal)
+			}
+		}
+	}
+}
+
+func TestMoney(t *testing.T) {
+	cases := map[int64]string{0: "0.00", 5: "0.05", 150: "1.50", -1: "-0.01", -123456: "-1234.56", 100000000: "1000000.00"}
+
+	for v, want := range cases {
+		if got := money(v); got != want {
+			t.Errorf("money(%d) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	got := FileName(testStatement(), FormatCSV)
+	if got != "statement-KZ699990000000000001-20220301-20220401.csv" {
+		t.Fatalf("FileName = %q", got)
+	}
+}
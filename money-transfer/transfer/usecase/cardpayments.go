@@ -0,0 +1,268 @@
+package transferUseCase
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+	"shared/rbac"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ProcessCardMessage pays card messages to the account of the merchant.
+// The message is reserved before any money moves, so of two copies sent at
+// once only the first is processed and the other gets its outcome. Only
+// approved messages are kept, a declined one can be sent again.
+func (tu *transferUseCase) ProcessCardMessage(ctx context.Context, m *domain.CardMessage) error {
+	if m.TerminalID == "" || m.RRN == "" || m.Amount < 0 {
+		return domain.ErrInvalidCardMessage
+	}
+
+	m.Created = time.Now()
+
+	switch err := tu.db.ReserveCardMessage(ctx, m); err {
+	case nil:
+	case domain.ErrCardMessageReplayed:
+		return tu.replayCardMessage(ctx, m)
+	default:
+		return err
+	}
+
+	if err := tu.processCardMessage(ctx, m); err != nil {
+		if releaseErr := tu.db.ReleaseCardMessage(ctx, m.ID); releaseErr != nil {
+			log.Warn().Err(releaseErr).Int64("message", m.ID).Msg("cannot release declined card message")
+		}
+		return err
+	}
+
+	// a message left pending answers its copies as in progress
+	if err := tu.db.ApproveCardMessage(ctx, m); err != nil {
+		log.Error().Err(err).Int64("message", m.ID).Msg("card message processed but not approved")
+		return err
+	}
+
+	return nil
+}
+
+func (tu *transferUseCase) processCardMessage(ctx context.Context, m *domain.CardMessage) error {
+	merchant, err := tu.merchantAccount(ctx, m.MerchantID)
+	if err != nil {
+		return err
+	}
+
+	switch m.Kind {
+	case domain.CardAuthorization:
+		return tu.authorizeCard(ctx, m, merchant)
+	case domain.CardCompletion:
+		return tu.completeCard(ctx, m, merchant)
+	case domain.CardReversal:
+		return tu.reverseCard(ctx, m)
+	default:
+		return domain.ErrInvalidCardMessage
+	}
+}
+
+// replayCardMessage answers a repeated message with the outcome of the first copy.
+func (tu *transferUseCase) replayCardMessage(ctx context.Context, m *domain.CardMessage) error {
+	done, err := tu.db.FindCardMessage(ctx, m.TerminalID, m.RRN, m.Kind)
+	switch err {
+	case nil:
+		m.ID, m.Status, m.CardID, m.HoldID, m.TransactionID, m.Created = done.ID, done.Status, done.CardID, done.HoldID, done.TransactionID, done.Created
+		return nil
+	case domain.ErrOriginalNotFound:
+		return domain.ErrCardMessageInProgress
+	default:
+		return err
+	}
+}
+
+// authorizeCard holds the amount on the account of the card for the merchant.
+func (tu *transferUseCase) authorizeCard(ctx context.Context, m *domain.CardMessage, merchant *domain.Account) error {
+	c, account, err := tu.checkCard(ctx, m, true)
+	if err != nil {
+		return err
+	}
+
+	h := &domain.Hold{AccountID: account.ID, ReceiverID: merchant.ID, Amount: m.Amount}
+	if err := tu.CreateHold(ctx, cardholder(c), h); err != nil {
+		return err
+	}
+
+	m.HoldID = h.ID
+
+	return nil
+}
+
+// completeCard captures the authorization with the same RRN and releases
+// the rest of it at once, so a failed completion has moved no money and
+// can be sent again. Without an authorization the amount is transferred
+// to the merchant like any transfer of the cardholder.
+func (tu *transferUseCase) completeCard(ctx context.Context, m *domain.CardMessage, merchant *domain.Account) error {
+	auth, err := tu.db.FindCardMessage(ctx, m.TerminalID, m.RRN, domain.CardAuthorization)
+	if err != nil && err != domain.ErrOriginalNotFound {
+		return err
+	}
+
+	// the CVV2 of a completion was checked by its authorization
+	c, account, err := tu.checkCard(ctx, m, auth == nil)
+	if err != nil {
+		return err
+	}
+
+	if auth == nil {
+		t, err := tu.CreateTransaction(ctx, cardholder(c), account.ID, merchant.ID, m.Amount)
+		if err != nil {
+			return err
+		}
+
+		m.TransactionID = t.ID
+		return nil
+	}
+
+	if auth.CardID != c.ID {
+		return domain.ErrInvalidCardMessage
+	}
+
+	t, err := tu.captureHold(ctx, merchant.OwnerID, auth.HoldID, m.Amount, true)
+	if err != nil {
+		return err
+	}
+
+	m.HoldID = auth.HoldID
+	m.TransactionID = t.ID
+
+	return nil
+}
+
+// reverseCard undoes the completion with the same RRN or, without one,
+// the authorization. The card is not checked, a payment of a card
+// blocked since is reversed too.
+func (tu *transferUseCase) reverseCard(ctx context.Context, m *domain.CardMessage) error {
+	c, err := tu.findCard(ctx, m.PAN)
+	if err != nil {
+		return err
+	}
+
+	original, err := tu.db.FindCardMessage(ctx, m.TerminalID, m.RRN, domain.CardCompletion)
+	if err == domain.ErrOriginalNotFound {
+		original, err = tu.db.FindCardMessage(ctx, m.TerminalID, m.RRN, domain.CardAuthorization)
+	}
+	if err != nil {
+		return err
+	}
+
+	if original.CardID != c.ID {
+		return domain.ErrOriginalNotFound
+	}
+
+	m.CardID = c.ID
+	m.Amount = original.Amount
+	m.Currency = original.Currency
+	m.HoldID = original.HoldID
+
+	if original.TransactionID != 0 {
+		t, err := tu.ReverseTransaction(ctx, original.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		m.TransactionID = t.ID
+	}
+
+	return tu.releaseHold(ctx, original.HoldID)
+}
+
+// checkCard finds the active card of the message and checks its expiry,
+// the CVV and the currency. A CVV that is sent is always checked, a missing
+// one is refused when needCVV unless the terminal is card-present.
+func (tu *transferUseCase) checkCard(ctx context.Context, m *domain.CardMessage, needCVV bool) (*domain.Card, *domain.Account, error) {
+	c, err := tu.findCard(ctx, m.PAN)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch c.Status {
+	case domain.CardExpired:
+		return nil, nil, domain.ErrCardExpired
+	case domain.CardBlocked:
+		return nil, nil, domain.ErrCardNotActive
+	}
+
+	if m.Expiry != c.Expiry() {
+		return nil, nil, domain.ErrCardDetails
+	}
+
+	switch {
+	case m.CVV != "":
+		if bcrypt.CompareHashAndPassword([]byte(c.CVVHash), []byte(m.CVV)) != nil {
+			return nil, nil, domain.ErrCardDetails
+		}
+	case needCVV && !m.CardPresent:
+		return nil, nil, domain.ErrCardDetails
+	}
+
+	account, err := tu.db.FindAccount(ctx, c.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.Currency == "" {
+		m.Currency = account.Currency
+	}
+
+	if m.Currency != account.Currency {
+		return nil, nil, domain.ErrInvalidCurrency
+	}
+
+	m.CardID = c.ID
+
+	return c, account, nil
+}
+
+func (tu *transferUseCase) findCard(ctx context.Context, ref string) (*domain.Card, error) {
+	pan, err := domain.ParsePAN(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tu.cardBINs.Contains(pan) {
+		return nil, domain.ErrCardNotFound
+	}
+
+	return tu.db.CardByPAN(ctx, tu.panHash(pan))
+}
+
+func (tu *transferUseCase) merchantAccount(ctx context.Context, merchantID string) (*domain.Account, error) {
+	merchant, ok := tu.cardMerchants[merchantID]
+	if !ok {
+		return nil, domain.ErrUnknownMerchant
+	}
+
+	ID, err := tu.ResolveAccount(ctx, merchant.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	return tu.db.FindAccount(ctx, ID)
+}
+
+// releaseHold voids what is left of a hold, a hold that is used up
+// or expired has nothing to release.
+func (tu *transferUseCase) releaseHold(ctx context.Context, ID int64) error {
+	if ID == 0 {
+		return nil
+	}
+
+	if err := tu.db.VoidHold(ctx, ID); err != domain.ErrHoldNotActive {
+		return err
+	}
+	return nil
+}
+
+// cardholder is the user card payments are made as.
+// Roles are kept by auth-service, card payments are limited like the ones of users.
+func cardholder(c *domain.Card) *domain.User {
+	return &domain.User{ID: c.OwnerID, Role: rbac.RoleUser}
+}
@@ -0,0 +1,162 @@
+package transferUseCase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"money-transfer/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPANAttempts is how many random PANs are drawn before issuing gives up.
+const maxPANAttempts = 5
+
+// IssueCard draws a random PAN in the BIN range until one is free.
+// The card is valid through the month of issue card_years ahead.
+func (tu *transferUseCase) IssueCard(ctx context.Context, requester, accountID int64) (*domain.Card, error) {
+	account, err := tu.db.FindAccount(ctx, accountID)
+	if err != nil || account.OwnerID != requester || account.Kind != domain.AccountCustomer {
+		return nil, domain.ErrNotFound
+	}
+
+	cvv, err := randomDigits(3)
+	if err != nil {
+		return nil, err
+	}
+
+	cvvHash, err := bcrypt.GenerateFromPassword([]byte(cvv), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	c := &domain.Card{
+		AccountID: account.ID,
+		OwnerID:   account.OwnerID,
+		CVVHash:   string(cvvHash),
+		Status:    domain.CardActive,
+		Created:   now,
+		Expires:   time.Date(now.Year()+tu.cardYears, now.Month()+1, 1, 0, 0, 0, 0, now.Location()),
+	}
+
+	for attempt := 1; ; attempt++ {
+		if c.PAN, err = tu.newPAN(); err != nil {
+			return nil, err
+		}
+
+		c.BIN = c.PAN[:6]
+		c.Last4 = c.PAN[len(c.PAN)-4:]
+		c.PANHash = tu.panHash(c.PAN)
+
+		err = tu.db.CreateCard(ctx, c)
+		if err != domain.ErrCardExists || attempt == maxPANAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.CVV = cvv
+
+	return c, nil
+}
+
+func (tu *transferUseCase) Cards(ctx context.Context, requester int64) ([]*domain.Card, error) {
+	return tu.db.Cards(ctx, requester)
+}
+
+func (tu *transferUseCase) BlockCard(ctx context.Context, requester, ID int64) (*domain.Card, error) {
+	return tu.setCardStatus(ctx, requester, ID, domain.CardBlocked)
+}
+
+func (tu *transferUseCase) UnblockCard(ctx context.Context, requester, ID int64) (*domain.Card, error) {
+	return tu.setCardStatus(ctx, requester, ID, domain.CardActive)
+}
+
+// setCardStatus blocks or unblocks a card of the requester, an expired card stays expired.
+func (tu *transferUseCase) setCardStatus(ctx context.Context, requester, ID int64, status string) (*domain.Card, error) {
+	c, err := tu.db.FindCard(ctx, ID)
+	if err == domain.ErrCardNotFound || err == nil && c.OwnerID != requester {
+		return nil, domain.ErrCardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Status == domain.CardExpired {
+		return nil, domain.ErrCardNotActive
+	}
+
+	if err := tu.db.SetCardStatus(ctx, ID, status); err != nil {
+		return nil, err
+	}
+
+	c.Status = status
+
+	return c, nil
+}
+
+// ResolveReceiver takes a card number as a card and never as an account,
+// a PAN outside our BIN range or of no card is ErrCardNotFound.
+func (tu *transferUseCase) ResolveReceiver(ctx context.Context, ref string) (int64, error) {
+	pan, err := domain.ParsePAN(ref)
+	if err != nil {
+		return tu.ResolveAccount(ctx, ref)
+	}
+
+	if !tu.cardBINs.Contains(pan) {
+		return 0, domain.ErrCardNotFound
+	}
+
+	c, err := tu.db.CardByPAN(ctx, tu.panHash(pan))
+	if err != nil {
+		return 0, err
+	}
+
+	if c.Status != domain.CardActive {
+		return 0, domain.ErrCardNotActive
+	}
+
+	return c.AccountID, nil
+}
+
+func (tu *transferUseCase) newPAN() (string, error) {
+	bin, err := rand.Int(rand.Reader, big.NewInt(int64(tu.cardBINs.To-tu.cardBINs.From+1)))
+	if err != nil {
+		return "", err
+	}
+
+	digits, err := randomDigits(9)
+	if err != nil {
+		return "", err
+	}
+
+	return domain.NewPAN(fmt.Sprint(tu.cardBINs.From+int(bin.Int64())), digits)
+}
+
+func (tu *transferUseCase) panHash(pan string) string {
+	mac := hmac.New(sha256.New, tu.cardPANKey)
+	mac.Write([]byte(pan))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomDigits returns n random decimal digits.
+func randomDigits(n int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+
+	v, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", n, v), nil
+}
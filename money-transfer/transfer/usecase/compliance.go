@@ -0,0 +1,135 @@
+package transferUseCase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+const casesLimit = 100
+
+// screen checks both parties of a transfer of screeningThreshold or more
+// against sanctions lists by the IIN of the account and the name of its owner,
+// which auth-service keeps. A transfer waits while auth-service is down.
+func (tu *transferUseCase) screen(ctx context.Context, requester *domain.User, sender *domain.Account, t *domain.Transaction) error {
+	if t.BaseAmount < tu.screeningThreshold {
+		return nil
+	}
+
+	receiver, err := tu.db.FindAccount(ctx, t.ReceiverID)
+	if err != nil {
+		return domain.ErrTransReceiver
+	}
+
+	senderName, err := tu.ownerName(ctx, sender.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	receiverName, err := tu.ownerName(ctx, receiver.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	c := &domain.ComplianceCase{
+		OwnerID:      requester.ID,
+		SenderID:     t.SenderID,
+		ReceiverID:   t.ReceiverID,
+		Amount:       t.Amount,
+		Currency:     t.Currency,
+		BaseAmount:   t.BaseAmount,
+		SenderIIN:    sender.IIN,
+		ReceiverIIN:  receiver.IIN,
+		SenderName:   senderName,
+		ReceiverName: receiverName,
+		Action:       domain.ScreeningClear,
+		Status:       domain.CaseOpen,
+		Created:      t.Date,
+	}
+
+	for _, party := range [][2]string{{senderName, sender.IIN}, {receiverName, receiver.IIN}} {
+		r := tu.screener.Screen(party[0], party[1])
+
+		c.Matches = append(c.Matches, r.Matches...)
+		if strength(r.Action) > strength(c.Action) {
+			c.Action = r.Action
+		}
+	}
+
+	if c.Action == domain.ScreeningClear {
+		return nil
+	}
+
+	if err := tu.db.CreateCase(ctx, c); err != nil {
+		return err
+	}
+
+	log.Warn().
+		Int64("case", c.ID).
+		Int64("owner", c.OwnerID).
+		Str("action", c.Action).
+		Msg("transfer matched sanctions list")
+
+	if c.Action == domain.ScreeningBlock {
+		return domain.ErrScreeningBlocked
+	}
+	return nil
+}
+
+// ownerName is the full name of the owner of an account. System accounts
+// have no owner and a user gone from auth-service is screened by IIN only.
+func (tu *transferUseCase) ownerName(ctx context.Context, ownerID int64) (string, error) {
+	if ownerID == 0 {
+		return "", nil
+	}
+
+	u, err := tu.users.User(ctx, ownerID)
+	if err == domain.ErrUserNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(u.FirstName + " " + u.LastName), nil
+}
+
+func strength(action string) int {
+	switch action {
+	case domain.ScreeningBlock:
+		return 2
+	case domain.ScreeningFlag:
+		return 1
+	}
+	return 0
+}
+
+func (tu *transferUseCase) ComplianceCases(ctx context.Context, status string) ([]*domain.ComplianceCase, error) {
+	return tu.db.Cases(ctx, status, casesLimit)
+}
+
+// ResolveCase only records the outcome, a blocked transfer is never made
+// and a flagged one was made already.
+func (tu *transferUseCase) ResolveCase(ctx context.Context, officer *domain.User, ID int64, status, note string) (*domain.ComplianceCase, error) {
+	now := time.Now()
+
+	c := &domain.ComplianceCase{
+		ID:         ID,
+		Status:     status,
+		Note:       strings.TrimSpace(note),
+		Resolved:   &now,
+		ResolvedBy: officer.ID,
+	}
+
+	if err := tu.db.ResolveCase(ctx, c); err != nil {
+		return nil, err
+	}
+
+	log.Info().Int64("case", c.ID).Int64("officer", officer.ID).Str("status", c.Status).Msg("compliance case resolved")
+
+	return c, nil
+}
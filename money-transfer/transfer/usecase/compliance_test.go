@@ -0,0 +1,171 @@
+package transferUseCase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+)
+
+// screeningRepository knows the accounts of a transfer and keeps the cases.
+type screeningRepository struct {
+	domain.Repository
+	accounts map[int64]*domain.Account
+	cases    []*domain.ComplianceCase
+}
+
+func (r *screeningRepository) FindAccount(ctx context.Context, ID int64) (*domain.Account, error) {
+	a, ok := r.accounts[ID]
+	if !ok {
+		return nil, domain.ErrAccountNotFound
+	}
+	return a, nil
+}
+
+func (r *screeningRepository) CreateCase(ctx context.Context, c *domain.ComplianceCase) error {
+	c.ID = int64(len(r.cases) + 1)
+	r.cases = append(r.cases, c)
+	return nil
+}
+
+type userDirectory struct {
+	users map[int64]*domain.User
+	err   error
+}
+
+func (d *userDirectory) User(ctx context.Context, ID int64) (*domain.User, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	u, ok := d.users[ID]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (d *userDirectory) Role(ctx context.Context, ID int64) (string, error) {
+	u, err := d.User(ctx, ID)
+	if err != nil {
+		return "", err
+	}
+	return u.Role, nil
+}
+
+// listScreener blocks the one name on its list, if any, and records who it screened.
+type listScreener struct {
+	listed   string
+	screened [][2]string
+}
+
+func (s *listScreener) Screen(name, iin string) *domain.ScreeningResult {
+	s.screened = append(s.screened, [2]string{name, iin})
+
+	if s.listed == "" || name != s.listed {
+		return &domain.ScreeningResult{Action: domain.ScreeningClear}
+	}
+
+	return &domain.ScreeningResult{
+		Action:  domain.ScreeningBlock,
+		Matches: []domain.ScreeningMatch{{List: "test", Name: name, Field: domain.MatchName, Value: name, Score: 1}},
+	}
+}
+
+func screeningUseCase(listed string, users *userDirectory) (*transferUseCase, *screeningRepository, *listScreener) {
+	repo := &screeningRepository{accounts: map[int64]*domain.Account{
+		1: {ID: 1, OwnerID: 10, IIN: "900101300007"},
+		2: {ID: 2, OwnerID: 20, IIN: "850505400015"},
+	}}
+	screener := &listScreener{listed: listed}
+
+	return &transferUseCase{db: repo, users: users, screener: screener, screeningThreshold: 1000}, repo, screener
+}
+
+func testUsers() *userDirectory {
+	return &userDirectory{users: map[int64]*domain.User{
+		10: {ID: 10, FirstName: "Aliya", LastName: "Nurlanova"},
+		20: {ID: 20, FirstName: "Ivan", LastName: "Petrov"},
+	}}
+}
+
+func screenedTransfer(amount int64) *domain.Transaction {
+	return &domain.Transaction{SenderID: 1, ReceiverID: 2, Amount: amount, BaseAmount: amount, Currency: domain.KZT, Date: time.Now()}
+}
+
+func TestScreenByOwnerNames(t *testing.T) {
+	tu, repo, screener := screeningUseCase("", testUsers())
+
+	sender := repo.accounts[1]
+	if err := tu.screen(context.Background(), &domain.User{ID: 10}, sender, screenedTransfer(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][2]string{{"Aliya Nurlanova", "900101300007"}, {"Ivan Petrov", "850505400015"}}
+	if len(screener.screened) != len(want) || screener.screened[0] != want[0] || screener.screened[1] != want[1] {
+		t.Errorf("screened %v, want %v", screener.screened, want)
+	}
+
+	if len(repo.cases) != 0 {
+		t.Errorf("%d cases of a clear transfer, want none", len(repo.cases))
+	}
+}
+
+func TestScreenBlocksListedReceiverName(t *testing.T) {
+	tu, repo, _ := screeningUseCase("Ivan Petrov", testUsers())
+
+	err := tu.screen(context.Background(), &domain.User{ID: 10}, repo.accounts[1], screenedTransfer(1000))
+	if err != domain.ErrScreeningBlocked {
+		t.Fatalf("screen() = %v, want %v", err, domain.ErrScreeningBlocked)
+	}
+
+	if len(repo.cases) != 1 {
+		t.Fatalf("%d cases, want 1", len(repo.cases))
+	}
+
+	c := repo.cases[0]
+	if c.Action != domain.ScreeningBlock || c.SenderName != "Aliya Nurlanova" || c.ReceiverName != "Ivan Petrov" {
+		t.Errorf("case %+v, want a block naming both parties", c)
+	}
+}
+
+func TestScreenBelowThreshold(t *testing.T) {
+	tu, repo, screener := screeningUseCase("Ivan Petrov", &userDirectory{err: errors.New("down")})
+
+	if err := tu.screen(context.Background(), &domain.User{ID: 10}, repo.accounts[1], screenedTransfer(999)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(screener.screened) != 0 {
+		t.Errorf("screened %v below the threshold", screener.screened)
+	}
+}
+
+func TestScreenWaitsForUsers(t *testing.T) {
+	down := errors.New("auth-service is down")
+	tu, repo, screener := screeningUseCase("", &userDirectory{err: down})
+
+	if err := tu.screen(context.Background(), &domain.User{ID: 10}, repo.accounts[1], screenedTransfer(1000)); err != down {
+		t.Errorf("screen() = %v, want %v", err, down)
+	}
+
+	if len(screener.screened) != 0 {
+		t.Errorf("screened %v without names", screener.screened)
+	}
+}
+
+func TestScreenGoneUserByIIN(t *testing.T) {
+	users := testUsers()
+	delete(users.users, 20)
+	tu, repo, screener := screeningUseCase("", users)
+
+	if err := tu.screen(context.Background(), &domain.User{ID: 10}, repo.accounts[1], screenedTransfer(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(screener.screened) != 2 || screener.screened[1] != [2]string{"", "850505400015"} {
+		t.Errorf("screened %v, want the receiver by IIN only", screener.screened)
+	}
+}
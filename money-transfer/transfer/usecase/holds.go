@@ -0,0 +1,191 @@
+package transferUseCase
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+)
+
+// CreateHold is checked like a transfer of the whole amount: it is screened,
+// assessed by risk rules and counts in limits. The fee of the whole amount
+// is reserved with the hold and paid by its captures.
+func (tu *transferUseCase) CreateHold(ctx context.Context, requester *domain.User, h *domain.Hold) error {
+	return tu.hold(ctx, requester, h, origin{})
+}
+
+// hold places the hold, a hold approved by a risk decision is not checked again.
+func (tu *transferUseCase) hold(ctx context.Context, requester *domain.User, h *domain.Hold, from origin) error {
+	account, err := tu.db.FindAccount(ctx, h.AccountID)
+	if err != nil || account.OwnerID != requester.ID {
+		return domain.ErrTransSender
+	}
+
+	if h.AccountID == h.ReceiverID {
+		return domain.ErrTransReceiver
+	}
+
+	if h.Amount < domain.MinAmount(account.Currency) {
+		return domain.ErrTransSum
+	}
+
+	receiver, err := tu.db.FindAccount(ctx, h.ReceiverID)
+	if err != nil {
+		return domain.ErrTransReceiver
+	}
+
+	// the pair has to be convertible, the rate is taken at capture
+	if _, err := tu.rates.Convert(account.Currency, receiver.Currency, h.Amount); err != nil {
+		return err
+	}
+
+	base, err := tu.rates.ToBase(account.Currency, h.Amount)
+	if err != nil {
+		return err
+	}
+
+	fee, err := tu.fees.Fee(account.Currency, h.Amount, receiver.OwnerID == account.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	h.Role = requester.Role
+	h.Currency = account.Currency
+	h.Captured, h.Fee, h.FeeCaptured = 0, fee, 0
+	h.Status = domain.HoldActive
+	h.Created = time.Now()
+	h.Expires = h.Created.Add(tu.holdTTL)
+	h.RiskDecisionID = from.riskDecisionID
+
+	// an approved risk decision was screened when the hold was requested
+	if h.RiskDecisionID == 0 {
+		t := &domain.Transaction{
+			SenderID:   h.AccountID,
+			ReceiverID: h.ReceiverID,
+			Amount:     h.Amount,
+			Date:       h.Created,
+			Currency:   h.Currency,
+			BaseAmount: base,
+		}
+
+		if err := tu.screen(ctx, requester, account, t); err != nil {
+			return err
+		}
+
+		d, err := tu.assess(ctx, requester, domain.DecisionHold, t)
+		if err != nil {
+			return err
+		}
+
+		h.RiskDecisionID = d.ID
+	}
+
+	check := limitCheck(tu.limits, requester.Role, account.Kind, base, tu.rates.Rates().Base)
+
+	return tu.db.CreateHold(ctx, h, check)
+}
+
+func (tu *transferUseCase) Holds(ctx context.Context, requester, accountID int64) ([]*domain.Hold, error) {
+	account, err := tu.db.FindAccount(ctx, accountID)
+	if err != nil || account.OwnerID != requester {
+		return nil, domain.ErrNotFound
+	}
+
+	return tu.db.Holds(ctx, accountID)
+}
+
+// CaptureHold transfers a part of the hold to the receiver at the current rate.
+// The capture counts in limits of the user who placed the hold and pays
+// its part of the fee reserved with the hold.
+func (tu *transferUseCase) CaptureHold(ctx context.Context, requester, ID, amount int64) (*domain.Transaction, error) {
+	return tu.captureHold(ctx, requester, ID, amount, false)
+}
+
+// captureHold captures amount of the hold, last releases the rest of it
+// with the capture.
+func (tu *transferUseCase) captureHold(ctx context.Context, requester, ID, amount int64, last bool) (*domain.Transaction, error) {
+	h, err := tu.receiverHold(ctx, requester, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Status != domain.HoldActive {
+		return nil, domain.ErrHoldNotActive
+	}
+
+	if amount == 0 {
+		amount = h.Amount - h.Captured
+	}
+
+	if amount <= 0 || amount > h.Amount-h.Captured {
+		return nil, domain.ErrHoldExceeded
+	}
+
+	account, err := tu.db.FindAccount(ctx, h.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver, err := tu.db.FindAccount(ctx, h.ReceiverID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := tu.rates.Convert(account.Currency, receiver.Currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if conv.Converted <= 0 {
+		return nil, domain.ErrTransSum
+	}
+
+	base, err := tu.rates.ToBase(account.Currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &domain.Transaction{
+		SenderID:         h.AccountID,
+		ReceiverID:       h.ReceiverID,
+		Amount:           amount,
+		Date:             time.Now(),
+		Currency:         conv.From,
+		ReceivedAmount:   conv.Converted,
+		ReceivedCurrency: conv.To,
+		Rate:             conv.Rate,
+		BaseAmount:       base,
+		HoldID:           h.ID,
+	}
+
+	check := limitCheck(tu.limits, h.Role, account.Kind, base, tu.rates.Rates().Base)
+
+	if err := tu.db.CaptureHold(ctx, t, last, check); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (tu *transferUseCase) VoidHold(ctx context.Context, requester, ID int64) error {
+	if _, err := tu.receiverHold(ctx, requester, ID); err != nil {
+		return err
+	}
+
+	return tu.db.VoidHold(ctx, ID)
+}
+
+// receiverHold finds a hold placed in favour of an account of the requester.
+func (tu *transferUseCase) receiverHold(ctx context.Context, requester, ID int64) (*domain.Hold, error) {
+	h, err := tu.db.FindHold(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver, err := tu.db.FindAccount(ctx, h.ReceiverID)
+	if err != nil || receiver.OwnerID != requester {
+		return nil, domain.ErrHoldNotFound
+	}
+
+	return h, nil
+}
@@ -0,0 +1,67 @@
+package transferUseCase
+
+import (
+	"money-transfer/domain"
+)
+
+// limitCheck returns the check of a transfer of amount against limits of the role
+// and of the account kind. Amount and usage are in the base currency.
+func limitCheck(limits *domain.Limits, role, kind string, amount int64, currency string) func(u *domain.TransferUsage) error {
+	return func(u *domain.TransferUsage) error {
+		if l, ok := limits.Roles[role]; ok {
+			if err := checkLimit("role "+role, l, u.User, amount, currency); err != nil {
+				return err
+			}
+		}
+
+		if l, ok := limits.Accounts[kind]; ok {
+			if err := checkLimit("account "+kind, l, u.Account, amount, currency); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func checkLimit(scope string, l domain.Limit, u domain.Usage, amount int64, currency string) error {
+	if l.DailyCount > 0 && u.DayCount >= l.DailyCount {
+		return &domain.LimitError{
+			Scope:    scope,
+			Limit:    domain.LimitDailyCount,
+			Max:      l.DailyCount,
+			Used:     u.DayCount,
+			Headroom: 0,
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		max  int64
+		used int64
+	}{
+		{domain.LimitPerTransaction, l.PerTransaction, 0},
+		{domain.LimitDaily, l.Daily, u.Day},
+		{domain.LimitMonthly, l.Monthly, u.Month},
+	} {
+		if c.max == 0 || c.used+amount <= c.max {
+			continue
+		}
+
+		headroom := c.max - c.used
+		if headroom < 0 {
+			headroom = 0
+		}
+
+		return &domain.LimitError{
+			Scope:    scope,
+			Limit:    c.name,
+			Max:      c.max,
+			Used:     c.used,
+			Headroom: headroom,
+			Currency: currency,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package transferUseCase
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+const outboxBatch = 100
+
+// RunRelay moves events from the outbox to the broker and to webhooks that want them.
+// An event is marked published only after both took it, so a crash in between
+// publishes it again.
+func (tu *transferUseCase) RunRelay(ctx context.Context) {
+	ticker := time.NewTicker(tu.outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		tu.relayEvents(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tu *transferUseCase) relayEvents(ctx context.Context) {
+	publish := func(e *domain.Event) error {
+		if err := tu.broker.Publish(ctx, e); err != nil {
+			return err
+		}
+
+		_, err := tu.db.EnqueueDeliveries(ctx, e)
+		return err
+	}
+
+	for ctx.Err() == nil {
+		n, err := tu.db.RelayEvents(ctx, outboxBatch, publish)
+		if err != nil {
+			log.Warn().Err(err).Int("published", n).Msg("cannot relay events")
+			return
+		}
+
+		if n < outboxBatch {
+			return
+		}
+	}
+}
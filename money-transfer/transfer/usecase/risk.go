@@ -0,0 +1,134 @@
+package transferUseCase
+
+import (
+	"context"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+const riskQueueLimit = 100
+
+// assess checks the transfer against risk rules and stores the decision,
+// kind tells whether the transfer is made or held when it is approved.
+// Transfers not allowed are refused with a RiskError naming the decision.
+func (tu *transferUseCase) assess(ctx context.Context, requester *domain.User, kind string, t *domain.Transaction) (*domain.RiskDecision, error) {
+	d := &domain.RiskDecision{
+		Kind:          kind,
+		OwnerID:       requester.ID,
+		Role:          requester.Role,
+		SenderID:      t.SenderID,
+		ReceiverID:    t.ReceiverID,
+		Amount:        t.Amount,
+		Currency:      t.Currency,
+		BaseAmount:    t.BaseAmount,
+		ScheduleRunID: t.ScheduleRunID,
+		Created:       t.Date,
+	}
+
+	if err := tu.risk.Assess(ctx, d); err != nil {
+		return nil, err
+	}
+
+	d.Status = domain.DecisionApproved
+	if d.Outcome != domain.RiskAllow {
+		d.Status = domain.DecisionOpen
+	}
+
+	if err := tu.db.CreateRiskDecision(ctx, d); err != nil {
+		return nil, err
+	}
+
+	if d.Outcome != domain.RiskAllow {
+		log.Info().
+			Int64("decision", d.ID).
+			Int64("owner", d.OwnerID).
+			Str("outcome", d.Outcome).
+			Strs("fired", d.Fired).
+			Msg("transfer refused by risk check")
+
+		return nil, &domain.RiskError{DecisionID: d.ID, Outcome: d.Outcome, Rule: d.Rule}
+	}
+
+	return d, nil
+}
+
+func (tu *transferUseCase) RiskQueue(ctx context.Context, status string) ([]*domain.RiskDecision, error) {
+	return tu.db.RiskDecisions(ctx, status, riskQueueLimit)
+}
+
+// ApproveRiskDecision makes the transfer or places the hold on behalf of its
+// sender with the current rates and fees, limits of the sender's current role
+// still apply. The decision stays open when the transfer or the hold fails.
+func (tu *transferUseCase) ApproveRiskDecision(ctx context.Context, admin *domain.User, ID int64) (*domain.RiskDecision, error) {
+	d, err := tu.openDecision(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := tu.users.Role(ctx, d.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	requester := &domain.User{ID: d.OwnerID, Role: role}
+
+	if d.Kind == domain.DecisionHold {
+		h := &domain.Hold{AccountID: d.SenderID, ReceiverID: d.ReceiverID, Amount: d.Amount}
+		if err := tu.hold(ctx, requester, h, origin{riskDecisionID: d.ID}); err != nil {
+			return nil, err
+		}
+
+		d.HoldID = h.ID
+		return d, tu.resolve(ctx, admin, d, domain.DecisionApproved)
+	}
+
+	t, err := tu.transfer(ctx, requester, d.SenderID, d.ReceiverID, d.Amount, origin{riskDecisionID: d.ID, scheduleRunID: d.ScheduleRunID})
+	if err != nil {
+		return nil, err
+	}
+
+	d.TransactionID = t.ID
+
+	return d, tu.resolve(ctx, admin, d, domain.DecisionApproved)
+}
+
+func (tu *transferUseCase) RejectRiskDecision(ctx context.Context, admin *domain.User, ID int64) (*domain.RiskDecision, error) {
+	d, err := tu.openDecision(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, tu.resolve(ctx, admin, d, domain.DecisionRejected)
+}
+
+func (tu *transferUseCase) openDecision(ctx context.Context, ID int64) (*domain.RiskDecision, error) {
+	d, err := tu.db.FindRiskDecision(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Status != domain.DecisionOpen {
+		return nil, domain.ErrDecisionResolved
+	}
+
+	return d, nil
+}
+
+func (tu *transferUseCase) resolve(ctx context.Context, admin *domain.User, d *domain.RiskDecision, status string) error {
+	now := time.Now()
+
+	d.Status = status
+	d.Resolved = &now
+	d.ResolvedBy = admin.ID
+
+	if err := tu.db.ResolveRiskDecision(ctx, d); err != nil {
+		return err
+	}
+
+	log.Info().Int64("decision", d.ID).Int64("admin", admin.ID).Str("status", d.Status).Msg("risk decision resolved")
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package transferUseCase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"money-transfer/domain"
+	"money-transfer/transfer/cron"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	scheduleBatch   = 50
+	maxRetryBackoff = 24 * time.Hour
+)
+
+// CreateSchedule validates the transfer like CreateTransaction does
+// and stores the schedule with its first occurrence.
+func (tu *transferUseCase) CreateSchedule(ctx context.Context, requester *domain.User, s *domain.Schedule) error {
+	if _, _, err := tu.quote(ctx, requester, s.SenderID, s.ReceiverID, s.Amount); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if s.Start.IsZero() {
+		s.Start = now
+	}
+
+	if s.Count < 0 || s.Start.Before(now.Add(-time.Minute)) {
+		return domain.ErrInvalidSchedule
+	}
+
+	first := s.Start
+
+	if s.Cron == "" {
+		s.Until, s.Count = nil, 1
+	} else {
+		expr, err := cron.Parse(s.Cron)
+		if err != nil {
+			return domain.ErrInvalidSchedule
+		}
+
+		// the first occurrence may fall right on the start
+		first = expr.Next(s.Start.Add(-time.Minute))
+		if first.IsZero() {
+			return domain.ErrInvalidSchedule
+		}
+	}
+
+	if s.Until != nil && first.After(*s.Until) {
+		return domain.ErrInvalidSchedule
+	}
+
+	s.OwnerID = requester.ID
+	s.Role = requester.Role
+	s.Remaining = s.Count
+	s.Status = domain.ScheduleActive
+	s.Occurrence = first
+	s.NextRun = first
+	s.Attempt = 0
+	s.Created = now
+
+	return tu.db.CreateSchedule(ctx, s)
+}
+
+func (tu *transferUseCase) Schedules(ctx context.Context, requester int64) ([]*domain.Schedule, error) {
+	return tu.db.Schedules(ctx, requester)
+}
+
+func (tu *transferUseCase) ScheduleRuns(ctx context.Context, requester, scheduleID int64) ([]*domain.ScheduleRun, error) {
+	s, err := tu.db.FindSchedule(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.OwnerID != requester {
+		return nil, domain.ErrScheduleNotFound
+	}
+
+	return tu.db.ScheduleRuns(ctx, scheduleID)
+}
+
+func (tu *transferUseCase) CancelSchedule(ctx context.Context, requester, scheduleID int64) error {
+	return tu.db.CancelSchedule(ctx, requester, scheduleID)
+}
+
+func (tu *transferUseCase) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(tu.schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		tu.runDueSchedules(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tu *transferUseCase) runDueSchedules(ctx context.Context) {
+	for ctx.Err() == nil {
+		schedules, err := tu.db.ClaimSchedules(ctx, time.Now(), tu.schedulerLease, scheduleBatch)
+		if err != nil {
+			log.Warn().Err(err).Msg("cannot claim due schedules")
+			return
+		}
+
+		for _, s := range schedules {
+			tu.runSchedule(ctx, s)
+		}
+
+		if len(schedules) < scheduleBatch {
+			return
+		}
+	}
+}
+
+// runSchedule pays the current occurrence of the schedule with the limits
+// of the role the owner has now. Runs that fail for reasons other than
+// the transfer itself, e.g. a lost database connection or auth-service
+// being down, are left to be picked up again when the lease runs out.
+func (tu *transferUseCase) runSchedule(ctx context.Context, s *domain.Schedule) {
+	run, err := tu.db.StartScheduleRun(ctx, s)
+	if err != nil {
+		log.Warn().Err(err).Int64("schedule", s.ID).Msg("cannot start schedule run")
+		return
+	}
+
+	var runErr error
+
+	if run.TransactionID == 0 {
+		var role string
+
+		role, runErr = tu.users.Role(ctx, s.OwnerID)
+		if runErr == nil {
+			requester := &domain.User{ID: s.OwnerID, Role: role}
+
+			_, runErr = tu.transfer(ctx, requester, s.SenderID, s.ReceiverID, s.Amount, origin{scheduleRunID: run.ID})
+		}
+
+		if runErr == domain.ErrScheduleRunDone {
+			runErr = nil
+		}
+
+		if runErr != nil && !transferRefused(runErr) {
+			log.Warn().Err(runErr).Int64("schedule", s.ID).Int64("run", run.ID).Msg("schedule run interrupted")
+			return
+		}
+	}
+
+	now := time.Now()
+	run.Finished = &now
+
+	var riskErr *domain.RiskError
+
+	switch {
+	case runErr == nil:
+		run.Status = domain.RunSucceeded
+		advance(s)
+
+	// the decision finishes the run, the schedule goes on meanwhile
+	case errors.As(runErr, &riskErr) && riskErr.Outcome == domain.RiskReview:
+		run.Status, run.Error = domain.RunPending, runErr.Error()
+		run.Finished = nil
+		advance(s)
+
+	case runErr == domain.ErrInvalidSum && s.Attempt < tu.maxRetries:
+		run.Status, run.Error = domain.RunRetrying, runErr.Error()
+		s.Attempt++
+		s.NextRun = now.Add(backoff(tu.retryDelay, s.Attempt))
+
+	default:
+		run.Status, run.Error = domain.RunFailed, runErr.Error()
+		advance(s)
+	}
+
+	if err := tu.db.FinishScheduleRun(ctx, run, s); err != nil {
+		log.Warn().Err(err).Int64("schedule", s.ID).Int64("run", run.ID).Msg("cannot finish schedule run")
+		return
+	}
+
+	log.Info().
+		Int64("schedule", s.ID).
+		Int64("run", run.ID).
+		Str("status", run.Status).
+		Str("error", run.Error).
+		Time("next", s.NextRun).
+		Msg("schedule run finished")
+}
+
+// advance moves the schedule to its next occurrence
+// or completes it when there is none.
+func advance(s *domain.Schedule) {
+	s.Attempt = 0
+
+	if s.Remaining > 0 {
+		s.Remaining--
+		if s.Remaining == 0 {
+			s.Status = domain.ScheduleCompleted
+			return
+		}
+	}
+
+	expr, err := cron.Parse(s.Cron)
+	if s.Cron == "" || err != nil {
+		s.Status = domain.ScheduleCompleted
+		return
+	}
+
+	next := expr.Next(s.Occurrence)
+	if next.IsZero() || (s.Until != nil && next.After(*s.Until)) {
+		s.Status = domain.ScheduleCompleted
+		return
+	}
+
+	s.Occurrence, s.NextRun = next, next
+}
+
+// backoff doubles the delay with every retry.
+func backoff(delay time.Duration, attempt int) time.Duration {
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// transferRefused tells failures of the transfer itself from failures to make it.
+func transferRefused(err error) bool {
+	var limitErr *domain.LimitError
+	if errors.As(err, &limitErr) {
+		return true
+	}
+
+	var riskErr *domain.RiskError
+	if errors.As(err, &riskErr) {
+		return true
+	}
+
+	switch err {
+	case domain.ErrInvalidSum, domain.ErrTransSum, domain.ErrTransSender, domain.ErrTransReceiver,
+		domain.ErrInvalidCurrency, domain.ErrNoRate, domain.ErrScreeningBlocked, domain.ErrUserNotFound:
+		return true
+	}
+	return false
+}
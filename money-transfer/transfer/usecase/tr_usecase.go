@@ -2,20 +2,60 @@ package transferUseCase
 
 import (
 	"context"
-	"math/rand"
+	"net/http"
+	"strings"
 	"time"
 
 	"money-transfer/domain"
+	"money-transfer/transfer/broker"
+	"money-transfer/transfer/repository/fees"
 	"money-transfer/transfer/repository/pg"
-)
+	"money-transfer/transfer/repository/rates"
+	"money-transfer/transfer/repository/users"
+	"money-transfer/transfer/risk"
+	"shared/iin"
+	"shared/screening"
 
-const (
-	Min int64 = 1000000000000000
-	Max int64 = 9999999999999999
+	"github.com/BurntSushi/toml"
 )
 
 type transferUseCase struct {
-	db domain.Repository
+	db     domain.Repository
+	rates  domain.RateStore
+	limits *domain.Limits
+	fees   domain.FeeStore
+	broker domain.Broker
+	risk   domain.RiskChecker
+	users  domain.UserDirectory
+
+	screener           domain.Screener
+	screeningThreshold int64
+
+	bankCode string
+
+	cardBINs   *domain.BINRange
+	cardYears  int
+	cardPANKey []byte
+
+	cardMerchants map[string]domain.CardMerchant
+
+	idempotencyKeyTTL time.Duration
+	idempotencyLease  time.Duration
+
+	schedulerInterval time.Duration
+	schedulerLease    time.Duration
+	retryDelay        time.Duration
+	maxRetries        int
+
+	holdTTL time.Duration
+
+	outboxInterval time.Duration
+
+	webhookClient      *http.Client
+	webhookInterval    time.Duration
+	webhookLease       time.Duration
+	webhookRetryDelay  time.Duration
+	webhookMaxAttempts int
 }
 
 func New(c *domain.Config) (domain.Transfer, error) {
@@ -24,26 +64,124 @@ func New(c *domain.Config) (domain.Transfer, error) {
 		return nil, err
 	}
 
+	rateStore, err := rates.NewFileRateStore(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// without limits file transfers are not limited
+	limits := &domain.Limits{}
+	if c.LimitsFile != "" {
+		if _, err := toml.DecodeFile(c.LimitsFile, limits); err != nil {
+			return nil, err
+		}
+	}
+
+	feeStore, err := fees.NewFileFeeStore(c)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := broker.New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	checker, err := risk.NewRulesEngine(c, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	screener, err := screening.New(c.ScreeningLists, c.ScreeningFlagScore, c.ScreeningBlockScore)
+	if err != nil {
+		return nil, err
+	}
+
+	if !domain.ValidBankCode(c.BankCode) {
+		return nil, domain.ErrInvalidBankCode
+	}
+
+	cardBINs, err := domain.NewBINRange(c.CardBINFrom, c.CardBINTo)
+	if err != nil {
+		return nil, err
+	}
+
 	return &transferUseCase{
-		db: repo,
+		db:     repo,
+		rates:  rateStore,
+		limits: limits,
+		fees:   feeStore,
+		broker: events,
+		risk:   checker,
+		users:  users.NewUserDirectory(c),
+
+		screener:           screener,
+		screeningThreshold: c.ScreeningThreshold,
+
+		bankCode: c.BankCode,
+
+		cardBINs:   cardBINs,
+		cardYears:  c.CardYears,
+		cardPANKey: []byte(c.CardPANKey),
+
+		cardMerchants: c.CardMerchants,
+
+		idempotencyKeyTTL: c.IdempotencyKeyTTL.Duration,
+		idempotencyLease:  c.IdempotencyLease.Duration,
+
+		schedulerInterval: c.SchedulerInterval.Duration,
+		schedulerLease:    c.SchedulerLease.Duration,
+		retryDelay:        c.SchedulerRetryDelay.Duration,
+		maxRetries:        c.SchedulerMaxRetries,
+
+		holdTTL: c.HoldTTL.Duration,
+
+		outboxInterval: c.OutboxInterval.Duration,
+
+		webhookClient:      newWebhookClient(c.WebhookTimeout.Duration),
+		webhookInterval:    c.WebhookInterval.Duration,
+		webhookLease:       c.WebhookLease.Duration,
+		webhookRetryDelay:  c.WebhookRetryDelay.Duration,
+		webhookMaxAttempts: c.WebhookMaxAttempts,
 	}, nil
 }
 
 func (tu *transferUseCase) CreateAccount(ctx context.Context, account *domain.Account) error {
-	rand.Seed(time.Now().UnixNano())
+	if account.Currency == "" {
+		account.Currency = domain.KZT
+	}
 
-	for {
-		randomID := rand.Int63n(Max-Min+1) + Min
+	if !domain.ValidCurrency(account.Currency) {
+		return domain.ErrInvalidCurrency
+	}
 
-		if !tu.db.AccountExists(ctx, randomID) {
-			account.ID = randomID
-			break
-		}
+	if _, err := iin.Parse(account.IIN); err != nil {
+		return err
+	}
+
+	seq, err := tu.db.NextAccountNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	if account.Number, err = domain.NewIBAN(tu.bankCode, seq); err != nil {
+		return err
 	}
 
 	return tu.db.CreateAccount(ctx, account)
 }
 
+// ResolveAccount never takes the internal ID, sequential IDs would let
+// anyone walk through the accounts of the bank.
+func (tu *transferUseCase) ResolveAccount(ctx context.Context, ref string) (int64, error) {
+	number, err := domain.ParseIBAN(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return tu.db.AccountIDByNumber(ctx, number)
+}
+
 func (tu *transferUseCase) FindAccount(ctx context.Context, ID int64) (*domain.Account, error) {
 	return tu.db.FindAccount(ctx, ID)
 }
@@ -52,45 +190,393 @@ func (tu *transferUseCase) GetAccounts(ctx context.Context, OwnerID int64) ([]*d
 	return tu.db.GetAccounts(ctx, OwnerID)
 }
 
-func (tu *transferUseCase) ChangeAccountSum(ctx context.Context, accountID, newValue int64) error {
-	if newValue < 150 {
-		return domain.ErrTransSum
+// CashIn tops up an account of the requester from a funding source.
+// Card payments are authorized synchronously and settle right away,
+// terminal cash-ins stay pending until the collected cash is confirmed.
+func (tu *transferUseCase) CashIn(ctx context.Context, requester int64, c *domain.CashIn) (*domain.Transaction, error) {
+	account, err := tu.db.FindAccount(ctx, c.AccountID)
+	if err != nil || account.OwnerID != requester {
+		return nil, domain.ErrNotFound
+	}
+
+	if c.Amount < domain.MinAmount(account.Currency) {
+		return nil, domain.ErrTransSum
+	}
+
+	source, err := sourceName(c)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &domain.Transaction{
+		ReceiverID:       c.AccountID,
+		Amount:           c.Amount,
+		Date:             time.Now(),
+		Source:           source,
+		Currency:         account.Currency,
+		ReceivedAmount:   c.Amount,
+		ReceivedCurrency: account.Currency,
+		Rate:             "1",
+	}
+
+	if err := tu.db.CreateCashIn(ctx, t); err != nil {
+		return nil, err
 	}
 
-	return tu.db.ChangeAccountSum(ctx, accountID, newValue)
+	if c.SourceType == domain.SourceCard {
+		return tu.SettleCashIn(ctx, t.ID)
+	}
+
+	return t, nil
 }
 
-func (tu *transferUseCase) CreateTransaction(ctx context.Context, requester, SenderID, ReceiverID, Value int64) error {
-	account, err := tu.db.FindAccount(ctx, SenderID)
-	if err != nil || account.OwnerID != requester {
-		return domain.ErrTransSender
+func (tu *transferUseCase) SettleCashIn(ctx context.Context, ID int64) (*domain.Transaction, error) {
+	return tu.db.CompleteCashIn(ctx, ID, domain.StatusSettled)
+}
+
+func (tu *transferUseCase) CancelCashIn(ctx context.Context, ID int64) (*domain.Transaction, error) {
+	return tu.db.CompleteCashIn(ctx, ID, domain.StatusCancelled)
+}
+
+// sourceName describes the funding source for history,
+// only the last digits of a card number are kept.
+func sourceName(c *domain.CashIn) (string, error) {
+	name := strings.TrimSpace(c.SourceName)
+	if name == "" || len(name) > 64 {
+		return "", domain.ErrInvalidSource
+	}
+
+	switch c.SourceType {
+	case domain.SourceCard:
+		digits := strings.ReplaceAll(name, " ", "")
+		if len(digits) < 12 || len(digits) > 19 || strings.Trim(digits, "0123456789") != "" {
+			return "", domain.ErrInvalidSource
+		}
+		return "card:*" + digits[len(digits)-4:], nil
+
+	case domain.SourceTerminal:
+		return "terminal:" + name, nil
+
+	default:
+		return "", domain.ErrInvalidSource
+	}
+}
+
+func (tu *transferUseCase) CreateTransaction(ctx context.Context, requester *domain.User, SenderID, ReceiverID, Value int64) (*domain.Transaction, error) {
+	return tu.transfer(ctx, requester, SenderID, ReceiverID, Value, origin{})
+}
+
+func (tu *transferUseCase) CreateQuotedTransaction(ctx context.Context, requester *domain.User, SenderID, ReceiverID, Value, Fee int64) (*domain.Transaction, error) {
+	return tu.transfer(ctx, requester, SenderID, ReceiverID, Value, origin{quotedFee: &Fee})
+}
+
+// origin tells what made a transfer other than a request of the user.
+type origin struct {
+	// scheduleRunID is the schedule run that made the transfer.
+	scheduleRunID int64
+	// riskDecisionID is the decision approved by an admin, the transfer is not checked again.
+	riskDecisionID int64
+	// quotedFee is the fee the user agreed to, nil takes the current one.
+	quotedFee *int64
+}
+
+// transfer checks the transfer against risk rules and makes it.
+func (tu *transferUseCase) transfer(ctx context.Context, requester *domain.User, SenderID, ReceiverID, Value int64, from origin) (*domain.Transaction, error) {
+	sender, q, err := tu.quote(ctx, requester, SenderID, ReceiverID, Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.quotedFee != nil && *from.quotedFee != q.Fee {
+		return nil, domain.ErrFeeChanged
+	}
+
+	base, err := tu.rates.ToBase(sender.Currency, Value)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &domain.Transaction{
+		SenderID:         SenderID,
+		ReceiverID:       ReceiverID,
+		Amount:           Value,
+		Date:             time.Now(),
+		Currency:         q.Currency,
+		ReceivedAmount:   q.ReceivedAmount,
+		ReceivedCurrency: q.ReceivedCurrency,
+		Rate:             q.Rate,
+		Fee:              q.Fee,
+		BaseAmount:       base,
+		ScheduleRunID:    from.scheduleRunID,
+		RiskDecisionID:   from.riskDecisionID,
+	}
+
+	// an approved risk decision was screened when the transfer was requested
+	if t.RiskDecisionID == 0 {
+		if err := tu.screen(ctx, requester, sender, t); err != nil {
+			return nil, err
+		}
+
+		d, err := tu.assess(ctx, requester, domain.DecisionTransfer, t)
+		if err != nil {
+			return nil, err
+		}
+
+		t.RiskDecisionID = d.ID
+	}
+
+	check := limitCheck(tu.limits, requester.Role, sender.Kind, base, tu.rates.Rates().Base)
+
+	if err := tu.db.CreateTransaction(ctx, t, check); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (tu *transferUseCase) QuoteTransaction(ctx context.Context, requester *domain.User, SenderID, ReceiverID, Value int64) (*domain.Quote, error) {
+	_, q, err := tu.quote(ctx, requester, SenderID, ReceiverID, Value)
+	return q, err
+}
+
+// quote validates a transfer and prices it with the current rates and fees.
+func (tu *transferUseCase) quote(ctx context.Context, requester *domain.User, SenderID, ReceiverID, Value int64) (*domain.Account, *domain.Quote, error) {
+	sender, err := tu.db.FindAccount(ctx, SenderID)
+	if err != nil || sender.OwnerID != requester.ID {
+		return nil, nil, domain.ErrTransSender
 	}
 
 	if SenderID == ReceiverID {
-		return domain.ErrTransReceiver
+		return nil, nil, domain.ErrTransReceiver
+	}
+
+	if Value < domain.MinAmount(sender.Currency) {
+		return nil, nil, domain.ErrTransSum
 	}
 
-	if Value < 150 {
-		return domain.ErrTransSum
+	receiver, err := tu.db.FindAccount(ctx, ReceiverID)
+	if err != nil {
+		return nil, nil, domain.ErrTransReceiver
+	}
+
+	conv, err := tu.rates.Convert(sender.Currency, receiver.Currency, Value)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if account.Amount < Value {
-		return domain.ErrInvalidSum
+	if conv.Converted <= 0 {
+		return nil, nil, domain.ErrTransSum
+	}
+
+	fee, err := tu.fees.Fee(sender.Currency, Value, receiver.OwnerID == sender.OwnerID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return tu.db.CreateTransaction(ctx, SenderID, ReceiverID, Value)
+	return sender, &domain.Quote{
+		SenderID:         SenderID,
+		ReceiverID:       ReceiverID,
+		SenderNumber:     sender.Number,
+		ReceiverNumber:   receiver.Number,
+		Amount:           Value,
+		Fee:              fee,
+		Total:            Value + fee,
+		Currency:         conv.From,
+		ReceivedAmount:   conv.Converted,
+		ReceivedCurrency: conv.To,
+		Rate:             conv.Rate,
+	}, nil
+}
+
+func (tu *transferUseCase) Rates() *domain.Rates {
+	return tu.rates.Rates()
+}
+
+func (tu *transferUseCase) FeeSchedule() *domain.FeeSchedule {
+	return tu.fees.Schedule()
+}
+
+func (tu *transferUseCase) SetRates(r *domain.Rates) error {
+	return tu.rates.SetRates(r)
+}
+
+// CheckLedger returns domain.ErrLedgerUnbalanced when the books don't balance.
+func (tu *transferUseCase) CheckLedger(ctx context.Context) error {
+	return tu.db.CheckLedger(ctx)
 }
 
 func (tu *transferUseCase) Close() {
+	tu.broker.Close()
 	tu.db.CloseConnection()
 }
 
-func (tu *transferUseCase) AccountTransactions(ctx context.Context, requester, accountID int64) ([]*domain.Transaction, error) {
+func (tu *transferUseCase) AccountTransactions(ctx context.Context, requester int64, f *domain.HistoryFilter) (*domain.HistoryPage, error) {
+
+	account, err := tu.db.FindAccount(ctx, f.AccountID)
+	if err != nil || account.OwnerID != requester {
+		return nil, domain.ErrNotFound
+	}
+
+	if err := validHistoryFilter(f); err != nil {
+		return nil, err
+	}
+
+	return tu.db.AccountTransactions(ctx, f)
+}
+
+// StartIdempotent reserves the key for a new request.
+// It returns the stored key when the request was already answered.
+func (tu *transferUseCase) StartIdempotent(ctx context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	key.Created = time.Now()
+	key.Leased = key.Created.Add(tu.idempotencyLease)
+
+	stored, err := tu.db.ReserveIdempotencyKey(ctx, key, tu.idempotencyKeyTTL)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+
+	if stored.Fingerprint != key.Fingerprint {
+		return nil, domain.ErrIdempotencyConflict
+	}
+
+	if stored.StatusCode == 0 {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+
+	return stored, nil
+}
+
+// FinishIdempotent stores the response of a request. Server errors are not
+// remembered, so the client is free to retry them with the same key.
+func (tu *transferUseCase) FinishIdempotent(ctx context.Context, key *domain.IdempotencyKey) error {
+	if key.StatusCode >= 500 {
+		return tu.db.DeleteIdempotencyKey(ctx, key.OwnerID, key.Key)
+	}
+
+	return tu.db.SaveIdempotencyKey(ctx, key)
+}
+
+func (tu *transferUseCase) AnyAccountTransactions(ctx context.Context, f *domain.HistoryFilter) (*domain.HistoryPage, error) {
+	if !tu.db.AccountExists(ctx, f.AccountID) {
+		return nil, domain.ErrAccountNotFound
+	}
+
+	if err := validHistoryFilter(f); err != nil {
+		return nil, err
+	}
+
+	return tu.db.AccountTransactions(ctx, f)
+}
 
+// Statement collects account history between from and to.
+func (tu *transferUseCase) Statement(ctx context.Context, requester, accountID int64, from, to time.Time) (*domain.Statement, error) {
 	account, err := tu.db.FindAccount(ctx, accountID)
 	if err != nil || account.OwnerID != requester {
 		return nil, domain.ErrNotFound
 	}
 
-	return tu.db.AccountTransactions(ctx, accountID)
+	if !from.Before(to) {
+		return nil, domain.ErrInvalidFilter
+	}
+
+	st := &domain.Statement{
+		Account:      account,
+		From:         from,
+		To:           to,
+		Transactions: make([]*domain.Transaction, 0),
+		Generated:    time.Now(),
+	}
+
+	if st.OpeningBalance, err = tu.db.BalanceAt(ctx, accountID, from); err != nil {
+		return nil, err
+	}
+
+	if st.ClosingBalance, err = tu.db.BalanceAt(ctx, accountID, to); err != nil {
+		return nil, err
+	}
+
+	// totals come from the same journal entries as the balances
+	if st.TotalIn, st.TotalOut, err = tu.db.AccountTurnover(ctx, accountID, from, to); err != nil {
+		return nil, err
+	}
+
+	filter := &domain.HistoryFilter{
+		AccountID: accountID,
+		Limit:     maxHistoryLimit,
+		From:      from,
+		To:        to,
+	}
+
+	for {
+		page, err := tu.db.AccountTransactions(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		st.Transactions = append(st.Transactions, page.Transactions...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	// history comes newest first
+	for i, j := 0, len(st.Transactions)-1; i < j; i, j = i+1, j-1 {
+		st.Transactions[i], st.Transactions[j] = st.Transactions[j], st.Transactions[i]
+	}
+
+	return st, nil
+}
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+func validHistoryFilter(f *domain.HistoryFilter) error {
+	if f.Limit == 0 {
+		f.Limit = defaultHistoryLimit
+	}
+
+	if f.Limit < 0 || f.Limit > maxHistoryLimit {
+		return domain.ErrInvalidFilter
+	}
+
+	if f.Direction != "" && f.Direction != domain.DirectionIn && f.Direction != domain.DirectionOut {
+		return domain.ErrInvalidFilter
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return domain.ErrInvalidFilter
+	}
+
+	if f.MinAmount < 0 || f.MaxAmount < 0 || (f.MaxAmount != 0 && f.MaxAmount < f.MinAmount) {
+		return domain.ErrInvalidFilter
+	}
+
+	return nil
+}
+
+func (tu *transferUseCase) ReverseTransaction(ctx context.Context, ID int64) (*domain.Transaction, error) {
+	return tu.db.CreateCompensation(ctx, ID, domain.TransactionReversal, 0)
+}
+
+// RefundTransaction is open to the owner of the receiving account only.
+func (tu *transferUseCase) RefundTransaction(ctx context.Context, requester, ID, amount int64) (*domain.Transaction, error) {
+	t, err := tu.db.FindTransaction(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver, err := tu.db.FindAccount(ctx, t.ReceiverID)
+	if err != nil || receiver.OwnerID != requester {
+		return nil, domain.ErrTransactionNotFound
+	}
+
+	if amount <= 0 {
+		return nil, domain.ErrRefundExceeded
+	}
+
+	return tu.db.CreateCompensation(ctx, ID, domain.TransactionRefund, amount)
 }
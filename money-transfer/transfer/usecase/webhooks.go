@@ -0,0 +1,368 @@
+package transferUseCase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"money-transfer/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	deliveryBatch     = 50
+	maxDeliveriesList = 200
+	maxWebhookURL     = 2048
+)
+
+func (tu *transferUseCase) CreateWebhook(ctx context.Context, requester int64, w *domain.Webhook) error {
+	account, err := tu.db.FindAccount(ctx, w.AccountID)
+	if err != nil || account.OwnerID != requester {
+		return domain.ErrNotFound
+	}
+
+	return tu.createWebhook(ctx, account, w)
+}
+
+func (tu *transferUseCase) AnyCreateWebhook(ctx context.Context, w *domain.Webhook) error {
+	account, err := tu.db.FindAccount(ctx, w.AccountID)
+	if err != nil {
+		return err
+	}
+
+	return tu.createWebhook(ctx, account, w)
+}
+
+// createWebhook gives the webhook to the owner of the account,
+// whoever registered it, and makes its signing secret.
+func (tu *transferUseCase) createWebhook(ctx context.Context, account *domain.Account, w *domain.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(w.URL) > maxWebhookURL {
+		return domain.ErrInvalidWebhook
+	}
+
+	if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+		return err
+	}
+
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+
+	for _, e := range w.Events {
+		if !domain.ValidEventType(e) {
+			return domain.ErrInvalidWebhook
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	w.OwnerID = account.OwnerID
+	w.Secret = "whsec_" + hex.EncodeToString(secret)
+	w.Created = time.Now()
+
+	return tu.db.CreateWebhook(ctx, w)
+}
+
+// Webhooks hides the secrets, they are shown only when a webhook is created.
+func (tu *transferUseCase) Webhooks(ctx context.Context, requester int64) ([]*domain.Webhook, error) {
+	webhooks, err := tu.db.Webhooks(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range webhooks {
+		w.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+func (tu *transferUseCase) DeleteWebhook(ctx context.Context, requester, ID int64) error {
+	if _, err := tu.ownWebhook(ctx, requester, ID); err != nil {
+		return err
+	}
+
+	return tu.db.DeleteWebhook(ctx, ID)
+}
+
+func (tu *transferUseCase) WebhookDeliveries(ctx context.Context, requester, webhookID int64, status string) ([]*domain.WebhookDelivery, error) {
+	if _, err := tu.ownWebhook(ctx, requester, webhookID); err != nil {
+		return nil, err
+	}
+
+	if !validDeliveryStatus(status) {
+		return nil, domain.ErrInvalidFilter
+	}
+
+	return tu.db.Deliveries(ctx, webhookID, status, maxDeliveriesList)
+}
+
+func (tu *transferUseCase) AnyDeliveries(ctx context.Context, status string) ([]*domain.WebhookDelivery, error) {
+	if !validDeliveryStatus(status) {
+		return nil, domain.ErrInvalidFilter
+	}
+
+	return tu.db.Deliveries(ctx, 0, status, maxDeliveriesList)
+}
+
+func (tu *transferUseCase) RedeliverDelivery(ctx context.Context, requester, ID int64) (*domain.WebhookDelivery, error) {
+	d, err := tu.db.FindDelivery(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tu.ownWebhook(ctx, requester, d.WebhookID); err != nil {
+		return nil, domain.ErrDeliveryNotFound
+	}
+
+	return tu.db.RedeliverDelivery(ctx, ID)
+}
+
+func (tu *transferUseCase) AnyRedeliverDelivery(ctx context.Context, ID int64) (*domain.WebhookDelivery, error) {
+	return tu.db.RedeliverDelivery(ctx, ID)
+}
+
+func (tu *transferUseCase) ownWebhook(ctx context.Context, requester, ID int64) (*domain.Webhook, error) {
+	w, err := tu.db.FindWebhook(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.OwnerID != requester {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	return w, nil
+}
+
+func validDeliveryStatus(status string) bool {
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryDead:
+		return true
+	default:
+		return false
+	}
+}
+
+func (tu *transferUseCase) RunWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(tu.webhookInterval)
+	defer ticker.Stop()
+
+	for {
+		tu.sendDueDeliveries(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tu *transferUseCase) sendDueDeliveries(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := tu.db.ClaimDeliveries(ctx, time.Now(), tu.webhookLease, deliveryBatch)
+		if err != nil {
+			log.Warn().Err(err).Msg("cannot claim webhook deliveries")
+			return
+		}
+
+		for _, d := range deliveries {
+			tu.sendDelivery(ctx, d)
+		}
+
+		if len(deliveries) < deliveryBatch {
+			return
+		}
+	}
+}
+
+// sendDelivery makes an attempt to deliver the event. A failed delivery
+// is retried with a doubling delay and is dead after the last attempt.
+func (tu *transferUseCase) sendDelivery(ctx context.Context, d *domain.WebhookDelivery) {
+	code, err := tu.postEvent(ctx, d)
+
+	now := time.Now()
+	d.Attempts++
+	d.ResponseCode = code
+
+	switch {
+	case err == nil:
+		d.Status, d.LastError, d.Delivered = domain.DeliveryDelivered, "", &now
+
+	case d.Attempts >= tu.webhookMaxAttempts:
+		d.Status, d.LastError = domain.DeliveryDead, err.Error()
+
+	default:
+		d.LastError = err.Error()
+		d.NextAttempt = now.Add(backoff(tu.webhookRetryDelay, d.Attempts))
+	}
+
+	if err := tu.db.FinishDelivery(ctx, d); err != nil {
+		log.Warn().Err(err).Int64("delivery", d.ID).Msg("cannot finish webhook delivery")
+		return
+	}
+
+	log.Info().
+		Int64("delivery", d.ID).
+		Int64("webhook", d.WebhookID).
+		Int64("event", d.EventID).
+		Str("status", d.Status).
+		Int("attempts", d.Attempts).
+		Str("error", d.LastError).
+		Msg("webhook delivery attempted")
+}
+
+// postEvent posts the event signed with the secret of the webhook.
+// Any 2xx answer is a delivery.
+func (tu *transferUseCase) postEvent(ctx context.Context, d *domain.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", strconv.FormatInt(d.WebhookID, 10))
+	req.Header.Set("X-Delivery-ID", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Event-ID", strconv.FormatInt(d.EventID, 10))
+	req.Header.Set("X-Event-Type", d.EventType)
+	req.Header.Set("X-Signature", "t="+timestamp+",v1="+signWebhook(d.Webhook.Secret, timestamp, body))
+
+	resp, err := tu.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the connection is reused only when the body is read
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// signWebhook is hex HMAC-SHA256 of "<timestamp>.<body>" with the secret.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// reservedNets are not covered by the checks of net.IP but aren't public either:
+// this network, carrier-grade NAT, IETF protocol assignments, benchmarking and reserved.
+var reservedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// publicIP reports whether webhooks may be sent to ip: it is not a loopback,
+// private, link-local, multicast, unspecified or reserved address.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost refuses hosts that are or resolve to addresses
+// that are not public. The address is checked again on every delivery,
+// the name may resolve elsewhere by then.
+func checkWebhookHost(ctx context.Context, host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return domain.ErrWebhookAddress
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return domain.ErrWebhookAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return domain.ErrInvalidWebhook
+	}
+
+	for _, a := range addrs {
+		if !publicIP(a.IP) {
+			return domain.ErrWebhookAddress
+		}
+	}
+	return nil
+}
+
+// newWebhookClient connects only to public addresses. The address is checked
+// right before connecting, after the name was resolved, so a name that
+// resolves to an internal address by the time of the delivery is refused too.
+// Proxies from the environment are not used, they would connect instead of it.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return domain.ErrWebhookAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// a redirect is not a delivery
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package transferUseCase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"money-transfer/domain"
+)
+
+func TestCheckWebhookHost(t *testing.T) {
+	refused := []string{
+		"localhost",
+		"api.localhost",
+		"127.0.0.1",
+		"10.1.2.3",
+		"172.16.0.1",
+		"192.168.1.1",
+		"169.254.169.254",
+		"100.64.0.1",
+		"0.0.0.0",
+		"::1",
+		"fd00::1",
+		"fe80::1",
+		"::ffff:127.0.0.1",
+	}
+
+	for _, host := range refused {
+		if err := checkWebhookHost(context.Background(), host); err != domain.ErrWebhookAddress {
+			t.Errorf("checkWebhookHost(%q) = %v, want %v", host, err, domain.ErrWebhookAddress)
+		}
+	}
+
+	for _, host := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		if err := checkWebhookHost(context.Background(), host); err != nil {
+			t.Errorf("checkWebhookHost(%q) = %v", host, err)
+		}
+	}
+}
+
+func TestWebhookClientRefusesLoopback(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	_, err := newWebhookClient(time.Second).Get(srv.URL)
+	if !errors.Is(err, domain.ErrWebhookAddress) {
+		t.Fatalf("Get(%s) = %v, want %v", srv.URL, err, domain.ErrWebhookAddress)
+	}
+
+	if called {
+		t.Fatal("loopback server was reached")
+	}
+}
@@ -0,0 +1,95 @@
+// Package iin decodes individual identification numbers of Kazakhstan.
+// auth-service checks the IINs of users with it, money-transfer the IINs of accounts.
+package iin
+
+import (
+	"errors"
+	"time"
+)
+
+// Genders decoded from an IIN.
+const (
+	Male   = "male"
+	Female = "female"
+)
+
+// ErrInvalid - IIN has a wrong check digit or doesn't decode to a birth date.
+var ErrInvalid = errors.New("invalid IIN")
+
+// Info is what an IIN tells about its holder.
+type Info struct {
+	BirthDate time.Time
+	Gender    string
+}
+
+// Parse checks the IIN and decodes it. The 12 digits are the birth date
+// as YYMMDD, the century and gender digit, a serial number and the check digit.
+func Parse(iin string) (*Info, error) {
+	if len(iin) != 12 {
+		return nil, ErrInvalid
+	}
+
+	digits := make([]int, 12)
+	for i, r := range iin {
+		if r < '0' || r > '9' {
+			return nil, ErrInvalid
+		}
+		digits[i] = int(r - '0')
+	}
+
+	check, ok := checkDigit(digits[:11])
+	if !ok || check != digits[11] {
+		return nil, ErrInvalid
+	}
+
+	// 1 and 2 are the 19th century, 3 and 4 the 20th, 5 and 6 the 21st,
+	// odd digits are men
+	c := digits[6]
+	if c < 1 || c > 6 {
+		return nil, ErrInvalid
+	}
+
+	year := 1800 + (c-1)/2*100 + digits[0]*10 + digits[1]
+	month := time.Month(digits[2]*10 + digits[3])
+	day := digits[4]*10 + digits[5]
+
+	birth := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if birth.Year() != year || birth.Month() != month || birth.Day() != day || birth.After(time.Now()) {
+		return nil, ErrInvalid
+	}
+
+	gender := Female
+	if c%2 == 1 {
+		gender = Male
+	}
+
+	return &Info{BirthDate: birth, Gender: gender}, nil
+}
+
+// checkDigit weighs the digits by 1 to 11, and by 3 to 11, 1, 2
+// when the first pass gives 10. An IIN can't give 10 twice.
+func checkDigit(digits []int) (int, bool) {
+	for _, first := range []int{1, 3} {
+		sum := 0
+		for i, d := range digits {
+			sum += d * ((first+i-1)%11 + 1)
+		}
+
+		if check := sum % 11; check != 10 {
+			return check, true
+		}
+	}
+
+	return 0, false
+}
+
+// Age is the age of the holder in full years at the moment.
+func (i *Info) Age(now time.Time) int {
+	years := now.Year() - i.BirthDate.Year()
+
+	// before the birthday of this year
+	if now.Month() < i.BirthDate.Month() || (now.Month() == i.BirthDate.Month() && now.Day() < i.BirthDate.Day()) {
+		years--
+	}
+	return years
+}
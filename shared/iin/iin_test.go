@@ -0,0 +1,73 @@
+package iin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		iin    string
+		birth  time.Time
+		gender string
+		err    error
+	}{
+		{"900101300007", date(1990, 1, 1), Male, nil},
+		{"851229403213", date(1985, 12, 29), Female, nil},
+		{"050314501235", date(2005, 3, 14), Male, nil},
+		{"000229600014", date(2000, 2, 29), Female, nil},
+		{"991231100040", date(1899, 12, 31), Male, nil},
+		// the first pass of weights gives 10
+		{"900101300811", date(1990, 1, 1), Male, nil},
+
+		{"900101300008", time.Time{}, "", ErrInvalid},  // check digit
+		{"030229500013", time.Time{}, "", ErrInvalid},  // no 29th of February in 2003
+		{"901301300007", time.Time{}, "", ErrInvalid},  // month 13
+		{"900101000008", time.Time{}, "", ErrInvalid},  // century digit 0
+		{"900101700002", time.Time{}, "", ErrInvalid},  // century digit 7
+		{"990101500006", time.Time{}, "", ErrInvalid},  // born in 2099
+		{"90010130000", time.Time{}, "", ErrInvalid},   // too short
+		{"9001013000070", time.Time{}, "", ErrInvalid}, // too long
+		{"90010130000a", time.Time{}, "", ErrInvalid},
+		{"", time.Time{}, "", ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		info, err := Parse(tt.iin)
+		if err != tt.err {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.iin, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if !info.BirthDate.Equal(tt.birth) || info.Gender != tt.gender {
+			t.Errorf("Parse(%q) = %v %s, want %v %s", tt.iin, info.BirthDate, info.Gender, tt.birth, tt.gender)
+		}
+	}
+}
+
+func TestIINAge(t *testing.T) {
+	info := &Info{BirthDate: time.Date(2006, 3, 14, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		now  time.Time
+		want int
+	}{
+		{time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), 17},
+		{time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), 18},
+		{time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC), 17},
+		{time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), 18},
+	}
+
+	for _, tt := range tests {
+		if got := info.Age(tt.now); got != tt.want {
+			t.Errorf("Age(%v) = %d, want %d", tt.now, got, tt.want)
+		}
+	}
+}
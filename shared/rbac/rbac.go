@@ -0,0 +1,63 @@
+// Package rbac names the roles of users and the permissions they grant.
+// auth-service assigns the roles, both services check the permissions.
+package rbac
+
+// Roles of users. New users always get RoleUser.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// Permission names an action guarded by role.
+type Permission string
+
+const (
+	PermUsersRead           Permission = "users:read"
+	PermUsersWrite          Permission = "users:write"
+	PermAccountsRead        Permission = "accounts:read"
+	PermTransactionsRead    Permission = "transactions:read"
+	PermCashInsSettle       Permission = "cashins:settle"
+	PermRatesWrite          Permission = "rates:write"
+	PermTransactionsReverse Permission = "transactions:reverse"
+	PermWebhooksWrite       Permission = "webhooks:write"
+	PermRiskReview          Permission = "risk:review"
+	PermComplianceReview    Permission = "compliance:review"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleUser: {},
+	RoleSupport: {
+		PermUsersRead,
+		PermAccountsRead,
+		PermTransactionsRead,
+	},
+	RoleAdmin: {
+		PermUsersRead,
+		PermUsersWrite,
+		PermAccountsRead,
+		PermTransactionsRead,
+		PermCashInsSettle,
+		PermRatesWrite,
+		PermTransactionsReverse,
+		PermWebhooksWrite,
+		PermRiskReview,
+		PermComplianceReview,
+	},
+}
+
+// ValidRole reports whether role is known.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether the role grants the permission.
+func Can(role string, p Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
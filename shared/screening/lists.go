@@ -0,0 +1,156 @@
+package screening
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readCSV reads a list with the header ref,name,aliases,iin in any order,
+// aliases are separated by semicolons. Other columns are ignored.
+func readCSV(path, list string) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidList, path, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: %s: no name column", ErrInvalidList, path)
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []*Entry
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidList, path, err)
+		}
+
+		name := field(record, "name")
+		if name == "" {
+			continue
+		}
+
+		e := &Entry{List: list, Ref: field(record, "ref"), Names: []string{name}}
+
+		for _, alias := range strings.Split(field(record, "aliases"), ";") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				e.Names = append(e.Names, alias)
+			}
+		}
+
+		if iin := field(record, "iin"); iin != "" {
+			e.IINs = append(e.IINs, iin)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// consolidatedList is the XML format of the UN Security Council consolidated list.
+type consolidatedList struct {
+	Individuals []struct {
+		Ref       string   `xml:"REFERENCE_NUMBER"`
+		First     string   `xml:"FIRST_NAME"`
+		Second    string   `xml:"SECOND_NAME"`
+		Third     string   `xml:"THIRD_NAME"`
+		Fourth    string   `xml:"FOURTH_NAME"`
+		Aliases   []string `xml:"INDIVIDUAL_ALIAS>ALIAS_NAME"`
+		Documents []string `xml:"INDIVIDUAL_DOCUMENT>NUMBER"`
+	} `xml:"INDIVIDUALS>INDIVIDUAL"`
+	Entities []struct {
+		Ref     string   `xml:"REFERENCE_NUMBER"`
+		Name    string   `xml:"FIRST_NAME"`
+		Aliases []string `xml:"ENTITY_ALIAS>ALIAS_NAME"`
+	} `xml:"ENTITIES>ENTITY"`
+}
+
+// readXML reads a list in the format of the UN consolidated list.
+// Document numbers of twelve digits are taken for IINs or BINs.
+func readXML(path, list string) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &consolidatedList{}
+	if err := xml.NewDecoder(f).Decode(l); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidList, path, err)
+	}
+
+	var entries []*Entry
+
+	for _, i := range l.Individuals {
+		name := strings.Join(strings.Fields(strings.Join([]string{i.First, i.Second, i.Third, i.Fourth}, " ")), " ")
+		if name == "" {
+			continue
+		}
+
+		e := &Entry{List: list, Ref: strings.TrimSpace(i.Ref), Names: []string{name}}
+		e.Names = append(e.Names, nonEmpty(i.Aliases)...)
+
+		for _, number := range i.Documents {
+			if d := digits(number); len(d) == 12 && d == strings.TrimSpace(number) {
+				e.IINs = append(e.IINs, d)
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	for _, en := range l.Entities {
+		name := strings.TrimSpace(en.Name)
+		if name == "" {
+			continue
+		}
+
+		e := &Entry{List: list, Ref: strings.TrimSpace(en.Ref), Names: []string{name}}
+		e.Names = append(e.Names, nonEmpty(en.Aliases)...)
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func nonEmpty(names []string) []string {
+	var out []string
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
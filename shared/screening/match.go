@@ -0,0 +1,151 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// cyrillic spells Russian and Kazakh letters in Latin,
+// so names written either way are compared alike.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'ә': "a", 'ғ': "g", 'қ': "k", 'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u",
+	'һ': "h", 'і': "i",
+}
+
+// normalize splits the name into lowercase Latin tokens,
+// anything but letters and digits separates them.
+func normalize(name string) []string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(name) {
+		if latin, ok := cyrillic[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		b.WriteByte(' ')
+	}
+
+	return strings.Fields(b.String())
+}
+
+// nameScore compares names regardless of the order of their parts. A name that
+// covers only some parts of the other, e.g. without a patronymic, scores
+// a bit less than the same name in full.
+func nameScore(a, b []string) float64 {
+	whole := jaroWinkler(sortedJoin(a), sortedJoin(b))
+
+	short, long := a, b
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+
+	// a single word matches too many names to be compared alone
+	if len(short) < 2 {
+		return whole
+	}
+
+	sum := 0.0
+	for _, s := range short {
+		best := 0.0
+		for _, l := range long {
+			if score := jaroWinkler(s, l); score > best {
+				best = score
+			}
+		}
+		sum += best
+	}
+
+	parts := sum / float64(len(short)) * (0.9 + 0.1*float64(len(short))/float64(len(long)))
+
+	if parts > whole {
+		return parts
+	}
+	return whole
+}
+
+func sortedJoin(tokens []string) string {
+	sorted := append([]string(nil), tokens...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
+
+// jaroWinkler is the Jaro similarity of a and b boosted by their common prefix.
+func jaroWinkler(a, b string) float64 {
+	s, t := []rune(a), []rune(b)
+	if len(s) == 0 || len(t) == 0 {
+		return 0
+	}
+
+	window := max(len(s), len(t))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	sMatched := make([]bool, len(s))
+	tMatched := make([]bool, len(t))
+
+	matches := 0
+	for i := range s {
+		from, to := max(0, i-window), min(len(t), i+window+1)
+		for j := from; j < to; j++ {
+			if !tMatched[j] && s[i] == t[j] {
+				sMatched[i], tMatched[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range s {
+		if !sMatched[i] {
+			continue
+		}
+		for !tMatched[j] {
+			j++
+		}
+		if s[i] != t[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s)) + m/float64(len(t)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, min(len(s), len(t))) && s[prefix] == t[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package screening
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Ivanov Ivan", []string{"ivanov", "ivan"}},
+		{"  IVANOV,  Ivan-Petrovich ", []string{"ivanov", "ivan", "petrovich"}},
+		{"Иванов Иван", []string{"ivanov", "ivan"}},
+		{"Жұмабек Әлиев", []string{"zhumabek", "aliev"}},
+		{"O'Neil", []string{"o", "neil"}},
+		{" - ", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := normalize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.840},
+		{"dixon", "dicksonx", 0.813},
+		{"ivanov", "ivanov", 1},
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+	}
+
+	for _, tt := range tests {
+		got := jaroWinkler(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("jaroWinkler(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+		if back := jaroWinkler(tt.b, tt.a); math.Abs(got-back) > 1e-9 {
+			t.Errorf("jaroWinkler(%q, %q) = %.3f, but %.3f the other way", tt.a, tt.b, got, back)
+		}
+	}
+}
+
+func TestNameScore(t *testing.T) {
+	listed := normalize("Ivanov Ivan Petrovich")
+
+	tests := []struct {
+		name     string
+		min, max float64
+	}{
+		{"Ivanov Ivan Petrovich", 1, 1},
+		{"Petrovich Ivan Ivanov", 1, 1},
+		{"Иванов Иван Петрович", 1, 1},
+		// without the patronymic
+		{"Ivan Ivanov", 0.96, 0.97},
+		{"Ivan Ivanof Petrovich", 0.97, 0.99},
+		{"Ivanoff Ivan Petrov", 0.94, 0.95},
+		// a shared surname alone stays under the usual flag score
+		{"Ivanov Igor", 0.79, 0.80},
+		{"Ivanov", 0.82, 0.83},
+		{"Petr Sidorov", 0.73, 0.75},
+	}
+
+	for _, tt := range tests {
+		got := nameScore(normalize(tt.name), listed)
+		if got < tt.min || got > tt.max {
+			t.Errorf("nameScore(%q) = %.3f, want %.2f to %.2f", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package screening matches names and IINs against sanctions lists.
+// auth-service screens sign-ups and money-transfer screens transfers with it,
+// so both services match parties the same way.
+package screening
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// indexed is a sanctions entry with its names split into normalized tokens.
+type indexed struct {
+	*Entry
+	names [][]string
+}
+
+// Screener matches parties against the lists it was loaded with.
+type Screener struct {
+	entries []indexed
+	byIIN   map[string][]*Entry
+
+	flagScore  float64
+	blockScore float64
+}
+
+// New loads the lists, CSV or XML by the extension. A list is named
+// after its file, e.g. un.xml is the list "un". A name scoring flagScore
+// or more is flagged, one scoring blockScore or more is blocked.
+func New(lists []string, flagScore, blockScore float64) (*Screener, error) {
+	s := &Screener{
+		byIIN:      make(map[string][]*Entry),
+		flagScore:  flagScore,
+		blockScore: blockScore,
+	}
+
+	if s.flagScore <= 0 || s.flagScore > s.blockScore {
+		return nil, fmt.Errorf("%w: flag score %v is over block score %v", ErrInvalidList, s.flagScore, s.blockScore)
+	}
+
+	for _, path := range lists {
+		list := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+		var entries []*Entry
+		var err error
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			entries, err = readCSV(path, list)
+		case ".xml":
+			entries, err = readXML(path, list)
+		default:
+			err = fmt.Errorf("%w: unknown format of %s", ErrInvalidList, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			s.add(e)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Screener) add(e *Entry) {
+	en := indexed{Entry: e}
+
+	for _, name := range e.Names {
+		if tokens := normalize(name); len(tokens) > 0 {
+			en.names = append(en.names, tokens)
+		}
+	}
+
+	for _, iin := range e.IINs {
+		if iin = digits(iin); iin != "" {
+			s.byIIN[iin] = append(s.byIIN[iin], e)
+		}
+	}
+
+	if len(en.names) > 0 {
+		s.entries = append(s.entries, en)
+	}
+}
+
+// Screen takes the best scoring name of every entry. An IIN on a list is
+// always a block, a name is blocked when it scores blockScore or more.
+func (s *Screener) Screen(name, iin string) *Result {
+	r := &Result{Action: Clear}
+
+	if iin = digits(iin); iin != "" {
+		for _, e := range s.byIIN[iin] {
+			r.Matches = append(r.Matches, Match{
+				List:  e.List,
+				Ref:   e.Ref,
+				Name:  e.Names[0],
+				Field: MatchIIN,
+				Value: iin,
+				Score: 1,
+			})
+		}
+	}
+
+	if tokens := normalize(name); len(tokens) > 0 {
+		for _, e := range s.entries {
+			best := 0.0
+			for _, listed := range e.names {
+				if score := nameScore(tokens, listed); score > best {
+					best = score
+				}
+			}
+
+			if best >= s.flagScore {
+				r.Matches = append(r.Matches, Match{
+					List:  e.List,
+					Ref:   e.Ref,
+					Name:  e.Names[0],
+					Field: MatchName,
+					Value: name,
+					Score: best,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(r.Matches, func(i, j int) bool {
+		return r.Matches[i].Score > r.Matches[j].Score
+	})
+
+	if len(r.Matches) > 0 {
+		r.Action = Flag
+		if r.Matches[0].Field == MatchIIN || r.Matches[0].Score >= s.blockScore {
+			r.Action = Block
+		}
+	}
+
+	return r
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
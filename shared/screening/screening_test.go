@@ -0,0 +1,60 @@
+package screening
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "local.csv")
+
+	list := "ref,name,aliases,iin\n" +
+		"local-1,Ivanov Ivan Petrovich,Ivanov Ivan;Иванов Иван Петрович,900101300007\n"
+
+	if err := os.WriteFile(path, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New([]string{path}, 0.88, 0.98)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, iin string
+		action    string
+		field     string
+	}{
+		{"Petrovich Ivan Ivanov", "", Block, MatchName},
+		{"Иванов Иван Петрович", "", Block, MatchName},
+		// the alias without the patronymic matches in full
+		{"Ivan Ivanov", "", Block, MatchName},
+		{"Ivanov Ivan Petrovic", "", Block, MatchName},
+		{"Ivanoff Ivan Petrov", "", Flag, MatchName},
+		{"Ivanov Igor", "", Clear, ""},
+		{"Petr Sidorov", "", Clear, ""},
+		{"Petr Sidorov", "9001 0130 0007", Block, MatchIIN},
+		{"", "900101300007", Block, MatchIIN},
+		{"", "", Clear, ""},
+	}
+
+	for _, tt := range tests {
+		r := s.Screen(tt.name, tt.iin)
+
+		if r.Action != tt.action {
+			t.Errorf("Screen(%q, %q) = %s, want %s", tt.name, tt.iin, r.Action, tt.action)
+			continue
+		}
+
+		if tt.field != "" && (len(r.Matches) == 0 || r.Matches[0].Field != tt.field || r.Matches[0].Ref != "local-1") {
+			t.Errorf("Screen(%q, %q) matches = %+v, want the first by %s of local-1", tt.name, tt.iin, r.Matches, tt.field)
+		}
+	}
+}
+
+func TestNewScores(t *testing.T) {
+	if _, err := New(nil, 0.99, 0.98); err == nil {
+		t.Error("flag score over block score: error = nil")
+	}
+}
@@ -0,0 +1,45 @@
+package screening
+
+import "errors"
+
+// Actions of sanctions screening, from the mildest. A flagged party goes on,
+// a blocked one is refused.
+const (
+	Clear = "clear"
+	Flag  = "flag"
+	Block = "block"
+)
+
+// Fields a party is matched by.
+const (
+	MatchName = "name"
+	MatchIIN  = "iin"
+)
+
+var ErrInvalidList = errors.New("invalid sanctions list")
+
+// Entry is a person or an entity on a sanctions list.
+// The first of Names is the main name, the rest are aliases.
+type Entry struct {
+	List  string
+	Ref   string
+	Names []string
+	IINs  []string
+}
+
+// Match is an entry resembling the screened party.
+// Value is what was screened, Name is the name of the entry.
+type Match struct {
+	List  string  `json:"List"`
+	Ref   string  `json:"Ref"`
+	Name  string  `json:"Name"`
+	Field string  `json:"Field"`
+	Value string  `json:"Value"`
+	Score float64 `json:"Score"`
+}
+
+// Result is the action taken on the matches, best match first.
+type Result struct {
+	Action  string
+	Matches []Match
+}